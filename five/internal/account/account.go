@@ -0,0 +1,290 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeromicro/go-zero/core/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 平台系统账户，用户 ID 小于等于 0，余额允许为负
+const (
+	FeeUserID      int64 = 0  // 手续费收入账户
+	HouseUserID    int64 = -1 // 成交清算账户：撮合成交时双方对冲为零，人工成交时为平台头寸
+	ExternalUserID int64 = -2 // 外部资金账户：入金与调账的对方科目
+)
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Posting 一条余额变动，Amount 为正增加余额，为负减少余额
+type Posting struct {
+	UserID int64
+	Asset  string
+	Bucket types.Bucket
+	Amount decimal.Decimal
+}
+
+// Journal 一组借贷平衡的余额变动，每个币种的变动之和必须为零
+type Journal struct {
+	Type     types.EntryType
+	OrderID  string
+	TradeID  string
+	Remark   string
+	Postings []Posting
+}
+
+// Key 用户单个币种的账户
+type Key struct {
+	UserID int64
+	Asset  string
+}
+
+// less 账户的加锁顺序：先按 user_id，再按 asset
+func (k Key) less(o Key) bool {
+	if k.UserID != o.UserID {
+		return k.UserID < o.UserID
+	}
+	return k.Asset < o.Asset
+}
+
+// sortKeys 按加锁顺序排序并去重
+func sortKeys(keys []Key) []Key {
+	sorted := append([]Key(nil), keys...)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i].less(sorted[k]) })
+	return slices.Compact(sorted)
+}
+
+// Lock 在事务内按 user_id、asset 顺序锁定一组账户。同一事务要记多组分录时先锁定全部涉及的账户，
+// 并发事务以相同顺序加锁，不会因加锁顺序相反而死锁
+func Lock(tx *gorm.DB, keys ...Key) error {
+	for _, key := range sortKeys(keys) {
+		if _, err := Get(tx, key.UserID, key.Asset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Post 在事务内校验借贷平衡、调整账户余额并写入分录。账户行按 user_id、asset 顺序锁定，
+// 用户账户任一余额变为负数时返回 ErrInsufficientBalance，调用方应回滚事务。
+func Post(tx *gorm.DB, j Journal) error {
+	if err := j.Check(); err != nil {
+		return err
+	}
+
+	entries := j.Entries()
+	ordered := append([]types.LedgerEntry(nil), entries...)
+	sort.SliceStable(ordered, func(i, k int) bool {
+		return Key{ordered[i].UserID, ordered[i].Asset}.less(Key{ordered[k].UserID, ordered[k].Asset})
+	})
+	for _, e := range ordered {
+		if err := change(tx, Posting{UserID: e.UserID, Asset: e.Asset, Bucket: e.Bucket, Amount: e.Amount}); err != nil {
+			return err
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+// Check 校验分录借贷平衡：每个币种的变动之和为零
+func (j Journal) Check() error {
+	sums := make(map[string]decimal.Decimal)
+	for _, p := range j.Postings {
+		if p.Asset == "" {
+			return fmt.Errorf("asset is required")
+		}
+		sums[p.Asset] = sums[p.Asset].Add(p.Amount)
+	}
+	for asset, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("unbalanced %s journal: %s sums to %s", j.Type, asset, sum)
+		}
+	}
+	return nil
+}
+
+// Entries 生成待写入的账本分录，跳过零金额变动，同一组分录共用一个 JournalID
+func (j Journal) Entries() []types.LedgerEntry {
+	journalID := utils.NewUuid()
+	entries := make([]types.LedgerEntry, 0, len(j.Postings))
+	for _, p := range j.Postings {
+		if p.Amount.IsZero() {
+			continue
+		}
+		entries = append(entries, types.LedgerEntry{
+			JournalID: journalID,
+			EntryType: j.Type,
+			UserID:    p.UserID,
+			Asset:     p.Asset,
+			Bucket:    p.Bucket,
+			Amount:    p.Amount,
+			OrderID:   j.OrderID,
+			TradeID:   j.TradeID,
+			Remark:    j.Remark,
+		})
+	}
+	return entries
+}
+
+// Apply 将一条余额变动计入账户，用户账户余额变为负数时返回 ErrInsufficientBalance
+func Apply(acct *types.Account, p Posting) error {
+	switch p.Bucket {
+	case types.BucketAvailable:
+		acct.Available = acct.Available.Add(p.Amount)
+	case types.BucketFrozen:
+		acct.Frozen = acct.Frozen.Add(p.Amount)
+	default:
+		return fmt.Errorf("unknown bucket %s", p.Bucket)
+	}
+	if p.UserID > FeeUserID && (acct.Available.IsNegative() || acct.Frozen.IsNegative()) {
+		return fmt.Errorf("%w: user %d asset %s", ErrInsufficientBalance, p.UserID, p.Asset)
+	}
+	return nil
+}
+
+// Available 用户可用余额变动
+func Available(userID int64, asset string, amount decimal.Decimal) Posting {
+	return Posting{UserID: userID, Asset: asset, Bucket: types.BucketAvailable, Amount: amount}
+}
+
+// Frozen 用户冻结余额变动
+func Frozen(userID int64, asset string, amount decimal.Decimal) Posting {
+	return Posting{UserID: userID, Asset: asset, Bucket: types.BucketFrozen, Amount: amount}
+}
+
+// Deposit 入金，增加可用余额
+func Deposit(tx *gorm.DB, userID int64, asset string, amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("deposit amount must be positive")
+	}
+	return Post(tx, DepositJournal(userID, asset, amount))
+}
+
+// DepositJournal 入金分录：外部资金账户转入用户可用余额
+func DepositJournal(userID int64, asset string, amount decimal.Decimal) Journal {
+	return Journal{
+		Type: types.EntryTypeDeposit,
+		Postings: []Posting{
+			Available(userID, asset, amount),
+			Available(ExternalUserID, asset, amount.Neg()),
+		},
+	}
+}
+
+// Adjust 人工调账，amount 为负时扣减可用余额
+func Adjust(tx *gorm.DB, userID int64, asset string, amount decimal.Decimal, remark string) error {
+	if amount.IsZero() {
+		return fmt.Errorf("adjustment amount must not be zero")
+	}
+	return Post(tx, Journal{
+		Type:   types.EntryTypeAdjustment,
+		Remark: remark,
+		Postings: []Posting{
+			Available(userID, asset, amount),
+			Available(ExternalUserID, asset, amount.Neg()),
+		},
+	})
+}
+
+// FreezeJournal 下单冻结分录：可用转冻结
+func FreezeJournal(userID int64, asset string, amount decimal.Decimal, orderID string) Journal {
+	return Journal{
+		Type:    types.EntryTypeFreeze,
+		OrderID: orderID,
+		Postings: []Posting{
+			Available(userID, asset, amount.Neg()),
+			Frozen(userID, asset, amount),
+		},
+	}
+}
+
+// UnfreezeJournal 撤单或订单完结时释放剩余冻结的分录：冻结转可用
+func UnfreezeJournal(userID int64, asset string, amount decimal.Decimal, orderID string) Journal {
+	return Journal{
+		Type:    types.EntryTypeUnfreeze,
+		OrderID: orderID,
+		Postings: []Posting{
+			Frozen(userID, asset, amount.Neg()),
+			Available(userID, asset, amount),
+		},
+	}
+}
+
+// Get 锁定并查询用户单个币种的账户，不存在时返回零余额账户
+func Get(tx *gorm.DB, userID int64, asset string) (*types.Account, error) {
+	var acct types.Account
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND asset = ?", userID, asset).
+		First(&acct).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &types.Account{UserID: userID, Asset: asset}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &acct, nil
+}
+
+// List 查询用户的资产账户，指定 assets 时只查询这些币种，否则查询全部
+func List(db *gorm.DB, userID int64, assets ...string) ([]types.Account, error) {
+	var accounts []types.Account
+	query := db.Where("user_id = ?", userID)
+	if len(assets) > 0 {
+		query = query.Where("asset IN ?", assets)
+	}
+	if err := query.Order("asset ASC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// SetFeeToken 设置用户是否使用平台币抵扣手续费
+func SetFeeToken(db *gorm.DB, userID int64, enabled bool) (*types.FeeSetting, error) {
+	setting := &types.FeeSetting{UserID: userID, PayWithToken: enabled}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"pay_with_token", "updated_at"}),
+	}).Create(setting).Error
+	if err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+// PaysFeeInToken 用户是否开启平台币抵扣手续费
+func PaysFeeInToken(db *gorm.DB, userID int64) (bool, error) {
+	var setting types.FeeSetting
+	err := db.Where("user_id = ?", userID).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return setting.PayWithToken, nil
+}
+
+// change 锁定账户行并调整一条余额，账户不存在时创建
+func change(tx *gorm.DB, p Posting) error {
+	acct, err := Get(tx, p.UserID, p.Asset)
+	if err != nil {
+		return err
+	}
+	if err := Apply(acct, p); err != nil {
+		return err
+	}
+
+	if acct.ID == 0 {
+		return tx.Create(acct).Error
+	}
+	return tx.Model(acct).Select("available", "frozen").Updates(acct).Error
+}
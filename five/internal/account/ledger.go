@@ -0,0 +1,157 @@
+package account
+
+import (
+	"sort"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 账本查询默认与最大条数
+const (
+	defaultLedgerLimit = 100
+	maxLedgerLimit     = 1000
+)
+
+// History 按时间倒序查询用户的分录，asset 为空时查询全部币种
+func History(db *gorm.DB, userID int64, asset string, limit int) ([]types.LedgerEntry, error) {
+	if limit <= 0 {
+		limit = defaultLedgerLimit
+	}
+	if limit > maxLedgerLimit {
+		limit = maxLedgerLimit
+	}
+
+	query := db.Where("user_id = ?", userID)
+	if asset != "" {
+		query = query.Where("asset = ?", asset)
+	}
+	var entries []types.LedgerEntry
+	if err := query.Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AssetAudit 单个币种的对账结果
+type AssetAudit struct {
+	Asset      string          `json:"asset"`
+	LedgerSum  decimal.Decimal `json:"ledger_sum"`  // 全部分录之和，复式记账下必须为零
+	BalanceSum decimal.Decimal `json:"balance_sum"` // 全部账户余额之和，必须与分录之和一致
+	Balanced   bool            `json:"balanced"`
+}
+
+// AccountMismatch 账户余额与分录累计不一致的账户
+type AccountMismatch struct {
+	UserID  int64           `json:"user_id"`
+	Asset   string          `json:"asset"`
+	Bucket  types.Bucket    `json:"bucket"`
+	Ledger  decimal.Decimal `json:"ledger"`  // 分录累计
+	Balance decimal.Decimal `json:"balance"` // 账户当前余额
+}
+
+// AuditReport 全量对账报告
+type AuditReport struct {
+	Balanced   bool              `json:"balanced"`
+	Assets     []AssetAudit      `json:"assets"`
+	Mismatches []AccountMismatch `json:"mismatches"`
+}
+
+type bucketSum struct {
+	UserID int64
+	Asset  string
+	Bucket types.Bucket
+	Total  decimal.Decimal
+}
+
+// Audit 校验每个币种的分录之和为零，且每个账户的余额等于其分录累计
+func Audit(db *gorm.DB) (*AuditReport, error) {
+	var sums []bucketSum
+	if err := db.Model(&types.LedgerEntry{}).
+		Select("user_id, asset, bucket, SUM(amount) AS total").
+		Group("user_id, asset, bucket").
+		Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	var accounts []types.Account
+	if err := db.Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		userID int64
+		asset  string
+		bucket types.Bucket
+	}
+	ledger := make(map[key]decimal.Decimal, len(sums))
+	assets := make(map[string]*AssetAudit)
+	audit := func(asset string) *AssetAudit {
+		a, ok := assets[asset]
+		if !ok {
+			a = &AssetAudit{Asset: asset}
+			assets[asset] = a
+		}
+		return a
+	}
+	for _, s := range sums {
+		ledger[key{s.UserID, s.Asset, s.Bucket}] = s.Total
+		a := audit(s.Asset)
+		a.LedgerSum = a.LedgerSum.Add(s.Total)
+	}
+
+	report := &AuditReport{Balanced: true, Mismatches: []AccountMismatch{}}
+	for _, acct := range accounts {
+		a := audit(acct.Asset)
+		a.BalanceSum = a.BalanceSum.Add(acct.Available).Add(acct.Frozen)
+
+		balances := map[types.Bucket]decimal.Decimal{
+			types.BucketAvailable: acct.Available,
+			types.BucketFrozen:    acct.Frozen,
+		}
+		for bucket, balance := range balances {
+			k := key{acct.UserID, acct.Asset, bucket}
+			if !ledger[k].Equal(balance) {
+				report.Mismatches = append(report.Mismatches, AccountMismatch{
+					UserID:  acct.UserID,
+					Asset:   acct.Asset,
+					Bucket:  bucket,
+					Ledger:  ledger[k],
+					Balance: balance,
+				})
+			}
+			delete(ledger, k)
+		}
+	}
+	// 有分录但账户不存在
+	for k, total := range ledger {
+		if !total.IsZero() {
+			report.Mismatches = append(report.Mismatches, AccountMismatch{
+				UserID: k.userID,
+				Asset:  k.asset,
+				Bucket: k.bucket,
+				Ledger: total,
+			})
+		}
+	}
+
+	for _, a := range assets {
+		a.Balanced = a.LedgerSum.IsZero() && a.BalanceSum.Equal(a.LedgerSum)
+		report.Balanced = report.Balanced && a.Balanced
+		report.Assets = append(report.Assets, *a)
+	}
+	sort.Slice(report.Assets, func(i, j int) bool { return report.Assets[i].Asset < report.Assets[j].Asset })
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		mi, mj := report.Mismatches[i], report.Mismatches[j]
+		if mi.UserID != mj.UserID {
+			return mi.UserID < mj.UserID
+		}
+		if mi.Asset != mj.Asset {
+			return mi.Asset < mj.Asset
+		}
+		return mi.Bucket < mj.Bucket
+	})
+	report.Balanced = report.Balanced && len(report.Mismatches) == 0
+	return report, nil
+}
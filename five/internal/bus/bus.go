@@ -0,0 +1,46 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrClosed = errors.New("bus closed")
+
+// Message 一条消息。Partition 与 Offset 由总线在发布时分配，消费确认时按二者提交位点。
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Partition int
+	Offset    int64
+	Time      time.Time
+}
+
+// Publisher 消息发布。同一 Key 的消息进入同一分区，保证分区内有序。
+type Publisher interface {
+	Publish(ctx context.Context, msgs ...Message) error
+}
+
+// Subscriber 消费组内的一个消费者。Fetch 不会自动提交位点，
+// 处理成功后需调用 Commit，未提交的消息在重启或重新分配分区后会被再次投递。
+type Subscriber interface {
+	// Fetch 阻塞直到取得下一条消息或 ctx 结束
+	Fetch(ctx context.Context) (Message, error)
+	// Commit 提交消息位点，之后同组的消费者从下一条开始消费
+	Commit(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// Bus 发布与订阅的总线
+type Bus interface {
+	Publisher
+	// Subscribe 以消费组 group 订阅 topic，同组消费者分摊分区，不同组各自消费全部消息
+	Subscribe(topic, group string) (Subscriber, error)
+	// ResetGroup 将消费组在 topic 上的位点重置到最早的消息，用于重建读模型。
+	// 消费组内不能有活跃的消费者。
+	ResetGroup(ctx context.Context, topic, group string) error
+	Close() error
+}
@@ -0,0 +1,140 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBus 基于 Kafka 的总线
+type KafkaBus struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+func NewKafkaBus(brokers []string) *KafkaBus {
+	return &KafkaBus{
+		brokers: brokers,
+		// 主题由每条消息指定，按 Key 哈希分区
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+func (b *KafkaBus) Publish(ctx context.Context, msgs ...Message) error {
+	out := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		out[i] = kafka.Message{Topic: m.Topic, Key: m.Key, Value: m.Value, Headers: toKafkaHeaders(m.Headers)}
+	}
+	return b.writer.WriteMessages(ctx, out...)
+}
+
+func (b *KafkaBus) Subscribe(topic, group string) (Subscriber, error) {
+	return &kafkaSubscriber{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  b.brokers,
+			Topic:    topic,
+			GroupID:  group,
+			MinBytes: 10e3, // 10KB
+			MaxBytes: 10e6, // 10MB
+		}),
+	}, nil
+}
+
+// ResetGroup 查询各分区最早的位点并以消费组名义提交，消费组需为空（成员已退出）
+func (b *KafkaBus) ResetGroup(ctx context.Context, topic, group string) error {
+	client := &kafka.Client{Addr: kafka.TCP(b.brokers...)}
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	if len(meta.Topics) != 1 || meta.Topics[0].Error != nil {
+		return fmt.Errorf("topic %s metadata: %v", topic, meta.Topics)
+	}
+	requests := make([]kafka.OffsetRequest, 0, len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return err
+	}
+	commits := make([]kafka.OffsetCommit, 0, len(requests))
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return fmt.Errorf("topic %s partition %d: %w", topic, p.Partition, p.Error)
+		}
+		commits = append(commits, kafka.OffsetCommit{Partition: p.Partition, Offset: p.FirstOffset})
+	}
+
+	resp, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1, // 不属于任何一代成员的提交，仅在消费组为空时被接受
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return fmt.Errorf("reset group %s partition %d: %w", group, p.Partition, p.Error)
+		}
+	}
+	return nil
+}
+
+func (b *KafkaBus) Close() error {
+	return b.writer.Close()
+}
+
+type kafkaSubscriber struct {
+	reader *kafka.Reader
+}
+
+func (s *kafkaSubscriber) Fetch(ctx context.Context) (Message, error) {
+	m, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return Message{
+		Topic:     m.Topic,
+		Key:       m.Key,
+		Value:     m.Value,
+		Headers:   headers,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Time:      m.Time,
+	}, nil
+}
+
+func (s *kafkaSubscriber) Commit(ctx context.Context, msgs ...Message) error {
+	out := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		out[i] = kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+	}
+	return s.reader.CommitMessages(ctx, out...)
+}
+
+func (s *kafkaSubscriber) Close() error {
+	return s.reader.Close()
+}
+
+func toKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make([]kafka.Header, 0, len(headers))
+	for k, v := range headers {
+		out = append(out, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return out
+}
@@ -0,0 +1,253 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sync"
+	"time"
+)
+
+// MemoryBus 进程内总线，行为与 Kafka 一致：主题分区、按 Key 分区保证有序、
+// 消费组内按分区分摊、消费组位点在提交后才前进。消息只保存在内存中，用于测试与单机运行。
+type MemoryBus struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string]*memoryTopic
+	groups     map[groupKey]*memoryGroup
+	closed     bool
+}
+
+type memoryTopic struct {
+	logs   [][]Message
+	next   int           // 无 Key 消息轮询分区
+	notify chan struct{} // 有新消息或消费组变化时关闭并替换，唤醒等待中的消费者
+}
+
+type groupKey struct {
+	topic string
+	group string
+}
+
+// memoryGroup 消费组状态：各分区已提交位点与组内成员，成员变化时分区重新分配
+type memoryGroup struct {
+	committed  []int64
+	members    []*memorySubscriber
+	generation int
+}
+
+func NewMemoryBus(partitions int) *MemoryBus {
+	if partitions <= 0 {
+		partitions = 1
+	}
+	return &MemoryBus{
+		partitions: partitions,
+		topics:     make(map[string]*memoryTopic),
+		groups:     make(map[groupKey]*memoryGroup),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, msgs ...Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	now := time.Now()
+	touched := make(map[*memoryTopic]struct{})
+	for _, m := range msgs {
+		t := b.topic(m.Topic)
+		p := t.partition(m.Key)
+		m.Partition = p
+		m.Offset = int64(len(t.logs[p]))
+		m.Time = now
+		m.Headers = maps.Clone(m.Headers)
+		t.logs[p] = append(t.logs[p], m)
+		touched[t] = struct{}{}
+	}
+	for t := range touched {
+		t.wake()
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic, group string) (Subscriber, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	g := b.group(topic, group)
+	s := &memorySubscriber{bus: b, key: groupKey{topic, group}, generation: -1}
+	g.members = append(g.members, s)
+	g.generation++
+	b.topic(topic).wake()
+	return s, nil
+}
+
+func (b *MemoryBus) ResetGroup(ctx context.Context, topic, group string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	g := b.group(topic, group)
+	if len(g.members) > 0 {
+		return fmt.Errorf("group %s on topic %s has %d active consumers", group, topic, len(g.members))
+	}
+	clear(g.committed)
+	return nil
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, t := range b.topics {
+		t.wake()
+	}
+	return nil
+}
+
+// topic 获取主题，不存在时自动创建
+func (b *MemoryBus) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{logs: make([][]Message, b.partitions), notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *MemoryBus) group(topic, group string) *memoryGroup {
+	key := groupKey{topic, group}
+	g, ok := b.groups[key]
+	if !ok {
+		g = &memoryGroup{committed: make([]int64, b.partitions)}
+		b.groups[key] = g
+	}
+	return g
+}
+
+// partition 有 Key 时按哈希分区，无 Key 时轮询
+func (t *memoryTopic) partition(key []byte) int {
+	if len(key) == 0 {
+		p := t.next
+		t.next = (t.next + 1) % len(t.logs)
+		return p
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(t.logs)))
+}
+
+func (t *memoryTopic) wake() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+type memorySubscriber struct {
+	bus        *MemoryBus
+	key        groupKey
+	positions  map[int]int64 // 已取出但可能未提交的下一条位置
+	generation int           // 分区分配所属的消费组版本
+	next       int           // 在分配到的分区间轮询
+	closed     bool
+}
+
+func (s *memorySubscriber) Fetch(ctx context.Context) (Message, error) {
+	for {
+		s.bus.mu.Lock()
+		if s.closed || s.bus.closed {
+			s.bus.mu.Unlock()
+			return Message{}, ErrClosed
+		}
+		if m, ok := s.poll(); ok {
+			s.bus.mu.Unlock()
+			return m, nil
+		}
+		wait := s.bus.topic(s.key.topic).notify
+		s.bus.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// poll 从分配到的分区中取下一条消息，调用方需持有总线锁。
+// 消费组成员变化后从已提交位点重新开始，未提交的消息会被再次投递。
+func (s *memorySubscriber) poll() (Message, bool) {
+	g := s.bus.groups[s.key]
+	if s.generation != g.generation {
+		s.generation = g.generation
+		s.positions = make(map[int]int64)
+	}
+
+	idx := -1
+	for i, m := range g.members {
+		if m == s {
+			idx = i
+		}
+	}
+	logs := s.bus.topic(s.key.topic).logs
+	for i := 0; i < len(logs); i++ {
+		p := (s.next + i) % len(logs)
+		if p%len(g.members) != idx {
+			continue
+		}
+		pos, ok := s.positions[p]
+		if !ok {
+			pos = g.committed[p]
+		}
+		if pos < int64(len(logs[p])) {
+			s.positions[p] = pos + 1
+			s.next = p + 1
+			return logs[p][pos], true
+		}
+	}
+	return Message{}, false
+}
+
+func (s *memorySubscriber) Commit(ctx context.Context, msgs ...Message) error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	g := s.bus.groups[s.key]
+	for _, m := range msgs {
+		if m.Partition < 0 || m.Partition >= len(g.committed) {
+			continue
+		}
+		g.committed[m.Partition] = max(g.committed[m.Partition], m.Offset+1)
+	}
+	return nil
+}
+
+func (s *memorySubscriber) Close() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	g := s.bus.groups[s.key]
+	for i, m := range g.members {
+		if m == s {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+	g.generation++
+	s.bus.topic(s.key.topic).wake()
+	return nil
+}
@@ -0,0 +1,147 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func fetch(t *testing.T, s Subscriber) Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m, err := s.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// drain 取出当前可取的全部消息
+func drain(t *testing.T, s Subscriber) []Message {
+	t.Helper()
+	var list []Message
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		m, err := s.Fetch(ctx)
+		cancel()
+		if err != nil {
+			return list
+		}
+		list = append(list, m)
+	}
+}
+
+func publish(t *testing.T, b *MemoryBus, topic, key, value string) {
+	t.Helper()
+	if err := b.Publish(context.Background(), Message{Topic: topic, Key: []byte(key), Value: []byte(value)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func subscribe(t *testing.T, b *MemoryBus, topic, group string) Subscriber {
+	t.Helper()
+	s, err := b.Subscribe(topic, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestMemoryBusKeepsKeyOrder(t *testing.T) {
+	b := NewMemoryBus(4)
+	for i := 0; i < 20; i++ {
+		publish(t, b, "orders", fmt.Sprintf("o%d", i%5), fmt.Sprint(i))
+	}
+
+	// 同一 Key 的消息在同一分区，按发布顺序取出
+	last := make(map[string]int)
+	partitions := make(map[string]int)
+	for _, m := range drain(t, subscribe(t, b, "orders", "g")) {
+		key := string(m.Key)
+		var n int
+		fmt.Sscan(string(m.Value), &n)
+		if prev, ok := last[key]; ok && n <= prev {
+			t.Fatalf("key %s: message %d after %d", key, n, prev)
+		}
+		if p, ok := partitions[key]; ok && p != m.Partition {
+			t.Fatalf("key %s in partitions %d and %d", key, p, m.Partition)
+		}
+		last[key], partitions[key] = n, m.Partition
+	}
+	if len(last) != 5 {
+		t.Fatalf("got %d keys, want 5", len(last))
+	}
+}
+
+func TestMemoryBusGroupsConsumeIndependently(t *testing.T) {
+	b := NewMemoryBus(2)
+	a1 := subscribe(t, b, "orders", "a")
+	a2 := subscribe(t, b, "orders", "a")
+	other := subscribe(t, b, "orders", "b")
+	for i := 0; i < 10; i++ {
+		publish(t, b, "orders", fmt.Sprintf("o%d", i), "v")
+	}
+
+	// 组内两个成员分摊分区，另一个组收到全部消息
+	got1, got2 := drain(t, a1), drain(t, a2)
+	if len(got1)+len(got2) != 10 {
+		t.Fatalf("group a got %d + %d messages, want 10", len(got1), len(got2))
+	}
+	for _, m := range got1 {
+		for _, n := range got2 {
+			if m.Partition == n.Partition {
+				t.Fatalf("partition %d consumed by both members", m.Partition)
+			}
+		}
+	}
+	if got := drain(t, other); len(got) != 10 {
+		t.Fatalf("group b got %d messages, want 10", len(got))
+	}
+}
+
+func TestMemoryBusRedeliversUncommitted(t *testing.T) {
+	b := NewMemoryBus(1)
+	publish(t, b, "orders", "o1", "1")
+	publish(t, b, "orders", "o1", "2")
+	publish(t, b, "orders", "o1", "3")
+
+	s := subscribe(t, b, "orders", "g")
+	first := fetch(t, s)
+	if err := s.Commit(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	fetch(t, s)
+	s.Close()
+	if _, err := s.Fetch(context.Background()); err != ErrClosed {
+		t.Fatalf("fetch after close: %v", err)
+	}
+
+	// 已取出但未提交的消息由下一个成员重新取出
+	got := drain(t, subscribe(t, b, "orders", "g"))
+	if len(got) != 2 || string(got[0].Value) != "2" || string(got[1].Value) != "3" {
+		t.Fatalf("redelivered %v, want messages 2 and 3", got)
+	}
+}
+
+func TestMemoryBusResetGroup(t *testing.T) {
+	b := NewMemoryBus(1)
+	publish(t, b, "orders", "o1", "1")
+	publish(t, b, "orders", "o1", "2")
+
+	s := subscribe(t, b, "orders", "g")
+	if err := s.Commit(context.Background(), drain(t, s)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ResetGroup(context.Background(), "orders", "g"); err == nil {
+		t.Fatal("reset with an active consumer should fail")
+	}
+	s.Close()
+	if err := b.ResetGroup(context.Background(), "orders", "g"); err != nil {
+		t.Fatal(err)
+	}
+	if got := drain(t, subscribe(t, b, "orders", "g")); len(got) != 2 {
+		t.Fatalf("got %d messages after reset, want 2", len(got))
+	}
+}
@@ -0,0 +1,252 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/utils"
+)
+
+// 重试与死信消息携带的失败元数据消息头
+const (
+	HeaderOriginalTopic     = "x-original-topic"     // 最初发布的主题
+	HeaderOriginalPartition = "x-original-partition" // 在最初主题中的分区
+	HeaderOriginalOffset    = "x-original-offset"    // 在最初主题中的位点
+	HeaderGroup             = "x-consumer-group"     // 处理失败的消费组
+	HeaderAttempts          = "x-attempts"           // 累计处理次数
+	HeaderError             = "x-error"              // 最后一次失败的错误
+	HeaderFailedAt          = "x-failed-at"          // 最后一次失败的时间，RFC3339
+	HeaderNotBefore         = "x-not-before"         // 重试主题中的消息最早可处理的时间，RFC3339
+	HeaderDeadLetterID      = "x-dead-letter-id"     // 死信编号，用于去重
+)
+
+// maxErrorHeaderLen 错误信息消息头的最大长度
+const maxErrorHeaderLen = 1000
+
+// RetryTopic 消费组 group 的第 level 级（从 1 开始）重试主题。
+// 重试主题按消费组区分，一个组处理失败的消息只会由该组重新处理，不会再次投递给其他组
+func RetryTopic(topic, group string, level int) string {
+	return fmt.Sprintf("%s.%s.retry.%d", topic, group, level)
+}
+
+// ReplayTopic 消费组 group 的重放主题，死信重放到处理失败的组，与主主题同级处理
+func ReplayTopic(topic, group string) string {
+	return fmt.Sprintf("%s.%s.replay", topic, group)
+}
+
+// DeadLetterTopic 死信主题，各消费组共用，消息头 HeaderGroup 记录处理失败的组
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记不可重试的错误（如消息无法解析），消息直接进入死信主题
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent 错误是否不可重试
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// RetryPolicy 消费失败的重试策略：每一级主题内按指数退避最多处理 Attempts 次，
+// 仍失败时转入下一级重试主题，在 Delays 指定的延迟后再次处理，全部失败后进入死信主题。
+type RetryPolicy struct {
+	Attempts   int             // 每一级主题内的最大处理次数
+	Backoff    time.Duration   // 同一级内首次重试的等待时间，之后翻倍
+	MaxBackoff time.Duration   // 退避上限，也用于拉取消息失败时的等待
+	Delays     []time.Duration // 各级重试主题的延迟，长度即重试主题的级数
+}
+
+// Consumer 带重试主题链与死信主题的消费者。主主题、本组的重放主题与各级重试主题使用同一个消费组，
+// 消息处理成功、转入下一级或进入死信主题后才提交位点。
+type Consumer struct {
+	bus    Bus
+	topic  string
+	group  string
+	policy RetryPolicy
+	handle func(ctx context.Context, msg Message) error
+}
+
+func NewConsumer(b Bus, topic, group string, policy RetryPolicy, handle func(ctx context.Context, msg Message) error) *Consumer {
+	if policy.Attempts <= 0 {
+		policy.Attempts = 1
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = policy.Backoff
+	}
+	return &Consumer{bus: b, topic: topic, group: group, policy: policy, handle: handle}
+}
+
+// Start 订阅主主题、本组的重放主题与各级重试主题并在后台消费，直到 ctx 结束；全部消费协程退出后关闭返回的通道
+func (c *Consumer) Start(ctx context.Context) (<-chan struct{}, error) {
+	// 重放的死信与主主题中的消息同级，重新走完整的重试链
+	topics := []string{c.topic, ReplayTopic(c.topic, c.group)}
+	levels := []int{0, 0}
+	for level := 1; level <= len(c.policy.Delays); level++ {
+		topics = append(topics, RetryTopic(c.topic, c.group, level))
+		levels = append(levels, level)
+	}
+	subs := make([]Subscriber, 0, len(topics))
+	for _, topic := range topics {
+		sub, err := c.bus.Subscribe(topic, c.group)
+		if err != nil {
+			for _, s := range subs {
+				s.Close()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sub.Close()
+			c.consume(ctx, sub, levels[i])
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done, nil
+}
+
+func (c *Consumer) consume(ctx context.Context, sub Subscriber, level int) {
+	backoff := c.policy.Backoff
+	for {
+		msg, err := sub.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+				return
+			}
+			// 连接异常时退避，避免空转
+			fmt.Printf("拉取消息失败，%v 后重试: %s: %v\n", backoff, c.topic, err)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, c.policy.MaxBackoff)
+			continue
+		}
+		backoff = c.policy.Backoff
+
+		// 重试主题中的消息等到延迟时间后再处理
+		if notBefore, err := time.Parse(time.RFC3339Nano, msg.Headers[HeaderNotBefore]); err == nil {
+			if !sleep(ctx, time.Until(notBefore)) {
+				return
+			}
+		}
+
+		attempts, err := c.process(ctx, msg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !c.forward(ctx, msg, level, attempts, err) {
+				return
+			}
+		}
+		if err := sub.Commit(ctx, msg); err != nil && ctx.Err() == nil {
+			fmt.Printf("提交消费位点失败: %s: %v\n", msg.Topic, err)
+		}
+	}
+}
+
+// process 在当前级内按指数退避处理消息，返回处理次数与最后一次的错误
+func (c *Consumer) process(ctx context.Context, msg Message) (int, error) {
+	backoff := c.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := c.handle(ctx, msg)
+		if err == nil || IsPermanent(err) || attempt == c.policy.Attempts {
+			return attempt, err
+		}
+		if !sleep(ctx, backoff) {
+			return attempt, ctx.Err()
+		}
+		backoff = min(backoff*2, c.policy.MaxBackoff)
+	}
+}
+
+// forward 将处理失败的消息转入下一级重试主题或死信主题，发布失败时退避重试直到成功；ctx 结束时返回 false
+func (c *Consumer) forward(ctx context.Context, msg Message, level, attempts int, cause error) bool {
+	headers := maps.Clone(msg.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	// 重放的死信保留最初的位置
+	if _, ok := headers[HeaderOriginalTopic]; !ok {
+		headers[HeaderOriginalTopic] = msg.Topic
+		headers[HeaderOriginalPartition] = strconv.Itoa(msg.Partition)
+		headers[HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+	}
+	total, _ := strconv.Atoi(headers[HeaderAttempts])
+	now := time.Now()
+	reason := cause.Error()
+	if len(reason) > maxErrorHeaderLen {
+		reason = reason[:maxErrorHeaderLen]
+	}
+	headers[HeaderGroup] = c.group
+	headers[HeaderAttempts] = strconv.Itoa(total + attempts)
+	headers[HeaderError] = reason
+	headers[HeaderFailedAt] = now.Format(time.RFC3339Nano)
+
+	next := level + 1
+	out := Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+	if IsPermanent(cause) || next > len(c.policy.Delays) {
+		out.Topic = DeadLetterTopic(c.topic)
+		delete(headers, HeaderNotBefore)
+		headers[HeaderDeadLetterID] = utils.NewUuid()
+		fmt.Printf("消息进入死信主题 %s: %s: %v\n", out.Topic, headers[HeaderOriginalTopic], cause)
+	} else {
+		out.Topic = RetryTopic(c.topic, c.group, next)
+		headers[HeaderNotBefore] = now.Add(c.policy.Delays[next-1]).Format(time.RFC3339Nano)
+	}
+
+	backoff := c.policy.Backoff
+	for {
+		err := c.bus.Publish(ctx, out)
+		if err == nil {
+			return true
+		}
+		fmt.Printf("转发失败消息到 %s 失败，%v 后重试: %v\n", out.Topic, backoff, err)
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = min(backoff*2, c.policy.MaxBackoff)
+	}
+}
+
+// sleep 等待 d 或 ctx 结束，ctx 结束时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
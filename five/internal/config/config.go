@@ -5,7 +5,11 @@ import "github.com/zeromicro/go-zero/rest"
 type Config struct {
 	rest.RestConf
 	MySQL struct {
-		DSN string
+		DSN string `json:",optional"`
+	}
+	Storage struct {
+		Driver string `json:",default=mysql,options=mysql|sqlite"` // 订单存储：mysql，或单机开发用的 sqlite
+		SQLite string `json:",default=order.db"`                   // sqlite 数据库文件
 	}
 	Redis struct {
 		Addr     string
@@ -13,6 +17,89 @@ type Config struct {
 		DB       int
 	}
 	Kafka struct {
-		Brokers []string
+		Brokers  []string `json:",optional"`
+		Topic    string   `json:",default=orders"`      // 订单事件主题
+		Group    string   `json:",default=order-group"` // 订单事件消费组
+		Instance string   `json:",optional"`            // 实例标识，行情与用户数据流推送按实例建消费组以收到全部订单事件，默认取主机名
+	}
+	Event struct {
+		Encoding string `json:",default=json,options=json|protobuf"` // 订单事件编码方式，消费者按消息头自动识别
+	}
+	EventStore struct {
+		SnapshotIntervalSec int `json:",default=300"`  // 检查是否需要生成快照的间隔
+		SnapshotMinEvents   int `json:",default=1000"` // 距上一个快照至少新增多少个事件才生成新快照
+		SnapshotKeep        int `json:",default=48"`   // 保留最近的快照个数，0 表示全部保留
+		SnapshotSettleSec   int `json:",default=10"`   // 事件发生后至少经过多久才纳入快照
+	}
+	Bus struct {
+		Driver     string `json:",default=kafka,options=kafka|memory"` // 消息总线：kafka，或无需 Kafka 的进程内总线 memory
+		Partitions int    `json:",default=4"`                          // memory 总线每个主题的分区数
+	}
+	Outbox struct {
+		BatchSize      int `json:",default=100"`   // 每批发布的消息数
+		PollIntervalMs int `json:",default=200"`   // 无待发布消息时的轮询间隔
+		MaxBackoffMs   int `json:",default=30000"` // 发布失败后指数退避的上限
+		RetentionHours int `json:",default=24"`    // 已发送消息的保留时长，0 表示不清理
+	}
+	Projection struct {
+		Attempts       int   `json:",default=3"`     // 主主题与每一级重试主题内的最大处理次数
+		RetryBackoffMs int   `json:",default=100"`   // 事件处理失败后首次重试的等待时间
+		MaxBackoffMs   int   `json:",default=30000"` // 重试退避上限
+		RetryDelaysMs  []int `json:",optional"`      // 各级重试主题的延迟，未配置时为 5s、1m 两级
+	}
+	Admin struct {
+		Token string `json:",optional"` // 管理接口令牌，为空时禁用管理接口
+	}
+	Auth struct {
+		AccessSecret string `json:",optional"` // 用户接口 JWT 的签名密钥，令牌的 userId 声明为用户 ID；为空时禁用需登录的接口
+	}
+	Market struct {
+		DepthLevels      int `json:",default=1000"` // 深度快照的档数，0 表示全部
+		TickerIntervalMs int `json:",default=1000"` // 24 小时行情的推送间隔
+		UpdateBuffer     int `json:",default=4096"` // 订单簿变更的缓冲队列长度
+		MaxSubscriptions int `json:",default=50"`   // 每个连接最多订阅的频道数
+		SendBuffer       int `json:",default=256"`  // 每个连接的待发送消息队列长度，写满时断开该连接
+		WriteTimeoutMs   int `json:",default=5000"` // 单条消息的写超时
+		PingIntervalSec  int `json:",default=30"`   // 心跳间隔
+	}
+	Kline struct {
+		DefaultLimit int `json:",default=500"`  // 查询未指定 limit 时返回的根数
+		MaxLimit     int `json:",default=1000"` // 单次查询最多返回的根数
+	}
+	UserStream struct {
+		ListenKeyTTLMin  int `json:",default=60"` // listen key 的有效期，续期后从续期时起重新计算
+		CheckIntervalSec int `json:",default=30"` // 检查连接所用 listen key 是否失效的间隔
+	}
+	Matching struct {
+		MaxSlippage     float64 `json:",default=0.05"`  // 市价单相对最优价的最大滑点
+		PostOnlyReprice bool    `json:",default=false"` // POST_ONLY 会吃单时改价而不是拒绝
+		PriceTick       float64 `json:",default=0.01"`  // POST_ONLY 改价的价格步长
+		AmountScale     int32   `json:",default=8"`     // 市价买单按金额换算数量保留的小数位
+		Journal         struct {
+			Dir                 string `json:",default=data/matching"` // 预写日志与订单簿快照所在的本地目录
+			Sync                bool   `json:",default=true"`          // 每条命令写入后 fsync
+			SnapshotIntervalSec int    `json:",default=60"`            // 订单簿快照间隔，缩短启动时需要重放的日志
+			SnapshotKeep        int    `json:",default=2"`             // 保留的快照个数，最新的快照损坏时回退到更早的快照
+			Strict              bool   `json:",default=true"`          // 恢复结果与数据库不一致时拒绝启动，否则按数据库重建订单簿
+		}
+	}
+	// 费率与金额按十进制字符串配置，避免浮点误差，yaml 中需加引号
+	Fee struct {
+		MakerRate   string `json:",default=0.001"` // VIP0 maker 费率
+		TakerRate   string `json:",default=0.001"` // VIP0 taker 费率
+		WindowDays  int    `json:",default=30"`    // VIP 等级按近多少天的成交额计算
+		VolumeAsset string `json:",default=USDT"`  // 统计成交额的计价币，其他计价币的交易对不计入
+		Tiers       []struct {
+			MinVolume string // 达到该等级所需的成交额（VolumeAsset）
+			MakerRate string
+			TakerRate string
+		} `json:",optional"`
+		Overrides []struct {
+			Symbol    string
+			MakerRate string
+			TakerRate string
+		} `json:",optional"` // 交易对专属费率，优先于 VIP 等级
+		Token         string `json:",optional"`     // 可抵扣手续费的平台币，为空时不启用
+		TokenDiscount string `json:",default=0.25"` // 平台币抵扣折扣
 	}
 }
@@ -0,0 +1,215 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 查询与批量重放的默认条数与上限
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Options 死信落库参数
+type Options struct {
+	Topic      string        // 订单事件主题，落库其死信主题
+	Group      string        // 落库使用的消费组
+	Backoff    time.Duration // 写库失败后首次重试的等待时间，之后翻倍
+	MaxBackoff time.Duration // 退避上限
+}
+
+// Sink 消费死信主题并写入 dead_letters 表，供管理员查询与重放。
+// 写库成功后才提交位点；同一条死信重复投递时按死信编号去重。
+type Sink struct {
+	db   *gorm.DB
+	bus  bus.Bus
+	opts Options
+}
+
+func NewSink(db *gorm.DB, b bus.Bus, opts Options) *Sink {
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = opts.Backoff
+	}
+	return &Sink{db: db, bus: b, opts: opts}
+}
+
+// Start 订阅死信主题并在后台落库，直到 ctx 结束
+func (s *Sink) Start(ctx context.Context) error {
+	sub, err := s.bus.Subscribe(bus.DeadLetterTopic(s.opts.Topic), s.opts.Group)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer sub.Close()
+		s.run(ctx, sub)
+	}()
+	return nil
+}
+
+func (s *Sink) run(ctx context.Context, sub bus.Subscriber) {
+	backoff := s.opts.Backoff
+	for {
+		msg, err := sub.Fetch(ctx)
+		if err == nil {
+			err = s.save(ctx, msg)
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, bus.ErrClosed) {
+				return
+			}
+			fmt.Printf("死信落库失败，%v 后重试: %v\n", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, s.opts.MaxBackoff)
+			continue
+		}
+		backoff = s.opts.Backoff
+		if err := sub.Commit(ctx, msg); err != nil && ctx.Err() == nil {
+			fmt.Printf("提交死信位点失败: %v\n", err)
+		}
+	}
+}
+
+// save 写入一条死信，已存在时忽略。失败时同一条消息会在下一次 Fetch 前重新处理
+func (s *Sink) save(ctx context.Context, msg bus.Message) error {
+	letter := fromMessage(msg)
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "dead_letter_id"}}, DoNothing: true}).
+		Create(letter).Error
+}
+
+// fromMessage 从死信消息头还原失败元数据
+func fromMessage(msg bus.Message) *types.DeadLetter {
+	h := msg.Headers
+	letter := &types.DeadLetter{
+		DeadLetterID:  h[bus.HeaderDeadLetterID],
+		OriginalTopic: h[bus.HeaderOriginalTopic],
+		ConsumerGroup: h[bus.HeaderGroup],
+		Key:           string(msg.Key),
+		Payload:       msg.Value,
+		Headers:       h,
+		Error:         h[bus.HeaderError],
+	}
+	if letter.DeadLetterID == "" {
+		// 非经重试链进入死信主题的消息，以分区与位点作为编号
+		letter.DeadLetterID = fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+	}
+	if letter.OriginalTopic == "" {
+		letter.OriginalTopic = msg.Topic
+	}
+	letter.Attempts, _ = strconv.Atoi(h[bus.HeaderAttempts])
+	if failedAt, err := time.Parse(time.RFC3339Nano, h[bus.HeaderFailedAt]); err == nil {
+		letter.FailedAt = &failedAt
+	}
+	if len(letter.Error) > 1000 {
+		letter.Error = letter.Error[:1000]
+	}
+	return letter
+}
+
+// List 按状态查询死信，最新的在前。status 为空时返回全部
+func List(db *gorm.DB, status types.DeadLetterStatus, limit int) ([]types.DeadLetter, error) {
+	limit = clampLimit(limit)
+	query := db.Order("id DESC").Limit(limit)
+	switch status {
+	case types.DeadLetterPending:
+		query = query.Where("replayed_at IS NULL")
+	case types.DeadLetterReplayed:
+		query = query.Where("replayed_at IS NOT NULL")
+	case "":
+	default:
+		return nil, fmt.Errorf("invalid status %q", status)
+	}
+	var letters []types.DeadLetter
+	if err := query.Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// Replay 将死信按原 Key 与内容重新发布到处理失败的消费组的重放主题，并记录重放时间，其他消费组不会再次收到。
+// 重放的消息只保留最初的主题、分区与位点，重新走该组的重试链；消费者需幂等处理。
+func Replay(ctx context.Context, db *gorm.DB, publisher bus.Publisher, id uint) (*types.DeadLetter, error) {
+	var letter types.DeadLetter
+	if err := db.WithContext(ctx).First(&letter, id).Error; err != nil {
+		return nil, err
+	}
+	if err := replay(ctx, db, publisher, &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// ReplayPending 重放最多 limit 条尚未重放的死信，按进入死信的顺序发布，返回重放条数
+func ReplayPending(ctx context.Context, db *gorm.DB, publisher bus.Publisher, limit int) (int, error) {
+	limit = clampLimit(limit)
+	var letters []types.DeadLetter
+	if err := db.WithContext(ctx).Where("replayed_at IS NULL").Order("id ASC").Limit(limit).Find(&letters).Error; err != nil {
+		return 0, err
+	}
+	for i := range letters {
+		if err := replay(ctx, db, publisher, &letters[i]); err != nil {
+			return i, fmt.Errorf("replay dead letter %d: %w", letters[i].ID, err)
+		}
+	}
+	return len(letters), nil
+}
+
+func replay(ctx context.Context, db *gorm.DB, publisher bus.Publisher, letter *types.DeadLetter) error {
+	headers := maps.Clone(letter.Headers)
+	for _, k := range []string{
+		bus.HeaderGroup, bus.HeaderAttempts, bus.HeaderError, bus.HeaderFailedAt,
+		bus.HeaderNotBefore, bus.HeaderDeadLetterID,
+	} {
+		delete(headers, k)
+	}
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[bus.HeaderOriginalTopic] = letter.OriginalTopic
+	// 不经重试链直接进入死信主题的消息没有消费组，只能发布回最初的主题
+	topic := letter.OriginalTopic
+	if letter.ConsumerGroup != "" {
+		topic = bus.ReplayTopic(letter.OriginalTopic, letter.ConsumerGroup)
+	}
+	if err := publisher.Publish(ctx, bus.Message{
+		Topic:   topic,
+		Key:     []byte(letter.Key),
+		Value:   letter.Payload,
+		Headers: headers,
+	}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	letter.ReplayedAt = &now
+	letter.Replays++
+	return db.WithContext(ctx).Model(&types.DeadLetter{}).Where("id = ?", letter.ID).Updates(map[string]interface{}{
+		"replayed_at": &now,
+		"replays":     gorm.Expr("replays + 1"),
+	}).Error
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	return min(limit, maxLimit)
+}
@@ -0,0 +1,116 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/repository"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// counter 按消费组记录处理次数
+type counter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *counter) handle(group string, fail func(n int) bool) func(ctx context.Context, msg bus.Message) error {
+	return func(ctx context.Context, msg bus.Message) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.calls[group]++
+		if fail(c.calls[group]) {
+			return errors.New("projection unavailable")
+		}
+		return nil
+	}
+}
+
+func (c *counter) get(group string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[group]
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func pending(t *testing.T, db *gorm.DB) []types.DeadLetter {
+	t.Helper()
+	letters, err := List(db, types.DeadLetterPending, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return letters
+}
+
+func TestRetryAndReplayStayInFailedGroup(t *testing.T) {
+	db, err := repository.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bus.NewMemoryBus(2)
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	policy := bus.RetryPolicy{Attempts: 1, Backoff: time.Millisecond, Delays: []time.Duration{time.Millisecond}}
+	calls := &counter{calls: make(map[string]int)}
+	// a 组在重试链内（主主题与一级重试）两次都失败，重放后成功；b 组一次成功
+	consumers := []*bus.Consumer{
+		bus.NewConsumer(b, "orders", "a", policy, calls.handle("a", func(n int) bool { return n <= 2 })),
+		bus.NewConsumer(b, "orders", "b", policy, calls.handle("b", func(int) bool { return false })),
+	}
+	for _, c := range consumers {
+		if _, err := c.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewSink(db, b, Options{Topic: "orders", Group: "dlq"}).Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish(ctx, bus.Message{Topic: "orders", Key: []byte("o1"), Value: []byte("created")}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "dead letter", func() bool { return len(pending(t, db)) == 1 })
+
+	letter := pending(t, db)[0]
+	if letter.ConsumerGroup != "a" || letter.OriginalTopic != "orders" || letter.Attempts != 2 {
+		t.Fatalf("dead letter %+v, want group a from orders after 2 attempts", letter)
+	}
+	if got := calls.get("b"); got != 1 {
+		t.Fatalf("group b handled %d times, want 1: retries must not reach other groups", got)
+	}
+
+	if n, err := ReplayPending(ctx, db, b, 0); err != nil || n != 1 {
+		t.Fatalf("replayed %d: %v", n, err)
+	}
+	waitFor(t, "replay", func() bool { return calls.get("a") == 3 })
+
+	// 给其他组留出收到消息的时间
+	time.Sleep(50 * time.Millisecond)
+	if got := calls.get("b"); got != 1 {
+		t.Fatalf("group b handled %d times after replay, want 1", got)
+	}
+	if got := calls.get("a"); got != 3 {
+		t.Fatalf("group a handled %d times, want 3", got)
+	}
+	if letters := pending(t, db); len(letters) != 0 {
+		t.Fatalf("replayed message dead-lettered again: %+v", letters)
+	}
+}
@@ -0,0 +1,108 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// HeaderContentType 消息头中的事件编码方式，未设置时按 JSON 解码
+const HeaderContentType = "content-type"
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ErrUnsupportedVersion 事件结构版本高于当前程序支持的版本，需升级消费者后重放
+var ErrUnsupportedVersion = errors.New("unsupported event schema version")
+
+// Codec 事件编码方式
+type Codec interface {
+	ContentType() string
+	Marshal(e *Event) ([]byte, error)
+	// Unmarshal 解码一条消息。早期版本的一条消息可能对应多个事件
+	Unmarshal(data []byte) ([]*Event, error)
+}
+
+// NewCodec 按名称选择编码方式：json 或 protobuf
+func NewCodec(encoding string) (Codec, error) {
+	switch encoding {
+	case "", "json":
+		return JSON, nil
+	case "protobuf":
+		return Protobuf, nil
+	}
+	return nil, fmt.Errorf("unknown event encoding %q", encoding)
+}
+
+// Encode 编码事件，返回消息内容与需随消息发布的消息头
+func Encode(c Codec, e *Event) ([]byte, map[string]string, error) {
+	data, err := c.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, map[string]string{HeaderContentType: c.ContentType()}, nil
+}
+
+// Decode 按消息头中的编码方式解码事件
+func Decode(data []byte, headers map[string]string) ([]*Event, error) {
+	switch ct := headers[HeaderContentType]; ct {
+	case "", ContentTypeJSON:
+		return JSON.Unmarshal(data)
+	case ContentTypeProtobuf:
+		return Protobuf.Unmarshal(data)
+	default:
+		return nil, fmt.Errorf("unknown event content type %q", ct)
+	}
+}
+
+// JSON 以 JSON 编码事件
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+func (jsonCodec) Marshal(e *Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// jsonEnvelope 解码用的事件信封，同时兼容版本 1 的消息字段
+type jsonEnvelope struct {
+	Event
+	Payload json.RawMessage `json:"payload"`
+
+	legacyMessage
+}
+
+func (jsonCodec) Unmarshal(data []byte) ([]*Event, error) {
+	var env jsonEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Version == 0 && env.Action != "" {
+		return env.legacyMessage.upgrade(env.OrderID)
+	}
+	if err := checkVersion(env.Version); err != nil {
+		return nil, err
+	}
+
+	payload := newPayload(env.Type)
+	if payload == nil {
+		return nil, fmt.Errorf("unknown event type %q", env.Type)
+	}
+	if err := json.Unmarshal(env.Payload, payload); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", env.Type, err)
+	}
+	e := env.Event
+	e.Payload = payload
+	return []*Event{&e}, nil
+}
+
+func checkVersion(version int) error {
+	if version != SchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	return nil
+}
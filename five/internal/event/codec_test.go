@@ -0,0 +1,208 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+var testTime = time.Date(2026, 3, 1, 8, 30, 0, 123456789, time.Local)
+
+func testOrder() Order {
+	triggered := testTime.Add(time.Second)
+	return Order{
+		OrderID:       "o1",
+		ClientOrderID: "c1",
+		UserID:        1001,
+		Symbol:        "BTC/USDT",
+		OrderType:     types.OrderTypeLimit,
+		OrderSide:     types.OrderSideBuy,
+		Price:         dec("5000.5"),
+		Amount:        dec("0.3"),
+		FilledAmount:  dec("0.1"),
+		FilledQuote:   dec("500.05"),
+		Fee:           dec("0.0001"),
+		FeeAsset:      "BTC",
+		Frozen:        dec("1000.1"),
+		TriggerType:   types.OrderTypeStopLimit,
+		TriggerPrice:  dec("4900"),
+		TriggeredAt:   &triggered,
+		TimeInForce:   types.TimeInForceGTC,
+		Status:        types.OrderStatusPartFilled,
+		Version:       3,
+		CreatedAt:     testTime,
+		UpdatedAt:     triggered,
+	}
+}
+
+func testTrade() Trade {
+	return Trade{
+		TradeID:        "t1",
+		MatchID:        "m1",
+		OrderID:        "o1",
+		CounterOrderID: "o2",
+		UserID:         1001,
+		Symbol:         "BTC/USDT",
+		Side:           types.OrderSideBuy,
+		Price:          dec("5000.5"),
+		Amount:         dec("0.1"),
+		Role:           types.LiquidityTaker,
+		FeeRate:        dec("0.001"),
+		Fee:            dec("0.0001"),
+		FeeAsset:       "BTC",
+		CreatedAt:      testTime,
+	}
+}
+
+// testEvents 每种事件类型各一个
+func testEvents() []*Event {
+	order := testOrder()
+	payloads := []Payload{
+		&OrderAccepted{Order: order},
+		&OrderTriggered{Order: order},
+		&OrderRepriced{Order: order},
+		&OrderFilled{Order: order, TradeID: "t1"},
+		&OrderCancelled{Order: order, Reason: "expired"},
+		&OrderRejected{Order: order, Reason: "post only"},
+		&TradeExecuted{Trade: testTrade()},
+	}
+	events := make([]*Event, len(payloads))
+	for i, p := range payloads {
+		events[i] = New("o1", p)
+		events[i].OccurredAt = testTime
+	}
+	return events
+}
+
+// assertSameEvent 以 JSON 比较事件，十进制数与时间按值比较
+func assertSameEvent(t *testing.T, want, got *Event) {
+	t.Helper()
+	w, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(w) != string(g) {
+		t.Fatalf("decoded %s\nwant %s", g, w)
+	}
+	if reflect.TypeOf(got.Payload) != reflect.TypeOf(want.Payload) {
+		t.Fatalf("payload %T, want %T", got.Payload, want.Payload)
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSON, Protobuf} {
+		for _, e := range testEvents() {
+			data, headers, err := Encode(codec, e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if headers[HeaderContentType] != codec.ContentType() {
+				t.Fatalf("content type %q, want %q", headers[HeaderContentType], codec.ContentType())
+			}
+			events, err := Decode(data, headers)
+			if err != nil {
+				t.Fatalf("%s %s: %v", codec.ContentType(), e.Type, err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("decoded %d events, want 1", len(events))
+			}
+			assertSameEvent(t, e, events[0])
+		}
+	}
+}
+
+func TestDecodeRejectsNewerVersion(t *testing.T) {
+	for _, codec := range []Codec{JSON, Protobuf} {
+		e := testEvents()[0]
+		e.Version = SchemaVersion + 1
+		data, headers, err := Encode(codec, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(data, headers); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("%s: got %v, want ErrUnsupportedVersion", codec.ContentType(), err)
+		}
+	}
+	if _, err := Decode([]byte("{}"), map[string]string{HeaderContentType: "text/plain"}); err == nil {
+		t.Fatal("expected unknown content type error")
+	}
+}
+
+// legacy 版本 1 的消息，发布时没有消息头
+func legacy(t *testing.T, action string, order *types.Order, trade *types.Trade) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"action":   action,
+		"order_id": order.OrderID,
+		"data":     order,
+		"trade":    trade,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeUpgradesLegacyMessages(t *testing.T) {
+	o := testOrder()
+	order := o.Model()
+
+	for action, want := range map[string]Type{
+		"create":  TypeOrderAccepted,
+		"trigger": TypeOrderTriggered,
+		"update":  TypeOrderFilled,
+		"cancel":  TypeOrderCancelled,
+		"reject":  TypeOrderRejected,
+	} {
+		events, err := Decode(legacy(t, action, order, nil), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+		if len(events) != 1 || events[0].Type != want || events[0].Version != SchemaVersion {
+			t.Fatalf("%s: decoded %+v, want one %s event", action, events, want)
+		}
+		got := events[0].Order()
+		if got.OrderID != "o1" || !got.FilledAmount.Equal(order.FilledAmount) || got.Status != order.Status {
+			t.Fatalf("%s: order %+v", action, got)
+		}
+	}
+
+	// fill 消息拆分为成交与订单状态两个事件，重复投递得到相同的事件编号
+	tr := testTrade()
+	trade := tr.Model()
+	first, err := Decode(legacy(t, "fill", order, trade), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Decode(legacy(t, "fill", order, trade), nil)
+	if len(first) != 2 || first[0].Type != TypeTradeExecuted || first[1].Type != TypeOrderFilled {
+		t.Fatalf("fill decoded to %+v", first)
+	}
+	if first[0].ID != "v1-o1-3-fill-trade" || first[1].ID != "v1-o1-3-fill" || first[0].ID != again[0].ID {
+		t.Fatalf("event ids %s %s, want stable ids", first[0].ID, first[1].ID)
+	}
+	if first[0].Trade().TradeID != "t1" || first[1].Payload.(*OrderFilled).TradeID != "t1" {
+		t.Fatalf("fill events do not reference trade t1: %+v %+v", first[0].Payload, first[1].Payload)
+	}
+
+	if _, err := Decode(legacy(t, "explode", order, nil), nil); err == nil {
+		t.Fatal("expected unknown action error")
+	}
+	if _, err := Decode([]byte(`{"action":"create","order_id":"o1"}`), nil); err == nil {
+		t.Fatal("expected missing order data error")
+	}
+}
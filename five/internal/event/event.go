@@ -0,0 +1,301 @@
+package event
+
+import (
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeromicro/go-zero/core/utils"
+)
+
+// SchemaVersion 当前的事件结构版本。
+// 版本 1 为早期的 {action, order_id, data, trade} 消息，解码时转换为当前版本的事件。
+const SchemaVersion = 2
+
+// Type 事件类型
+type Type string
+
+const (
+	TypeOrderAccepted  Type = "OrderAccepted"  // 订单已受理，进入订单簿或触发队列
+	TypeOrderTriggered Type = "OrderTriggered" // 条件单已触发
+	TypeOrderRepriced  Type = "OrderRepriced"  // POST_ONLY 订单改价后挂单
+	TypeOrderFilled    Type = "OrderFilled"    // 订单成交后的状态，Status 为 part_filled 或 filled
+	TypeOrderCancelled Type = "OrderCancelled" // 订单已取消（含系统取消与到期）
+	TypeOrderRejected  Type = "OrderRejected"  // 订单已拒绝
+	TypeTradeExecuted  Type = "TradeExecuted"  // 订单产生一条成交记录
+)
+
+// Event 订单事件。同一订单的事件以订单号为消息 Key 按发生顺序发布
+type Event struct {
+	ID         string    `json:"id"`          // 事件编号，全局唯一，消费者可据此去重
+	Type       Type      `json:"type"`        // 事件类型
+	Version    int       `json:"version"`     // 事件结构版本
+	OrderID    string    `json:"order_id"`    // 所属订单
+	OccurredAt time.Time `json:"occurred_at"` // 事件发生时间
+	Payload    Payload   `json:"payload"`     // 与 Type 对应的事件内容
+}
+
+// Payload 事件内容
+type Payload interface {
+	EventType() Type
+}
+
+// New 创建当前版本的事件
+func New(orderID string, payload Payload) *Event {
+	return &Event{
+		ID:         utils.NewUuid(),
+		Type:       payload.EventType(),
+		Version:    SchemaVersion,
+		OrderID:    orderID,
+		OccurredAt: time.Now(),
+		Payload:    payload,
+	}
+}
+
+// Order 事件携带的订单状态，不携带订单状态的事件返回 nil
+func (e *Event) Order() *Order {
+	switch p := e.Payload.(type) {
+	case *OrderAccepted:
+		return &p.Order
+	case *OrderTriggered:
+		return &p.Order
+	case *OrderRepriced:
+		return &p.Order
+	case *OrderFilled:
+		return &p.Order
+	case *OrderCancelled:
+		return &p.Order
+	case *OrderRejected:
+		return &p.Order
+	}
+	return nil
+}
+
+// Trade 事件携带的成交记录，非 TradeExecuted 事件返回 nil
+func (e *Event) Trade() *Trade {
+	if p, ok := e.Payload.(*TradeExecuted); ok {
+		return &p.Trade
+	}
+	return nil
+}
+
+// OrderAccepted 订单已受理：普通订单状态为 pending，条件单为 untriggered
+type OrderAccepted struct {
+	Order Order `json:"order"`
+}
+
+// OrderTriggered 条件单已触发，OrderType 已转为实际执行的类型
+type OrderTriggered struct {
+	Order Order `json:"order"`
+}
+
+// OrderRepriced POST_ONLY 订单按对手价改价，Price 为改价后的挂单价格
+type OrderRepriced struct {
+	Order Order `json:"order"`
+}
+
+// OrderFilled 订单成交后的状态
+type OrderFilled struct {
+	Order   Order  `json:"order"`
+	TradeID string `json:"trade_id,omitempty"` // 本次成交的成交编号，市价买单余额不足一个最小单位而视为成交时为空
+}
+
+// OrderCancelled 订单已取消
+type OrderCancelled struct {
+	Order  Order  `json:"order"`
+	Reason string `json:"reason,omitempty"` // 系统取消原因，用户取消时为空
+}
+
+// OrderRejected 订单已拒绝
+type OrderRejected struct {
+	Order  Order  `json:"order"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// TradeExecuted 订单产生一条成交记录。撮合成交的 maker 与 taker 各有一条
+type TradeExecuted struct {
+	Trade Trade `json:"trade"`
+}
+
+func (*OrderAccepted) EventType() Type  { return TypeOrderAccepted }
+func (*OrderTriggered) EventType() Type { return TypeOrderTriggered }
+func (*OrderRepriced) EventType() Type  { return TypeOrderRepriced }
+func (*OrderFilled) EventType() Type    { return TypeOrderFilled }
+func (*OrderCancelled) EventType() Type { return TypeOrderCancelled }
+func (*OrderRejected) EventType() Type  { return TypeOrderRejected }
+func (*TradeExecuted) EventType() Type  { return TypeTradeExecuted }
+
+// newPayload 按事件类型创建空的事件内容，未知类型返回 nil
+func newPayload(t Type) Payload {
+	switch t {
+	case TypeOrderAccepted:
+		return &OrderAccepted{}
+	case TypeOrderTriggered:
+		return &OrderTriggered{}
+	case TypeOrderRepriced:
+		return &OrderRepriced{}
+	case TypeOrderFilled:
+		return &OrderFilled{}
+	case TypeOrderCancelled:
+		return &OrderCancelled{}
+	case TypeOrderRejected:
+		return &OrderRejected{}
+	case TypeTradeExecuted:
+		return &TradeExecuted{}
+	}
+	return nil
+}
+
+// Order 事件中的订单状态。与 types.Order 字段一一对应，但不依赖数据库模型的 json 标签，
+// 创建与更新时间等不在接口中返回的字段也会完整携带。
+type Order struct {
+	OrderID        string            `json:"order_id"`
+	ClientOrderID  string            `json:"client_order_id"`
+	UserID         int64             `json:"user_id"`
+	Symbol         string            `json:"symbol"`
+	OrderType      types.OrderType   `json:"order_type"`
+	OrderSide      types.OrderSide   `json:"order_side"`
+	Price          decimal.Decimal   `json:"price"`
+	Amount         decimal.Decimal   `json:"amount"`
+	QuoteAmount    decimal.Decimal   `json:"quote_amount"`
+	FilledAmount   decimal.Decimal   `json:"filled_amount"`
+	FilledQuote    decimal.Decimal   `json:"filled_quote"`
+	Fee            decimal.Decimal   `json:"fee"`
+	FeeAsset       string            `json:"fee_asset"`
+	TokenFee       decimal.Decimal   `json:"token_fee"`
+	Frozen         decimal.Decimal   `json:"frozen"`
+	TriggerType    types.OrderType   `json:"trigger_type,omitempty"`
+	TriggerPrice   decimal.Decimal   `json:"trigger_price"`
+	TrailingOffset decimal.Decimal   `json:"trailing_offset"`
+	TriggeredAt    *time.Time        `json:"triggered_at,omitempty"`
+	TimeInForce    types.TimeInForce `json:"time_in_force"`
+	ExpireAt       *time.Time        `json:"expire_at,omitempty"`
+	Status         types.OrderStatus `json:"status"`
+	CancelReason   string            `json:"cancel_reason,omitempty"`
+	Version        int64             `json:"version"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// NewOrder 从订单模型生成事件中的订单状态
+func NewOrder(o *types.Order) Order {
+	return Order{
+		OrderID:        o.OrderID,
+		ClientOrderID:  o.ClientOrderID,
+		UserID:         o.UserID,
+		Symbol:         o.Symbol,
+		OrderType:      o.OrderType,
+		OrderSide:      o.OrderSide,
+		Price:          o.Price,
+		Amount:         o.Amount,
+		QuoteAmount:    o.QuoteAmount,
+		FilledAmount:   o.FilledAmount,
+		FilledQuote:    o.FilledQuote,
+		Fee:            o.Fee,
+		FeeAsset:       o.FeeAsset,
+		TokenFee:       o.TokenFee,
+		Frozen:         o.Frozen,
+		TriggerType:    o.TriggerType,
+		TriggerPrice:   o.TriggerPrice,
+		TrailingOffset: o.TrailingOffset,
+		TriggeredAt:    o.TriggeredAt,
+		TimeInForce:    o.TimeInForce,
+		ExpireAt:       o.ExpireAt,
+		Status:         o.Status,
+		CancelReason:   o.CancelReason,
+		Version:        o.Version,
+		CreatedAt:      o.CreatedAt,
+		UpdatedAt:      o.UpdatedAt,
+	}
+}
+
+// Model 转换为订单模型，不含数据库自增主键
+func (o *Order) Model() *types.Order {
+	return &types.Order{
+		CreatedAt:      o.CreatedAt,
+		UpdatedAt:      o.UpdatedAt,
+		OrderID:        o.OrderID,
+		ClientOrderID:  o.ClientOrderID,
+		UserID:         o.UserID,
+		Symbol:         o.Symbol,
+		OrderType:      o.OrderType,
+		OrderSide:      o.OrderSide,
+		Price:          o.Price,
+		Amount:         o.Amount,
+		QuoteAmount:    o.QuoteAmount,
+		FilledAmount:   o.FilledAmount,
+		FilledQuote:    o.FilledQuote,
+		Fee:            o.Fee,
+		FeeAsset:       o.FeeAsset,
+		TokenFee:       o.TokenFee,
+		Frozen:         o.Frozen,
+		TriggerType:    o.TriggerType,
+		TriggerPrice:   o.TriggerPrice,
+		TrailingOffset: o.TrailingOffset,
+		TriggeredAt:    o.TriggeredAt,
+		TimeInForce:    o.TimeInForce,
+		ExpireAt:       o.ExpireAt,
+		Status:         o.Status,
+		CancelReason:   o.CancelReason,
+		Version:        o.Version,
+	}
+}
+
+// Trade 事件中的成交记录
+type Trade struct {
+	TradeID        string              `json:"trade_id"`
+	MatchID        string              `json:"match_id"`
+	OrderID        string              `json:"order_id"`
+	CounterOrderID string              `json:"counter_order_id,omitempty"` // 人工成交没有对手方
+	UserID         int64               `json:"user_id"`
+	Symbol         string              `json:"symbol"`
+	Side           types.OrderSide     `json:"side"`
+	Price          decimal.Decimal     `json:"price"`
+	Amount         decimal.Decimal     `json:"amount"`
+	Role           types.LiquidityRole `json:"role"`
+	FeeRate        decimal.Decimal     `json:"fee_rate"`
+	Fee            decimal.Decimal     `json:"fee"`
+	FeeAsset       string              `json:"fee_asset"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+// NewTrade 从成交记录模型生成事件中的成交记录
+func NewTrade(t *types.Trade) Trade {
+	return Trade{
+		TradeID:        t.TradeID,
+		MatchID:        t.MatchID,
+		OrderID:        t.OrderID,
+		CounterOrderID: t.CounterOrderID,
+		UserID:         t.UserID,
+		Symbol:         t.Symbol,
+		Side:           t.Side,
+		Price:          t.Price,
+		Amount:         t.Amount,
+		Role:           t.Role,
+		FeeRate:        t.FeeRate,
+		Fee:            t.Fee,
+		FeeAsset:       t.FeeAsset,
+		CreatedAt:      t.CreatedAt,
+	}
+}
+
+// Model 转换为成交记录模型，不含数据库自增主键
+func (t *Trade) Model() *types.Trade {
+	return &types.Trade{
+		CreatedAt:      t.CreatedAt,
+		TradeID:        t.TradeID,
+		MatchID:        t.MatchID,
+		OrderID:        t.OrderID,
+		CounterOrderID: t.CounterOrderID,
+		UserID:         t.UserID,
+		Symbol:         t.Symbol,
+		Side:           t.Side,
+		Price:          t.Price,
+		Amount:         t.Amount,
+		Role:           t.Role,
+		FeeRate:        t.FeeRate,
+		Fee:            t.Fee,
+		FeeAsset:       t.FeeAsset,
+	}
+}
@@ -0,0 +1,51 @@
+package event
+
+import (
+	"fmt"
+
+	"five/internal/types"
+)
+
+// legacyMessage 版本 1 的订单消息：action 为 create、trigger、update、cancel、reject 或 fill，
+// data 为订单模型的 JSON（不含创建与更新时间），fill 消息附带成交记录
+type legacyMessage struct {
+	Action string       `json:"action"`
+	Data   *types.Order `json:"data"`
+	Trade  *types.Trade `json:"trade"`
+}
+
+// upgrade 将版本 1 的消息转换为当前版本的事件。版本 1 没有事件编号，按订单号、订单版本与动作生成，
+// 重复投递的同一条消息得到相同的编号；也没有发生时间，OccurredAt 为零值。
+// fill 消息转换为 TradeExecuted 与 OrderFilled 两个事件。
+func (m *legacyMessage) upgrade(orderID string) ([]*Event, error) {
+	if m.Data == nil {
+		return nil, fmt.Errorf("legacy %s message without order data", m.Action)
+	}
+	order := NewOrder(m.Data)
+	id := fmt.Sprintf("v1-%s-%d-%s", orderID, order.Version, m.Action)
+	legacy := func(id string, payload Payload) *Event {
+		return &Event{ID: id, Type: payload.EventType(), Version: SchemaVersion, OrderID: orderID, Payload: payload}
+	}
+
+	switch m.Action {
+	case "create":
+		return []*Event{legacy(id, &OrderAccepted{Order: order})}, nil
+	case "trigger":
+		return []*Event{legacy(id, &OrderTriggered{Order: order})}, nil
+	case "update":
+		return []*Event{legacy(id, &OrderFilled{Order: order})}, nil
+	case "cancel":
+		return []*Event{legacy(id, &OrderCancelled{Order: order, Reason: order.CancelReason})}, nil
+	case "reject":
+		return []*Event{legacy(id, &OrderRejected{Order: order, Reason: order.CancelReason})}, nil
+	case "fill":
+		filled := &OrderFilled{Order: order}
+		events := make([]*Event, 0, 2)
+		if m.Trade != nil {
+			filled.TradeID = m.Trade.TradeID
+			events = append(events, legacy(id+"-trade", &TradeExecuted{Trade: NewTrade(m.Trade)}))
+		}
+		return append(events, legacy(id, filled)), nil
+	}
+	return nil, fmt.Errorf("unknown legacy action %q", m.Action)
+}
@@ -0,0 +1,268 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"five/internal/event/pb"
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protobuf 以 Protobuf 编码事件，结构定义见 pb/event.proto
+var Protobuf Codec = protobufCodec{}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufCodec) Marshal(e *Event) ([]byte, error) {
+	env := &pb.Envelope{
+		Id:         e.ID,
+		Type:       string(e.Type),
+		Version:    int32(e.Version),
+		OrderId:    e.OrderID,
+		OccurredAt: unixNano(e.OccurredAt),
+	}
+	switch p := e.Payload.(type) {
+	case *OrderAccepted:
+		env.Payload = &pb.Envelope_OrderAccepted{OrderAccepted: &pb.OrderAccepted{Order: orderToProto(&p.Order)}}
+	case *OrderTriggered:
+		env.Payload = &pb.Envelope_OrderTriggered{OrderTriggered: &pb.OrderTriggered{Order: orderToProto(&p.Order)}}
+	case *OrderRepriced:
+		env.Payload = &pb.Envelope_OrderRepriced{OrderRepriced: &pb.OrderRepriced{Order: orderToProto(&p.Order)}}
+	case *OrderFilled:
+		env.Payload = &pb.Envelope_OrderFilled{OrderFilled: &pb.OrderFilled{Order: orderToProto(&p.Order), TradeId: p.TradeID}}
+	case *OrderCancelled:
+		env.Payload = &pb.Envelope_OrderCancelled{OrderCancelled: &pb.OrderCancelled{Order: orderToProto(&p.Order), Reason: p.Reason}}
+	case *OrderRejected:
+		env.Payload = &pb.Envelope_OrderRejected{OrderRejected: &pb.OrderRejected{Order: orderToProto(&p.Order), Reason: p.Reason}}
+	case *TradeExecuted:
+		env.Payload = &pb.Envelope_TradeExecuted{TradeExecuted: &pb.TradeExecuted{Trade: tradeToProto(&p.Trade)}}
+	default:
+		return nil, fmt.Errorf("unknown event payload %T", e.Payload)
+	}
+	return proto.Marshal(env)
+}
+
+func (protobufCodec) Unmarshal(data []byte) ([]*Event, error) {
+	var env pb.Envelope
+	if err := proto.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(int(env.Version)); err != nil {
+		return nil, err
+	}
+
+	e := &Event{
+		ID:         env.Id,
+		Type:       Type(env.Type),
+		Version:    int(env.Version),
+		OrderID:    env.OrderId,
+		OccurredAt: fromUnixNano(env.OccurredAt),
+	}
+	var err error
+	switch p := env.Payload.(type) {
+	case *pb.Envelope_OrderAccepted:
+		payload := &OrderAccepted{}
+		payload.Order, err = orderFromProto(p.OrderAccepted.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_OrderTriggered:
+		payload := &OrderTriggered{}
+		payload.Order, err = orderFromProto(p.OrderTriggered.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_OrderRepriced:
+		payload := &OrderRepriced{}
+		payload.Order, err = orderFromProto(p.OrderRepriced.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_OrderFilled:
+		payload := &OrderFilled{TradeID: p.OrderFilled.GetTradeId()}
+		payload.Order, err = orderFromProto(p.OrderFilled.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_OrderCancelled:
+		payload := &OrderCancelled{Reason: p.OrderCancelled.GetReason()}
+		payload.Order, err = orderFromProto(p.OrderCancelled.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_OrderRejected:
+		payload := &OrderRejected{Reason: p.OrderRejected.GetReason()}
+		payload.Order, err = orderFromProto(p.OrderRejected.GetOrder())
+		e.Payload = payload
+	case *pb.Envelope_TradeExecuted:
+		payload := &TradeExecuted{}
+		payload.Trade, err = tradeFromProto(p.TradeExecuted.GetTrade())
+		e.Payload = payload
+	default:
+		return nil, fmt.Errorf("unknown event type %q", env.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", env.Type, err)
+	}
+	if e.Payload.EventType() != e.Type {
+		return nil, fmt.Errorf("event type %q does not match payload %s", e.Type, e.Payload.EventType())
+	}
+	return []*Event{e}, nil
+}
+
+func orderToProto(o *Order) *pb.Order {
+	return &pb.Order{
+		OrderId:        o.OrderID,
+		ClientOrderId:  o.ClientOrderID,
+		UserId:         o.UserID,
+		Symbol:         o.Symbol,
+		OrderType:      string(o.OrderType),
+		OrderSide:      string(o.OrderSide),
+		Price:          o.Price.String(),
+		Amount:         o.Amount.String(),
+		QuoteAmount:    o.QuoteAmount.String(),
+		FilledAmount:   o.FilledAmount.String(),
+		FilledQuote:    o.FilledQuote.String(),
+		Fee:            o.Fee.String(),
+		FeeAsset:       o.FeeAsset,
+		TokenFee:       o.TokenFee.String(),
+		Frozen:         o.Frozen.String(),
+		TriggerType:    string(o.TriggerType),
+		TriggerPrice:   o.TriggerPrice.String(),
+		TrailingOffset: o.TrailingOffset.String(),
+		TriggeredAt:    unixNanoPtr(o.TriggeredAt),
+		TimeInForce:    string(o.TimeInForce),
+		ExpireAt:       unixNanoPtr(o.ExpireAt),
+		Status:         string(o.Status),
+		CancelReason:   o.CancelReason,
+		Version:        o.Version,
+		CreatedAt:      unixNano(o.CreatedAt),
+		UpdatedAt:      unixNano(o.UpdatedAt),
+	}
+}
+
+func orderFromProto(p *pb.Order) (Order, error) {
+	if p == nil {
+		return Order{}, fmt.Errorf("missing order")
+	}
+	o := Order{
+		OrderID:       p.OrderId,
+		ClientOrderID: p.ClientOrderId,
+		UserID:        p.UserId,
+		Symbol:        p.Symbol,
+		OrderType:     types.OrderType(p.OrderType),
+		OrderSide:     types.OrderSide(p.OrderSide),
+		FeeAsset:      p.FeeAsset,
+		TriggerType:   types.OrderType(p.TriggerType),
+		TriggeredAt:   fromUnixNanoPtr(p.TriggeredAt),
+		TimeInForce:   types.TimeInForce(p.TimeInForce),
+		ExpireAt:      fromUnixNanoPtr(p.ExpireAt),
+		Status:        types.OrderStatus(p.Status),
+		CancelReason:  p.CancelReason,
+		Version:       p.Version,
+		CreatedAt:     fromUnixNano(p.CreatedAt),
+		UpdatedAt:     fromUnixNano(p.UpdatedAt),
+	}
+	err := parseDecimals([]decimalField{
+		{p.Price, &o.Price},
+		{p.Amount, &o.Amount},
+		{p.QuoteAmount, &o.QuoteAmount},
+		{p.FilledAmount, &o.FilledAmount},
+		{p.FilledQuote, &o.FilledQuote},
+		{p.Fee, &o.Fee},
+		{p.TokenFee, &o.TokenFee},
+		{p.Frozen, &o.Frozen},
+		{p.TriggerPrice, &o.TriggerPrice},
+		{p.TrailingOffset, &o.TrailingOffset},
+	})
+	return o, err
+}
+
+func tradeToProto(t *Trade) *pb.Trade {
+	return &pb.Trade{
+		TradeId:        t.TradeID,
+		MatchId:        t.MatchID,
+		OrderId:        t.OrderID,
+		CounterOrderId: t.CounterOrderID,
+		UserId:         t.UserID,
+		Symbol:         t.Symbol,
+		Side:           string(t.Side),
+		Price:          t.Price.String(),
+		Amount:         t.Amount.String(),
+		Role:           string(t.Role),
+		FeeRate:        t.FeeRate.String(),
+		Fee:            t.Fee.String(),
+		FeeAsset:       t.FeeAsset,
+		CreatedAt:      unixNano(t.CreatedAt),
+	}
+}
+
+func tradeFromProto(p *pb.Trade) (Trade, error) {
+	if p == nil {
+		return Trade{}, fmt.Errorf("missing trade")
+	}
+	t := Trade{
+		TradeID:        p.TradeId,
+		MatchID:        p.MatchId,
+		OrderID:        p.OrderId,
+		CounterOrderID: p.CounterOrderId,
+		UserID:         p.UserId,
+		Symbol:         p.Symbol,
+		Side:           types.OrderSide(p.Side),
+		Role:           types.LiquidityRole(p.Role),
+		FeeAsset:       p.FeeAsset,
+		CreatedAt:      fromUnixNano(p.CreatedAt),
+	}
+	err := parseDecimals([]decimalField{
+		{p.Price, &t.Price},
+		{p.Amount, &t.Amount},
+		{p.FeeRate, &t.FeeRate},
+		{p.Fee, &t.Fee},
+	})
+	return t, err
+}
+
+type decimalField struct {
+	value string
+	dst   *decimal.Decimal
+}
+
+// parseDecimals 解析十进制字符串，空字符串视为零
+func parseDecimals(fields []decimalField) error {
+	for _, f := range fields {
+		if f.value == "" {
+			*f.dst = decimal.Zero
+			continue
+		}
+		d, err := decimal.NewFromString(f.value)
+		if err != nil {
+			return err
+		}
+		*f.dst = d
+	}
+	return nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func unixNanoPtr(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return unixNano(*t)
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func fromUnixNanoPtr(n int64) *time.Time {
+	if n == 0 {
+		return nil
+	}
+	t := time.Unix(0, n)
+	return &t
+}
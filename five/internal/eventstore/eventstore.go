@@ -0,0 +1,79 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"five/internal/event"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// 按序号分批读取事件的批大小
+const loadBatchSize = 1000
+
+// NewRecord 将事件编码为事件存储中的记录。事件存储固定以 JSON 编码，与消息总线上的编码方式无关
+func NewRecord(e *event.Event) (*types.EventRecord, error) {
+	payload, err := event.JSON.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return &types.EventRecord{
+		EventID:    e.ID,
+		Type:       string(e.Type),
+		Version:    e.Version,
+		OrderID:    e.OrderID,
+		OccurredAt: e.OccurredAt,
+		Payload:    payload,
+	}, nil
+}
+
+// Append 在业务事务内追加一条事件
+func Append(tx *gorm.DB, e *event.Event) error {
+	record, err := NewRecord(e)
+	if err != nil {
+		return err
+	}
+	return tx.Create(record).Error
+}
+
+// Decode 解码事件存储中的记录
+func Decode(record *types.EventRecord) (*event.Event, error) {
+	events, err := event.JSON.Unmarshal(record.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode event %d: %w", record.Sequence, err)
+	}
+	if len(events) != 1 {
+		return nil, fmt.Errorf("decode event %d: got %d events", record.Sequence, len(events))
+	}
+	return events[0], nil
+}
+
+// Load 按序号依次读取 after 之后的事件交给 fn，until 非零时跳过发生时间晚于 until 的事件
+func Load(ctx context.Context, db *gorm.DB, after uint64, until time.Time, fn func(seq uint64, e *event.Event) error) error {
+	for {
+		query := db.WithContext(ctx).Where("sequence > ?", after)
+		if !until.IsZero() {
+			query = query.Where("occurred_at <= ?", until)
+		}
+		var records []types.EventRecord
+		if err := query.Order("sequence ASC").Limit(loadBatchSize).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			e, err := Decode(&records[i])
+			if err != nil {
+				return err
+			}
+			if err := fn(records[i].Sequence, e); err != nil {
+				return err
+			}
+			after = records[i].Sequence
+		}
+		if len(records) < loadBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package eventstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"five/internal/event"
+	"five/internal/types"
+
+	"github.com/glebarez/sqlite"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+var start = time.Now().Add(-time.Hour).Truncate(time.Second)
+
+// newTestDB 建有事件、快照、订单与成交表的 SQLite 数据库。repository 依赖本包，不能用 repository.OpenSQLite
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&types.EventRecord{}, &types.OrderSnapshot{}, &types.Order{}, &types.Trade{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func orderEvent(orderID string, status types.OrderStatus, version int64, filled string, at time.Time) *event.Event {
+	order := event.Order{
+		OrderID:       orderID,
+		ClientOrderID: orderID,
+		UserID:        1,
+		Symbol:        "BTC/USDT",
+		OrderType:     types.OrderTypeLimit,
+		OrderSide:     types.OrderSideBuy,
+		Price:         dec("100"),
+		Amount:        dec("1"),
+		FilledAmount:  dec(filled),
+		Status:        status,
+		Version:       version,
+		CreatedAt:     start,
+	}
+	var payload event.Payload = &event.OrderFilled{Order: order}
+	if status == types.OrderStatusPending {
+		payload = &event.OrderAccepted{Order: order}
+	}
+	e := event.New(orderID, payload)
+	e.OccurredAt = at
+	return e
+}
+
+func tradeEvent(tradeID, orderID, amount string, at time.Time) *event.Event {
+	e := event.New(orderID, &event.TradeExecuted{Trade: event.Trade{
+		TradeID:   tradeID,
+		OrderID:   orderID,
+		Symbol:    "BTC/USDT",
+		Price:     dec("100"),
+		Amount:    dec(amount),
+		CreatedAt: at,
+	}})
+	e.OccurredAt = at
+	return e
+}
+
+func appendEvents(t *testing.T, db *gorm.DB, events ...*event.Event) {
+	t.Helper()
+	for _, e := range events {
+		if err := Append(db, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// summary 订单状态与已成交数量，以及成交编号
+func summary(s *State) (map[string]string, []string) {
+	orders := make(map[string]string)
+	for id, o := range s.Orders {
+		orders[id] = string(o.Status) + " " + o.FilledAmount.String()
+	}
+	var trades []string
+	for _, t := range s.TradeList() {
+		trades = append(trades, t.TradeID)
+	}
+	return orders, trades
+}
+
+func TestApplyKeepsLatestVersion(t *testing.T) {
+	s := NewState()
+	s.Apply(1, orderEvent("o1", types.OrderStatusPending, 1, "0", start))
+	s.Apply(3, orderEvent("o1", types.OrderStatusFilled, 3, "1", start.Add(2*time.Minute)))
+	// 乱序到达的旧版本与重复的成交不改变状态
+	s.Apply(2, orderEvent("o1", types.OrderStatusPartFilled, 2, "0.4", start.Add(time.Minute)))
+	s.Apply(4, tradeEvent("t1", "o1", "0.4", start.Add(time.Minute)))
+	s.Apply(5, tradeEvent("t1", "o1", "0.4", start.Add(time.Minute)))
+
+	orders, trades := summary(s)
+	if !reflect.DeepEqual(orders, map[string]string{"o1": "filled 1"}) || !reflect.DeepEqual(trades, []string{"t1"}) {
+		t.Fatalf("state orders %v trades %v", orders, trades)
+	}
+	if s.Sequence != 5 || !s.OccurredAt.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("state at sequence %d occurred %s", s.Sequence, s.OccurredAt)
+	}
+}
+
+func TestReplayFromSnapshot(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	appendEvents(t, db,
+		orderEvent("o1", types.OrderStatusPending, 1, "0", start),
+		orderEvent("o2", types.OrderStatusPending, 1, "0", start.Add(time.Minute)),
+		tradeEvent("t1", "o1", "0.4", start.Add(2*time.Minute)),
+		orderEvent("o1", types.OrderStatusPartFilled, 2, "0.4", start.Add(2*time.Minute)),
+	)
+	snapshot, err := TakeSnapshot(ctx, db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot == nil || snapshot.Sequence != 4 || snapshot.Orders != 2 || snapshot.Trades != 1 {
+		t.Fatalf("snapshot %+v, want 2 orders and 1 trade up to sequence 4", snapshot)
+	}
+	if again, err := TakeSnapshot(ctx, db, 0); err != nil || again != nil {
+		t.Fatalf("snapshot without new events: %+v, %v", again, err)
+	}
+
+	appendEvents(t, db,
+		tradeEvent("t2", "o1", "0.6", start.Add(3*time.Minute)),
+		orderEvent("o1", types.OrderStatusFilled, 3, "1", start.Add(3*time.Minute)),
+	)
+
+	for _, c := range []struct {
+		until  time.Time
+		orders map[string]string
+		trades []string
+	}{
+		{time.Time{}, map[string]string{"o1": "filled 1", "o2": "pending 0"}, []string{"t1", "t2"}},
+		// 快照之后的时刻：快照加之后的事件
+		{start.Add(150 * time.Second), map[string]string{"o1": "part_filled 0.4", "o2": "pending 0"}, []string{"t1"}},
+		// 快照之前的时刻：不使用快照，从头折叠
+		{start.Add(90 * time.Second), map[string]string{"o1": "pending 0", "o2": "pending 0"}, nil},
+	} {
+		state, err := Replay(ctx, db, c.until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orders, trades := summary(state)
+		if !reflect.DeepEqual(orders, c.orders) || !reflect.DeepEqual(trades, c.trades) {
+			t.Fatalf("replay until %s: orders %v trades %v, want %v %v", c.until, orders, trades, c.orders, c.trades)
+		}
+	}
+}
+
+func TestRestoreAndVerify(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	appendEvents(t, db,
+		orderEvent("o1", types.OrderStatusPending, 1, "0", start),
+		tradeEvent("t1", "o1", "1", start.Add(time.Minute)),
+		orderEvent("o1", types.OrderStatusFilled, 2, "1", start.Add(time.Minute)),
+		orderEvent("o2", types.OrderStatusPending, 1, "0", start.Add(time.Minute)),
+	)
+	state, err := Replay(ctx, db, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(ctx, db, state); err != nil {
+		t.Fatal(err)
+	}
+	if diffs, err := Verify(ctx, db, state); err != nil || len(diffs) != 0 {
+		t.Fatalf("verify after restore: %v, %v", diffs, err)
+	}
+	if err := Restore(ctx, db, state); err == nil {
+		t.Fatal("restoring into a non-empty table should fail")
+	}
+
+	// 数据库与事件不一致的订单与成交均被报告
+	db.Model(&types.Order{}).Where("order_id = ?", "o2").Update("frozen", "50")
+	db.Model(&types.Order{}).Where("order_id = ?", "o1").Update("status", types.OrderStatusCancelled)
+	db.Create(&types.Trade{TradeID: "t9", OrderID: "o9", Symbol: "BTC/USDT", Price: dec("1"), Amount: dec("1")})
+	db.Create(&types.Order{OrderID: "o9", ClientOrderID: "o9", UserID: 1, Symbol: "BTC/USDT", Price: dec("1"), Amount: dec("1"), Status: types.OrderStatusFilled})
+	delete(state.Trades, "t1")
+
+	diffs, err := Verify(ctx, db, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"order o1: status filled version 2, database has cancelled version 2",
+		"order o2: frozen 0, database has 50",
+		"order o9: missing from events",
+		"trade t1: missing from events",
+		"trade t9: missing from events",
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("diffs %q, want %q", diffs, want)
+	}
+}
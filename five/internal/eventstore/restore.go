@@ -0,0 +1,118 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 写入还原结果的批大小
+const restoreBatchSize = 500
+
+// Restore 将重放得到的订单与成交记录写入数据库，orders 与 trades 表必须为空
+func Restore(ctx context.Context, db *gorm.DB, state *State) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"orders", "trades"} {
+			var n int64
+			if err := tx.Table(table).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%s table is not empty", table)
+			}
+		}
+
+		orders := make([]*types.Order, 0, len(state.Orders))
+		for _, o := range state.OrderList() {
+			orders = append(orders, o.Model())
+		}
+		trades := make([]*types.Trade, 0, len(state.Trades))
+		for _, t := range state.TradeList() {
+			trades = append(trades, t.Model())
+		}
+		if len(orders) > 0 {
+			if err := tx.CreateInBatches(orders, restoreBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(trades) > 0 {
+			if err := tx.CreateInBatches(trades, restoreBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Verify 比对重放结果与数据库中的订单与成交记录，返回不一致之处。
+// 事件存储启用之前的订单没有事件，会报告为重放结果中缺失。
+func Verify(ctx context.Context, db *gorm.DB, state *State) ([]string, error) {
+	var diffs []string
+	seen := make(map[string]bool, len(state.Orders))
+
+	var orders []types.Order
+	if err := db.WithContext(ctx).Order("id ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		want := &orders[i]
+		seen[want.OrderID] = true
+		got, ok := state.Orders[want.OrderID]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("order %s: missing from events", want.OrderID))
+			continue
+		}
+		if got.Status != want.Status || got.Version != want.Version {
+			diffs = append(diffs, fmt.Sprintf("order %s: status %s version %d, database has %s version %d",
+				want.OrderID, got.Status, got.Version, want.Status, want.Version))
+			continue
+		}
+		for _, f := range []struct {
+			name      string
+			got, want decimal.Decimal
+		}{
+			{"filled_amount", got.FilledAmount, want.FilledAmount},
+			{"filled_quote", got.FilledQuote, want.FilledQuote},
+			{"fee", got.Fee, want.Fee},
+			{"token_fee", got.TokenFee, want.TokenFee},
+			{"frozen", got.Frozen, want.Frozen},
+		} {
+			if !f.got.Equal(f.want) {
+				diffs = append(diffs, fmt.Sprintf("order %s: %s %s, database has %s", want.OrderID, f.name, f.got, f.want))
+			}
+		}
+	}
+	for id := range state.Orders {
+		if !seen[id] {
+			diffs = append(diffs, fmt.Sprintf("order %s: missing from database", id))
+		}
+	}
+
+	var trades []types.Trade
+	if err := db.WithContext(ctx).Order("id ASC").Find(&trades).Error; err != nil {
+		return nil, err
+	}
+	seen = make(map[string]bool, len(trades))
+	for i := range trades {
+		want := &trades[i]
+		seen[want.TradeID] = true
+		got, ok := state.Trades[want.TradeID]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("trade %s: missing from events", want.TradeID))
+			continue
+		}
+		if got.OrderID != want.OrderID || !got.Price.Equal(want.Price) || !got.Amount.Equal(want.Amount) || !got.Fee.Equal(want.Fee) {
+			diffs = append(diffs, fmt.Sprintf("trade %s: differs from database", want.TradeID))
+		}
+	}
+	for id := range state.Trades {
+		if !seen[id] {
+			diffs = append(diffs, fmt.Sprintf("trade %s: missing from database", id))
+		}
+	}
+	return diffs, nil
+}
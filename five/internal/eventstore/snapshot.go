@@ -0,0 +1,175 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"five/internal/event"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// errStop 结束 Load 的遍历
+var errStop = errors.New("stop loading events")
+
+// Replay 还原 until 时刻的订单与成交记录，until 为零时还原到最新。
+// 从发生时间不晚于 until 的最近一个快照开始，折叠其后发生时间不晚于 until 的事件。
+func Replay(ctx context.Context, db *gorm.DB, until time.Time) (*State, error) {
+	state, err := latestSnapshot(ctx, db, until)
+	if err != nil {
+		return nil, err
+	}
+	err = Load(ctx, db, state.Sequence, until, func(seq uint64, e *event.Event) error {
+		state.Apply(seq, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// TakeSnapshot 从最近的快照起折叠新事件并保存快照，没有新事件时返回 nil。
+// 快照只包含发生时间早于 settle 之前的连续事件：并发事务的序号与提交顺序不一定一致，
+// 等待 settle 后再纳入快照，避免之后才提交的较小序号的事件被快照遗漏。
+func TakeSnapshot(ctx context.Context, db *gorm.DB, settle time.Duration) (*types.OrderSnapshot, error) {
+	state, err := latestSnapshot(ctx, db, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	base := state.Sequence
+	cutoff := time.Now().Add(-settle)
+	err = Load(ctx, db, base, time.Time{}, func(seq uint64, e *event.Event) error {
+		if e.OccurredAt.After(cutoff) {
+			return errStop
+		}
+		state.Apply(seq, e)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+	if state.Sequence == base {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state.data())
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &types.OrderSnapshot{
+		Sequence:   state.Sequence,
+		OccurredAt: state.OccurredAt,
+		Orders:     len(state.Orders),
+		Trades:     len(state.Trades),
+		Data:       data,
+	}
+	if err := db.WithContext(ctx).Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// latestSnapshot 加载发生时间不晚于 until 的最近一个快照，until 为零时加载最新的快照；没有快照时返回空状态
+func latestSnapshot(ctx context.Context, db *gorm.DB, until time.Time) (*State, error) {
+	query := db.WithContext(ctx).Order("sequence DESC")
+	if !until.IsZero() {
+		query = query.Where("occurred_at <= ?", until)
+	}
+	var snapshots []types.OrderSnapshot
+	if err := query.Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return NewState(), nil
+	}
+
+	snapshot := &snapshots[0]
+	var data stateData
+	if err := json.Unmarshal(snapshot.Data, &data); err != nil {
+		return nil, fmt.Errorf("decode snapshot %d: %w", snapshot.ID, err)
+	}
+	return data.state(), nil
+}
+
+// SnapshotOptions 定期快照参数
+type SnapshotOptions struct {
+	Interval  time.Duration // 检查间隔
+	MinEvents int64         // 距上一个快照至少新增多少个事件才生成新快照
+	Keep      int           // 保留最近的快照个数，0 表示全部保留
+	Settle    time.Duration // 事件发生后至少经过多久才纳入快照
+}
+
+// Snapshotter 定期生成订单状态快照，限制重放需要折叠的事件数
+type Snapshotter struct {
+	db   *gorm.DB
+	opts SnapshotOptions
+}
+
+func NewSnapshotter(db *gorm.DB, opts SnapshotOptions) *Snapshotter {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Minute
+	}
+	if opts.MinEvents <= 0 {
+		opts.MinEvents = 1
+	}
+	return &Snapshotter{db: db, opts: opts}
+}
+
+// Run 定期检查并生成快照，直到 ctx 结束
+func (s *Snapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.snapshot(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("生成订单状态快照失败: %v\n", err)
+		}
+	}
+}
+
+func (s *Snapshotter) snapshot(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+	var last uint64
+	if err := db.Model(&types.OrderSnapshot{}).Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+	var pending int64
+	if err := db.Model(&types.EventRecord{}).Where("sequence > ?", last).Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending < s.opts.MinEvents {
+		return nil
+	}
+
+	snapshot, err := TakeSnapshot(ctx, s.db, s.opts.Settle)
+	if err != nil || snapshot == nil {
+		return err
+	}
+	fmt.Printf("已生成订单状态快照: 序号 %d, 订单 %d, 成交 %d\n", snapshot.Sequence, snapshot.Orders, snapshot.Trades)
+	return s.prune(ctx)
+}
+
+// prune 删除超出保留个数的旧快照
+func (s *Snapshotter) prune(ctx context.Context) error {
+	if s.opts.Keep <= 0 {
+		return nil
+	}
+	var kept []uint64
+	if err := s.db.WithContext(ctx).Model(&types.OrderSnapshot{}).
+		Order("sequence DESC").Limit(s.opts.Keep).Pluck("sequence", &kept).Error; err != nil {
+		return err
+	}
+	if len(kept) < s.opts.Keep {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("sequence < ?", kept[len(kept)-1]).Delete(&types.OrderSnapshot{}).Error
+}
@@ -0,0 +1,103 @@
+package eventstore
+
+import (
+	"sort"
+	"time"
+
+	"five/internal/event"
+)
+
+// State 折叠订单事件得到的订单与成交记录
+type State struct {
+	Sequence   uint64                  `json:"sequence"`    // 已折叠的最后一个事件
+	OccurredAt time.Time               `json:"occurred_at"` // 已折叠的事件中最晚的发生时间
+	Orders     map[string]*event.Order `json:"-"`
+	Trades     map[string]*event.Trade `json:"-"`
+}
+
+func NewState() *State {
+	return &State{
+		Orders: make(map[string]*event.Order),
+		Trades: make(map[string]*event.Trade),
+	}
+}
+
+// Apply 折叠一个事件。订单事件携带变更后的完整订单状态，按订单版本号取最新的状态，
+// 重复或乱序到达的旧版本不会覆盖新状态；成交记录按成交编号去重。
+func (s *State) Apply(seq uint64, e *event.Event) {
+	s.Sequence = max(s.Sequence, seq)
+	if e.OccurredAt.After(s.OccurredAt) {
+		s.OccurredAt = e.OccurredAt
+	}
+
+	if order := e.Order(); order != nil {
+		if prev, ok := s.Orders[order.OrderID]; !ok || order.Version >= prev.Version {
+			o := *order
+			s.Orders[o.OrderID] = &o
+		}
+		return
+	}
+	if trade := e.Trade(); trade != nil {
+		t := *trade
+		s.Trades[t.TradeID] = &t
+	}
+}
+
+// OrderList 按创建时间升序排列的订单
+func (s *State) OrderList() []*event.Order {
+	list := make([]*event.Order, 0, len(s.Orders))
+	for _, o := range s.Orders {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].OrderID < list[j].OrderID
+	})
+	return list
+}
+
+// TradeList 按成交时间升序排列的成交记录
+func (s *State) TradeList() []*event.Trade {
+	list := make([]*event.Trade, 0, len(s.Trades))
+	for _, t := range s.Trades {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].TradeID < list[j].TradeID
+	})
+	return list
+}
+
+// stateData 状态的序列化形式，用于快照与 replay 命令的输出
+type stateData struct {
+	Sequence   uint64         `json:"sequence"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Orders     []*event.Order `json:"orders"`
+	Trades     []*event.Trade `json:"trades"`
+}
+
+func (s *State) data() *stateData {
+	return &stateData{
+		Sequence:   s.Sequence,
+		OccurredAt: s.OccurredAt,
+		Orders:     s.OrderList(),
+		Trades:     s.TradeList(),
+	}
+}
+
+func (d *stateData) state() *State {
+	s := NewState()
+	s.Sequence, s.OccurredAt = d.Sequence, d.OccurredAt
+	for _, o := range d.Orders {
+		s.Orders[o.OrderID] = o
+	}
+	for _, t := range d.Trades {
+		s.Trades[t.TradeID] = t
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+package fee
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// 用户成交量缓存时间，避免每笔成交都汇总成交记录
+const volumeCacheTTL = time.Minute
+
+// Rates maker 与 taker 费率
+type Rates struct {
+	Maker decimal.Decimal `json:"maker_rate"`
+	Taker decimal.Decimal `json:"taker_rate"`
+}
+
+// Rate 按流动性角色取费率
+func (r Rates) Rate(role types.LiquidityRole) decimal.Decimal {
+	if role == types.LiquidityMaker {
+		return r.Maker
+	}
+	return r.Taker
+}
+
+func (r Rates) validate() error {
+	for _, rate := range []decimal.Decimal{r.Maker, r.Taker} {
+		if rate.IsNegative() || rate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			return fmt.Errorf("fee rate %s out of range [0, 1)", rate)
+		}
+	}
+	return nil
+}
+
+// Tier VIP 等级：近期成交额达到 MinVolume 即适用该等级费率
+type Tier struct {
+	MinVolume decimal.Decimal
+	Rates
+}
+
+// Options 费率表参数
+type Options struct {
+	Base      Rates            // VIP0 费率
+	Tiers     []Tier           // VIP1 起的等级，按 MinVolume 升序
+	Overrides map[string]Rates // 交易对专属费率，优先于 VIP 等级
+	Window    time.Duration    // 统计成交额的时间窗口
+
+	// VolumeAsset 统计成交额的计价币。不同计价币的成交额不能直接相加，只计入以它计价的交易对
+	VolumeAsset string
+
+	Token         string          // 可抵扣手续费的平台币，为空表示不启用
+	TokenDiscount decimal.Decimal // 平台币抵扣折扣，如 0.25 表示按 75% 收取
+}
+
+// UserRates 用户在某交易对上适用的费率
+type UserRates struct {
+	UserID      int64           `json:"user_id"`
+	Symbol      string          `json:"symbol"`
+	Tier        int             `json:"tier"`         // VIP 等级，0 为基础费率
+	Volume      decimal.Decimal `json:"volume"`       // 时间窗口内以 VolumeAsset 计价的成交额
+	VolumeAsset string          `json:"volume_asset"` // 统计成交额的计价币
+	Rates
+}
+
+type volumeEntry struct {
+	volume decimal.Decimal
+	at     time.Time
+}
+
+// VolumeSource 查询用户近期在以 quoteAsset 计价的交易对上的成交额，由成交记录仓储实现
+type VolumeSource interface {
+	Volume(userID int64, quoteAsset string, since time.Time) (decimal.Decimal, error)
+}
+
+// Schedule 服务端手续费率表：maker/taker 分开计费，按近期成交额划分 VIP 等级，支持交易对专属费率
+type Schedule struct {
+	volumes VolumeSource
+	opts    Options
+
+	mu    sync.Mutex
+	cache map[int64]volumeEntry
+}
+
+func NewSchedule(volumes VolumeSource, opts Options) (*Schedule, error) {
+	if err := opts.Base.validate(); err != nil {
+		return nil, err
+	}
+	if len(opts.Tiers) > 0 && opts.VolumeAsset == "" {
+		return nil, fmt.Errorf("fee tiers require a volume asset")
+	}
+	for i, tier := range opts.Tiers {
+		if err := tier.validate(); err != nil {
+			return nil, err
+		}
+		if i > 0 && !tier.MinVolume.GreaterThan(opts.Tiers[i-1].MinVolume) {
+			return nil, fmt.Errorf("fee tiers must be sorted by ascending min volume")
+		}
+	}
+	if opts.TokenDiscount.IsNegative() || opts.TokenDiscount.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("fee token discount %s out of range [0, 1)", opts.TokenDiscount)
+	}
+	for symbol, rates := range opts.Overrides {
+		if err := rates.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", symbol, err)
+		}
+	}
+	return &Schedule{
+		volumes: volumes,
+		opts:    opts,
+		cache:   make(map[int64]volumeEntry),
+	}, nil
+}
+
+// Rate 计算用户在交易对上按流动性角色适用的费率。
+// 成交结算在事务内调用，volumes 传入事务内的成交记录仓储，避免在持有事务时另开连接查询
+func (s *Schedule) Rate(volumes VolumeSource, userID int64, symbol string, role types.LiquidityRole) (decimal.Decimal, error) {
+	rates, err := s.userRates(volumes, userID, symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return rates.Rate(role), nil
+}
+
+// UserRates 查询用户在交易对上的 VIP 等级与费率
+func (s *Schedule) UserRates(userID int64, symbol string) (*UserRates, error) {
+	return s.userRates(s.volumes, userID, symbol)
+}
+
+func (s *Schedule) userRates(volumes VolumeSource, userID int64, symbol string) (*UserRates, error) {
+	volume, err := s.volume(volumes, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserRates{UserID: userID, Symbol: symbol, Volume: volume, VolumeAsset: s.opts.VolumeAsset, Rates: s.opts.Base}
+	for i, tier := range s.opts.Tiers {
+		if volume.LessThan(tier.MinVolume) {
+			break
+		}
+		result.Tier = i + 1
+		result.Rates = tier.Rates
+	}
+	if rates, ok := s.opts.Overrides[symbol]; ok {
+		result.Rates = rates
+	}
+	return result, nil
+}
+
+// Token 可抵扣手续费的平台币，为空表示不启用
+func (s *Schedule) Token() string {
+	return s.opts.Token
+}
+
+// TokenAmount 将计价币计的手续费按平台币参考价折算并打折，结果按手续费精度向上取整
+func (s *Schedule) TokenAmount(value, tokenPrice decimal.Decimal) decimal.Decimal {
+	discounted := value.Mul(decimal.NewFromInt(1).Sub(s.opts.TokenDiscount))
+	return types.RoundFee(discounted.Div(tokenPrice))
+}
+
+// volume 用户在时间窗口内以 VolumeAsset 计价的成交额（成交价 × 数量之和），带短时缓存
+func (s *Schedule) volume(volumes VolumeSource, userID int64) (decimal.Decimal, error) {
+	if len(s.opts.Tiers) == 0 {
+		return decimal.Zero, nil
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	cached, ok := s.cache[userID]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.at) < volumeCacheTTL {
+		return cached.volume, nil
+	}
+
+	volume, err := volumes.Volume(userID, s.opts.VolumeAsset, now.Add(-s.opts.Window))
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	s.mu.Lock()
+	s.cache[userID] = volumeEntry{volume: volume, at: now}
+	s.mu.Unlock()
+	return volume, nil
+}
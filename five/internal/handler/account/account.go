@@ -0,0 +1,112 @@
+package account
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"five/internal/logic/account"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetBalancesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		result, err := l.GetBalances()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func SetFeeTokenHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.FeeTokenReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		result, err := l.SetFeeToken(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func DepositHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.DepositReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		if req.UserID <= 0 {
+			httpx.ErrorCtx(r.Context(), w, errors.New("user_id is required"))
+			return
+		}
+
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		if err := l.Deposit(&req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.Ok(w)
+		}
+	}
+}
+
+func GetLedgerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asset := r.URL.Query().Get("asset")
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		result, err := l.GetLedger(asset, limit)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func AdjustHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.AdjustReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		if req.UserID <= 0 {
+			httpx.ErrorCtx(r.Context(), w, errors.New("user_id is required"))
+			return
+		}
+
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		if err := l.Adjust(&req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.Ok(w)
+		}
+	}
+}
+
+func AuditLedgerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := account.NewAccountLogic(r.Context(), svcCtx)
+		result, err := l.AuditLedger()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package deadletter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"five/internal/logic/deadletter"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func ListDeadLettersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := types.DeadLetterStatus(r.URL.Query().Get("status"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		l := deadletter.NewDeadLetterLogic(r.Context(), svcCtx)
+		result, err := l.ListDeadLetters(status, limit)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+// ReplayHandler 按 id 重放一条死信；all=true 时批量重放最多 limit 条未重放的死信
+func ReplayHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		l := deadletter.NewDeadLetterLogic(r.Context(), svcCtx)
+
+		if query.Get("all") == "true" {
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			n, err := l.ReplayPending(limit)
+			if err != nil {
+				httpx.ErrorCtx(r.Context(), w, err)
+			} else {
+				httpx.OkJson(w, map[string]int{"replayed": n})
+			}
+			return
+		}
+
+		id, _ := strconv.ParseUint(query.Get("id"), 10, 64)
+		if id == 0 {
+			httpx.ErrorCtx(r.Context(), w, errors.New("id is required"))
+			return
+		}
+		result, err := l.Replay(uint(id))
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
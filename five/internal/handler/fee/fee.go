@@ -0,0 +1,29 @@
+package fee
+
+import (
+	"errors"
+	"net/http"
+
+	"five/internal/logic/fee"
+	"five/internal/svc"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetUserRatesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			httpx.ErrorCtx(r.Context(), w, errors.New("symbol is required"))
+			return
+		}
+
+		l := fee.NewFeeLogic(r.Context(), svcCtx)
+		result, err := l.GetUserRates(symbol)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
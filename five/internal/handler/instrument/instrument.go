@@ -0,0 +1,37 @@
+package instrument
+
+import (
+	"net/http"
+
+	"five/internal/logic/instrument"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func ListInstrumentsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := types.InstrumentStatus(r.URL.Query().Get("status"))
+
+		l := instrument.NewInstrumentLogic(r.Context(), svcCtx)
+		httpx.OkJson(w, l.ListInstruments(status))
+	}
+}
+
+func UpdateInstrumentHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.Instrument
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := instrument.NewInstrumentLogic(r.Context(), svcCtx)
+		if err := l.UpdateInstrument(&req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, req)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package market
+
+import (
+	"net/http"
+
+	"five/internal/logic/market"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// MarketStreamHandler 公开行情 WebSocket：订阅 depth、trades、ticker 频道
+func MarketStreamHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svcCtx.Market.ServeHTTP(w, r)
+	}
+}
+
+// GetKlinesHandler 查询K线：symbol、interval 必填，start、end 为毫秒时间戳
+func GetKlinesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.KlinesReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := market.NewMarketLogic(r.Context(), svcCtx)
+		result, err := l.GetKlines(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
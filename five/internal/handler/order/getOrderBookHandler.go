@@ -0,0 +1,29 @@
+package order
+
+import (
+	"net/http"
+
+	"five/internal/logic/order"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetOrderBookHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.SymbolReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := order.NewGetOrderBookLogic(r.Context(), svcCtx)
+		resp, err := l.GetOrderBook(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
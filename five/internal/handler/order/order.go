@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/shopspring/decimal"
 	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
@@ -19,27 +20,37 @@ func CreateOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
+		// Idempotency-Key 请求头等同于 client_order_id
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			if req.ClientOrderID != "" && req.ClientOrderID != key {
+				httpx.ErrorCtx(r.Context(), w, errors.New("client_order_id conflicts with Idempotency-Key"))
+				return
+			}
+			req.ClientOrderID = key
+		}
+
 		l := order.NewOrderLogic(r.Context(), svcCtx)
-		err := l.CreateOrder(&req)
+		result, err := l.CreateOrder(&req)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
-			httpx.Ok(w)
+			httpx.OkJson(w, result)
 		}
 	}
 }
 
 func CancelOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Query().Get("order_id")
 		reason := r.URL.Query().Get("reason")
-		if orderID == "" {
-			httpx.ErrorCtx(r.Context(), w, errors.New("order_id is required"))
+
+		l := order.NewOrderLogic(r.Context(), svcCtx)
+		orderID, err := orderIDFromQuery(r, l)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
 			return
 		}
 
-		l := order.NewOrderLogic(r.Context(), svcCtx)
-		err := l.CancelOrder(orderID, reason)
+		err = l.CancelOrder(orderID, reason)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
@@ -50,23 +61,22 @@ func CancelOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 
 func FillOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Query().Get("order_id")
-		fillPrice, _ := strconv.ParseFloat(r.URL.Query().Get("price"), 64)
-		fillAmount, _ := strconv.ParseFloat(r.URL.Query().Get("amount"), 64)
-		feeRate, _ := strconv.ParseFloat(r.URL.Query().Get("fee_rate"), 64)
+		fillPrice, _ := decimal.NewFromString(r.URL.Query().Get("price"))
+		fillAmount, _ := decimal.NewFromString(r.URL.Query().Get("amount"))
 
-		if orderID == "" || fillPrice <= 0 || fillAmount <= 0 {
+		if !fillPrice.IsPositive() || !fillAmount.IsPositive() {
 			httpx.ErrorCtx(r.Context(), w, errors.New("invalid parameters"))
 			return
 		}
 
-		// 默认手续费率 0.1%
-		if feeRate <= 0 {
-			feeRate = 0.001
+		l := order.NewOrderLogic(r.Context(), svcCtx)
+		orderID, err := orderIDFromQuery(r, l)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
 		}
 
-		l := order.NewOrderLogic(r.Context(), svcCtx)
-		err := l.FillOrder(orderID, fillPrice, fillAmount, feeRate)
+		err = l.FillOrder(orderID, fillPrice, fillAmount)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
@@ -77,13 +87,13 @@ func FillOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 
 func GetOrderHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Query().Get("order_id")
-		if orderID == "" {
-			httpx.ErrorCtx(r.Context(), w, errors.New("order_id is required"))
+		l := order.NewOrderLogic(r.Context(), svcCtx)
+		orderID, err := orderIDFromQuery(r, l)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
 			return
 		}
 
-		l := order.NewOrderLogic(r.Context(), svcCtx)
 		result, err := l.GetOrder(orderID)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
@@ -115,13 +125,13 @@ func GetUserOrdersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 
 func GetOrderTradesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Query().Get("order_id")
-		if orderID == "" {
-			httpx.ErrorCtx(r.Context(), w, errors.New("order_id is required"))
+		l := order.NewOrderLogic(r.Context(), svcCtx)
+		orderID, err := orderIDFromQuery(r, l)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
 			return
 		}
 
-		l := order.NewOrderLogic(r.Context(), svcCtx)
 		result, err := l.GetOrderTrades(orderID)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
@@ -130,3 +140,22 @@ func GetOrderTradesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		}
 	}
 }
+
+// orderIDFromQuery 从 order_id，或 user_id 与 client_order_id 参数确定订单号
+func orderIDFromQuery(r *http.Request, l *order.OrderLogic) (string, error) {
+	query := r.URL.Query()
+	if orderID := query.Get("order_id"); orderID != "" {
+		return orderID, nil
+	}
+
+	userID, _ := strconv.ParseInt(query.Get("user_id"), 10, 64)
+	clientOrderID := query.Get("client_order_id")
+	if userID <= 0 || clientOrderID == "" {
+		return "", errors.New("order_id or user_id with client_order_id is required")
+	}
+	o, err := l.GetOrderByClientID(userID, clientOrderID)
+	if err != nil {
+		return "", err
+	}
+	return o.OrderID, nil
+}
@@ -0,0 +1,77 @@
+package projection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"five/internal/logic/projection"
+	"five/internal/svc"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetOpenOrdersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		if userID <= 0 {
+			httpx.ErrorCtx(r.Context(), w, errors.New("user_id is required"))
+			return
+		}
+
+		l := projection.NewProjectionLogic(r.Context(), svcCtx)
+		result, err := l.GetOpenOrders(userID)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func GetOrderCountsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		if userID <= 0 {
+			httpx.ErrorCtx(r.Context(), w, errors.New("user_id is required"))
+			return
+		}
+
+		l := projection.NewProjectionLogic(r.Context(), svcCtx)
+		result, err := l.GetOrderCounts(userID)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func GetSymbolStatsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			httpx.ErrorCtx(r.Context(), w, errors.New("symbol is required"))
+			return
+		}
+
+		l := projection.NewProjectionLogic(r.Context(), svcCtx)
+		result, err := l.GetSymbolStats(symbol)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func RebuildHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := projection.NewProjectionLogic(r.Context(), svcCtx)
+		if err := l.Rebuild(); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.Ok(w)
+		}
+	}
+}
@@ -7,16 +7,37 @@ import (
 	"context"
 	"net/http"
 
+	"five/internal/handler/account"
+	"five/internal/handler/deadletter"
+	"five/internal/handler/fee"
+	"five/internal/handler/instrument"
+	"five/internal/handler/market"
 	"five/internal/handler/order"
+	"five/internal/handler/projection"
+	"five/internal/handler/userstream"
 	logicOrder "five/internal/logic/order"  // 添加logic包的导入
 	"five/internal/svc"
 	"github.com/zeromicro/go-zero/rest"
 )
 
 func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
-	// 启动Kafka消费者
+	// 启动订单事件消费，构建读模型
 	orderLogic := logicOrder.NewOrderLogic(context.Background(), serverCtx)  // 使用logic包
 	orderLogic.StartKafkaConsumer()
+	// 启动发件箱转发，将订单事件发布到Kafka
+	orderLogic.StartOutboxRelay()
+	// 启动GTD订单到期清理
+	orderLogic.StartExpiryWorker()
+	// 启动订单状态快照
+	orderLogic.StartSnapshotWorker()
+	// 启动订单簿快照
+	orderLogic.StartCheckpointWorker()
+	// 启动公开行情推送
+	orderLogic.StartMarketGateway()
+	// 启动K线聚合
+	orderLogic.StartKlineAggregator()
+	// 启动用户数据流推送
+	orderLogic.StartUserStream()
 
 	server.AddRoutes(
 		[]rest.Route{
@@ -30,11 +51,6 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/order/cancel",
 				Handler: order.CancelOrderHandler(serverCtx),
 			},
-			{
-				Method:  http.MethodPost,
-				Path:    "/order/fill",
-				Handler: order.FillOrderHandler(serverCtx),
-			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/order/get",
@@ -50,6 +66,169 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/order/trades",
 				Handler: order.GetOrderTradesHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/order/book",
+				Handler: order.GetOrderBookHandler(serverCtx),
+			},
+		},
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/instrument/list",
+				Handler: instrument.ListInstrumentsHandler(serverCtx),
+			},
+		},
+	)
+
+	// 账户与费率只能由登录用户本人查询与设置，需鉴权
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.Auth},
+			[]rest.Route{
+				{
+					Method:  http.MethodGet,
+					Path:    "/account/balances",
+					Handler: account.GetBalancesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/account/ledger",
+					Handler: account.GetLedgerHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/account/fee-token",
+					Handler: account.SetFeeTokenHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/fee/rates",
+					Handler: fee.GetUserRatesHandler(serverCtx),
+				},
+			}...,
+		),
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/projection/open-orders",
+				Handler: projection.GetOpenOrdersHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/projection/order-counts",
+				Handler: projection.GetOrderCountsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/projection/symbol-stats",
+				Handler: projection.GetSymbolStatsHandler(serverCtx),
+			},
+		},
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/ws/market",
+				Handler: market.MarketStreamHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/market/klines",
+				Handler: market.GetKlinesHandler(serverCtx),
+			},
 		},
 	)
+
+	// listen key 归属于登录用户，需鉴权
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.Auth},
+			[]rest.Route{
+				{
+					Method:  http.MethodPost,
+					Path:    "/user-stream/listen-key",
+					Handler: userstream.CreateListenKeyHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/user-stream/listen-key",
+					Handler: userstream.KeepaliveListenKeyHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/user-stream/listen-key",
+					Handler: userstream.CloseListenKeyHandler(serverCtx),
+				},
+			}...,
+		),
+	)
+
+	// 用户数据流凭 listen key 连接
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/ws/user",
+				Handler: userstream.UserStreamHandler(serverCtx),
+			},
+		},
+	)
+
+	// 管理接口
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.Admin},
+			[]rest.Route{
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/order/fill",
+					Handler: order.FillOrderHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/instrument/update",
+					Handler: instrument.UpdateInstrumentHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/account/deposit",
+					Handler: account.DepositHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/account/adjust",
+					Handler: account.AdjustHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/admin/ledger/audit",
+					Handler: account.AuditLedgerHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/projection/rebuild",
+					Handler: projection.RebuildHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/admin/dlq/list",
+					Handler: deadletter.ListDeadLettersHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/dlq/replay",
+					Handler: deadletter.ReplayHandler(serverCtx),
+				},
+			}...,
+		),
+	)
 }
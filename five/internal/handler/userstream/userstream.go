@@ -0,0 +1,65 @@
+package userstream
+
+import (
+	"net/http"
+
+	"five/internal/logic/userstream"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func CreateListenKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := userstream.NewUserStreamLogic(r.Context(), svcCtx)
+		result, err := l.CreateListenKey()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func KeepaliveListenKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ListenKeyReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := userstream.NewUserStreamLogic(r.Context(), svcCtx)
+		result, err := l.KeepaliveListenKey(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJson(w, result)
+		}
+	}
+}
+
+func CloseListenKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ListenKeyReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := userstream.NewUserStreamLogic(r.Context(), svcCtx)
+		if err := l.CloseListenKey(&req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.Ok(w)
+		}
+	}
+}
+
+// UserStreamHandler 私有用户数据流 WebSocket：凭 listen_key 连接，推送订单、成交与余额变化
+func UserStreamHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svcCtx.UserStream.ServeHTTP(w, r)
+	}
+}
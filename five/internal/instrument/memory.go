@@ -0,0 +1,59 @@
+package instrument
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"five/internal/types"
+)
+
+// MemoryRegistry 进程内交易对注册表，不依赖数据库，用于离线单元测试
+type MemoryRegistry struct {
+	mu    sync.RWMutex
+	items map[string]types.Instrument
+}
+
+func NewMemoryRegistry(instruments ...types.Instrument) *MemoryRegistry {
+	r := &MemoryRegistry{items: make(map[string]types.Instrument, len(instruments))}
+	for _, inst := range instruments {
+		r.items[inst.Symbol] = inst
+	}
+	return r
+}
+
+func (r *MemoryRegistry) Get(symbol string) (*types.Instrument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inst, ok := r.items[symbol]
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return &inst, nil
+}
+
+func (r *MemoryRegistry) List(status types.InstrumentStatus) []types.Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]types.Instrument, 0, len(r.items))
+	for _, inst := range r.items {
+		if status == "" || inst.Status == status {
+			list = append(list, inst)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
+
+func (r *MemoryRegistry) Upsert(inst *types.Instrument) error {
+	if err := inst.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[inst.Symbol] = *inst
+	return nil
+}
@@ -0,0 +1,139 @@
+package instrument
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Catalog 交易对查询与维护，由数据库注册表与内存实现
+type Catalog interface {
+	Get(symbol string) (*types.Instrument, error)
+	List(status types.InstrumentStatus) []types.Instrument
+	Upsert(inst *types.Instrument) error
+}
+
+// Registry 交易对注册表，数据库为准，内存缓存供下单校验使用
+type Registry struct {
+	mu    sync.RWMutex
+	db    *gorm.DB
+	items map[string]*types.Instrument
+}
+
+func NewRegistry(db *gorm.DB) *Registry {
+	return &Registry{
+		db:    db,
+		items: make(map[string]*types.Instrument),
+	}
+}
+
+// Load 从数据库加载全部交易对
+func (r *Registry) Load() error {
+	var list []types.Instrument
+	if err := r.db.Find(&list).Error; err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = make(map[string]*types.Instrument, len(list))
+	for i := range list {
+		r.items[list[i].Symbol] = &list[i]
+	}
+	return nil
+}
+
+// Get 查询交易对，未注册时返回错误
+func (r *Registry) Get(symbol string) (*types.Instrument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inst, ok := r.items[symbol]
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	copied := *inst
+	return &copied, nil
+}
+
+// List 按交易对名称排序返回全部交易对，status 为空时不过滤
+func (r *Registry) List(status types.InstrumentStatus) []types.Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]types.Instrument, 0, len(r.items))
+	for _, inst := range r.items {
+		if status == "" || inst.Status == status {
+			list = append(list, *inst)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
+
+// Upsert 新增或更新交易对，写库成功后刷新缓存
+func (r *Registry) Upsert(inst *types.Instrument) error {
+	if err := inst.Validate(); err != nil {
+		return err
+	}
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "symbol"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"base_asset", "quote_asset", "price_tick", "qty_step",
+			"min_qty", "max_qty", "min_notional", "status", "updated_at",
+		}),
+	}).Create(inst).Error
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *inst
+	r.items[inst.Symbol] = &copied
+	return nil
+}
+
+// SeedDefaults 交易对表为空时写入默认交易对，方便本地联调
+func (r *Registry) SeedDefaults() error {
+	var count int64
+	if err := r.db.Model(&types.Instrument{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	defaults := Defaults()
+	for i := range defaults {
+		if err := r.Upsert(&defaults[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Defaults 默认交易对
+func Defaults() []types.Instrument {
+	return []types.Instrument{
+		{
+			Symbol: "BTC/USDT", BaseAsset: "BTC", QuoteAsset: "USDT",
+			PriceTick: decimal.RequireFromString("0.01"), QtyStep: decimal.RequireFromString("0.0001"),
+			MinQty: decimal.RequireFromString("0.0001"), MaxQty: decimal.RequireFromString("1000"),
+			MinNotional: decimal.RequireFromString("10"), Status: types.InstrumentStatusTrading,
+		},
+		{
+			Symbol: "ETH/USDT", BaseAsset: "ETH", QuoteAsset: "USDT",
+			PriceTick: decimal.RequireFromString("0.01"), QtyStep: decimal.RequireFromString("0.001"),
+			MinQty: decimal.RequireFromString("0.001"), MaxQty: decimal.RequireFromString("10000"),
+			MinNotional: decimal.RequireFromString("10"), Status: types.InstrumentStatusTrading,
+		},
+	}
+}
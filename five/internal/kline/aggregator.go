@@ -0,0 +1,562 @@
+package kline
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/instrument"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	leaderLock    = "order.kline_writer" // 写入实例持有的 MySQL 命名锁
+	leaderWaitSec = 5                    // 每次等待写入锁的秒数，超时后检查是否已退出再继续等待
+	retryInterval = time.Second          // 聚合中断后重新取得写入锁前的等待时间
+)
+
+// Options K 线聚合参数
+type Options struct {
+	Topic        string // 订单事件主题，K 线由其中的成交事件驱动
+	Group        string // 消费组，只有写入实例订阅
+	DefaultLimit int    // 查询未指定 limit 时返回的根数
+	MaxLimit     int    // 单次查询最多返回的根数
+}
+
+// pending 已结束但尚未写入数据库的 K 线，写入失败时保留到下次重试
+type pending struct {
+	symbol string
+	iv     Interval
+	bar    *types.Kline // 周期内没有成交时为 nil
+	end    time.Time    // 非零时同时将完整时间段推进到 end
+}
+
+// Aggregator 按成交生成各周期的 K 线。每个交易对每个周期在内存中维护当前周期的 K 线，
+// 周期结束后写入数据库并推进 K 线完整的时间段；查询早于该时间段的 K 线时从成交表回补。
+//
+// 多个实例同时运行时只有取得写入锁的实例（写入实例）消费成交事件并写入 K 线，其余实例等待接替，
+// 避免各实例重复计入同一笔成交；非写入实例查询时，尚未写入的 K 线由已写入的 1m K 线与其后的成交统计得到。
+//
+// 取得写入锁后从成交表与已写入的较短周期 K 线恢复当前周期，之后只处理发生在此之后的成交事件，
+// 此前的成交（含重新投递的事件）已计入。迟到的成交合并进已写入的 K 线。
+type Aggregator struct {
+	db          *gorm.DB
+	instruments instrument.Catalog
+	bus         bus.Bus
+	opts        Options
+
+	mu      sync.Mutex
+	writer  *gorm.DB                  // 写入实例持有写入锁的连接，未取得写入锁时为 nil
+	open    map[string][]*types.Kline // 各交易对当前周期的 K 线，下标与 Intervals 对应
+	pending []pending
+	since   time.Time
+
+	fillMu sync.Mutex // 串行化回补与完整时间段的创建
+}
+
+func NewAggregator(db *gorm.DB, instruments instrument.Catalog, b bus.Bus, opts Options) *Aggregator {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = 500
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 1000
+	}
+	return &Aggregator{
+		db:          db,
+		instruments: instruments,
+		bus:         b,
+		opts:        opts,
+		open:        make(map[string][]*types.Kline),
+	}
+}
+
+// Run 等待取得写入锁后聚合成交并写入 K 线，直到 ctx 结束
+func (a *Aggregator) Run(ctx context.Context) {
+	for {
+		err := a.lead(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("K线聚合中断，%v 后重新取得写入锁: %v\n", retryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// lead 取得写入锁后在持有锁的连接上聚合。连接断开时锁随之释放，由其他实例接替，
+// 原实例在下一次检查时发现失去写入锁并停止消费。SQLite 只有一个连接且仅用于单机，不加锁
+func (a *Aggregator) lead(ctx context.Context) error {
+	db := a.db.WithContext(ctx)
+	if db.Dialector.Name() != "mysql" {
+		return a.run(ctx, db, nil)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := acquire(ctx, conn); err != nil {
+			return err
+		}
+		defer conn.WithContext(context.Background()).Exec("SELECT RELEASE_LOCK(?)", leaderLock)
+		return a.run(ctx, conn, func() error { return holding(conn) })
+	})
+}
+
+// acquire 等待取得写入锁，直到取得或 ctx 结束
+func acquire(ctx context.Context, conn *gorm.DB) error {
+	for {
+		var got sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", leaderLock, leaderWaitSec).Row().Scan(&got); err != nil {
+			return err
+		}
+		if got.Valid && got.Int64 == 1 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// holding 连接是否仍持有写入锁
+func holding(conn *gorm.DB) error {
+	var held sql.NullInt64
+	if err := conn.Raw("SELECT IS_USED_LOCK(?) = CONNECTION_ID()", leaderLock).Row().Scan(&held); err != nil {
+		return err
+	}
+	if !held.Valid || held.Int64 != 1 {
+		return errors.New("kline writer lock lost")
+	}
+	return nil
+}
+
+// run 恢复各交易对当前周期的 K 线，订阅订单事件中的成交并按时结束周期，直到 ctx 结束或 check 报告失去写入锁。
+// 返回前停止消费并丢弃内存中的 K 线，下一次取得写入锁时从成交表恢复
+func (a *Aggregator) run(ctx context.Context, db *gorm.DB, check func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	a.writer, a.since = db, time.Now()
+	var err error
+	for _, inst := range a.instruments.List("") {
+		if _, err = a.series(ctx, inst.Symbol); err != nil {
+			break
+		}
+	}
+	a.mu.Unlock()
+	defer a.resign()
+	if err != nil {
+		return err
+	}
+
+	consumer := bus.NewConsumer(a.bus, a.opts.Topic, a.opts.Group, bus.RetryPolicy{}, a.handleEvent)
+	done, err := consumer.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			// 持有写入锁的连接只在 a.mu 内使用
+			a.mu.Lock()
+			if check != nil {
+				if err := check(); err != nil {
+					a.mu.Unlock()
+					return err
+				}
+			}
+			for symbol, bars := range a.open {
+				for i, iv := range Intervals {
+					if start := iv.Start(now); start.After(bars[i].OpenTime) {
+						a.roll(symbol, i, start)
+					}
+				}
+			}
+			a.flush(ctx)
+			a.mu.Unlock()
+		}
+	}
+}
+
+// resign 停止写入，丢弃内存中的 K 线
+func (a *Aggregator) resign() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.writer = nil
+	a.open = make(map[string][]*types.Kline)
+	a.pending = nil
+}
+
+// series 交易对当前周期的 K 线，首次访问时恢复（写入实例，调用方需持有锁）。
+// 当前周期中启动前的部分：1m 从成交表统计，更长的周期由已写入的较短周期 K 线加上较短周期的当前 K 线合并得到，
+// 避免重启时从成交表读取整周的成交
+func (a *Aggregator) series(ctx context.Context, symbol string) ([]*types.Kline, error) {
+	if bars, ok := a.open[symbol]; ok {
+		return bars, nil
+	}
+
+	bars := make([]*types.Kline, len(Intervals))
+	for i, iv := range Intervals {
+		start := iv.Start(a.since)
+		// 补齐上次停止到本次启动之间的 K 线，使完整时间段与当前周期相接
+		if err := a.ensure(ctx, a.writer, symbol, iv, start, start); err != nil {
+			return nil, err
+		}
+		bars[i] = newBar(symbol, iv, start)
+		if i == 0 {
+			err := scanTrades(ctx, a.writer, symbol, start, a.since, func(t *types.Trade) {
+				add(bars[0], t.Price, t.Amount, t.CreatedAt)
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		finer := Intervals[i-1]
+		if finerStart := finer.Start(a.since); start.Before(finerStart) {
+			if err := a.ensure(ctx, a.writer, symbol, finer, start, finerStart); err != nil {
+				return nil, err
+			}
+			closed, err := listBars(a.writer.WithContext(ctx), symbol, finer, start, finerStart)
+			if err != nil {
+				return nil, err
+			}
+			for j := range closed {
+				merge(bars[i], &closed[j])
+			}
+		}
+		merge(bars[i], bars[i-1])
+	}
+	a.open[symbol] = bars
+	return bars, nil
+}
+
+// ensure 从成交表回补，使 K 线完整的时间段覆盖 [from, to)，时间段不存在时以 [from, to) 创建。
+// 回补的都是取得写入锁前的成交，不会与成交事件重复计入。向后回补只由写入实例进行；
+// 向前回补按成交表覆盖写入，多个实例同时回补同一时间段的结果相同
+func (a *Aggregator) ensure(ctx context.Context, db *gorm.DB, symbol string, iv Interval, from, to time.Time) error {
+	a.fillMu.Lock()
+	defer a.fillMu.Unlock()
+
+	db = db.WithContext(ctx)
+	rng, err := loadRange(db, symbol, iv)
+	if err != nil {
+		return err
+	}
+	if rng == nil {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := a.backfill(ctx, tx, symbol, iv, from, to); err != nil {
+				return err
+			}
+			return tx.Create(&types.KlineRange{Symbol: symbol, Interval: iv.Name, StartTime: from, EndTime: to}).Error
+		})
+	}
+	if rng.EndTime.Before(to) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := a.backfill(ctx, tx, symbol, iv, rng.EndTime, to); err != nil {
+				return err
+			}
+			return extendEnd(tx, symbol, iv, to)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// 向前回补按批提交，超时中断后已回补的部分仍然有效
+	for start := rng.StartTime; from.Before(start); {
+		batchStart := start.Add(-batchSize * iv.Duration)
+		if batchStart.Before(from) {
+			batchStart = from
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := a.backfill(ctx, tx, symbol, iv, batchStart, start); err != nil {
+				return err
+			}
+			return extendStart(tx, symbol, iv, batchStart)
+		})
+		if err != nil {
+			return err
+		}
+		start = batchStart
+	}
+	return nil
+}
+
+func (a *Aggregator) backfill(ctx context.Context, tx *gorm.DB, symbol string, iv Interval, from, to time.Time) error {
+	if !from.Before(to) {
+		return nil
+	}
+	bars, err := aggregate(ctx, tx, symbol, iv, from, to)
+	if err != nil {
+		return err
+	}
+	return saveBars(tx, bars)
+}
+
+// handleEvent 处理订单事件中的成交。无法解码的事件直接跳过，由订单读模型的消费者送入死信
+func (a *Aggregator) handleEvent(ctx context.Context, msg bus.Message) error {
+	events, err := event.Decode(msg.Value, msg.Headers)
+	if err != nil {
+		return nil
+	}
+	for _, e := range events {
+		if trade := e.Trade(); trade != nil {
+			a.onTrade(ctx, trade)
+		}
+	}
+	return nil
+}
+
+// onTrade 一次撮合的 maker 与 taker 各有一条成交记录，只按 taker 一侧计入；人工成交没有对手方，直接计入
+func (a *Aggregator) onTrade(ctx context.Context, t *event.Trade) {
+	if t.CounterOrderID != "" && t.Role != types.LiquidityTaker {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.writer == nil || t.CreatedAt.Before(a.since) {
+		return
+	}
+	bars, err := a.series(ctx, t.Symbol)
+	if err != nil {
+		fmt.Printf("恢复 %s 的K线失败: %v\n", t.Symbol, err)
+		return
+	}
+	for i, iv := range Intervals {
+		start := iv.Start(t.CreatedAt)
+		if start.After(bars[i].OpenTime) {
+			a.roll(t.Symbol, i, start)
+		}
+		if start.Equal(bars[i].OpenTime) {
+			add(bars[i], t.Price, t.Amount, t.CreatedAt)
+			continue
+		}
+		// 迟到的成交，所在周期已结束
+		late := newBar(t.Symbol, iv, start)
+		add(late, t.Price, t.Amount, t.CreatedAt)
+		a.pending = append(a.pending, pending{symbol: t.Symbol, iv: iv, bar: late})
+	}
+	if len(a.pending) > 0 {
+		a.flush(ctx)
+	}
+}
+
+// roll 结束交易对第 i 个周期的当前 K 线，开始从 start 起的新周期（调用方需持有锁）
+func (a *Aggregator) roll(symbol string, i int, start time.Time) {
+	bars := a.open[symbol]
+	p := pending{symbol: symbol, iv: Intervals[i], end: start}
+	if bars[i].Trades > 0 {
+		p.bar = bars[i]
+	}
+	a.pending = append(a.pending, p)
+	bars[i] = newBar(symbol, Intervals[i], start)
+}
+
+// flush 按顺序写入已结束的 K 线，失败时保留剩余部分等待下次重试（写入实例，调用方需持有锁）
+func (a *Aggregator) flush(ctx context.Context) {
+	for len(a.pending) > 0 {
+		p := a.pending[0]
+		err := a.writer.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if p.bar != nil {
+				if err := mergeBar(tx, p.bar); err != nil {
+					return err
+				}
+			}
+			if !p.end.IsZero() {
+				return extendEnd(tx, p.symbol, p.iv, p.end)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("写入K线失败: %v\n", err)
+			return
+		}
+		a.pending = a.pending[1:]
+	}
+}
+
+// Query 查询 [start, end] 内的 K 线，按开盘时间升序。未指定 start 时返回截至 end 的最近 limit 根，
+// 否则返回从 start 起的 limit 根；最后一根可能是尚未结束的当前周期。早于完整时间段的部分先从成交表回补。
+// 写入实例合并内存中尚未写入的 K 线，其他实例统计完整时间段之后的部分
+func (a *Aggregator) Query(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]types.Kline, error) {
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.instruments.Get(symbol); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = a.opts.DefaultLimit
+	}
+	if limit > a.opts.MaxLimit {
+		limit = a.opts.MaxLimit
+	}
+	now := time.Now()
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+	from := iv.Start(start)
+	if start.IsZero() {
+		from = iv.Start(end).Add(-time.Duration(limit-1) * iv.Duration)
+	}
+	to := iv.Start(end).Add(iv.Duration)
+	if maxTo := from.Add(time.Duration(limit) * iv.Duration); maxTo.Before(to) {
+		to = maxTo
+	}
+	if !from.Before(to) {
+		return []types.Kline{}, nil
+	}
+
+	if err := a.backfillHistory(ctx, symbol, iv, from); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rows, err := listBars(a.db.WithContext(ctx), symbol, iv, from, to)
+	if err != nil {
+		return nil, err
+	}
+	bars := make(map[int64]*types.Kline, len(rows))
+	for i := range rows {
+		bars[rows[i].OpenTime.UnixMilli()] = &rows[i]
+	}
+	if a.writer == nil {
+		recent, err := a.recent(ctx, symbol, iv, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, bar := range recent {
+			bars[bar.OpenTime.UnixMilli()] = bar
+		}
+	}
+	// 尚未写入数据库的已结束 K 线与当前周期
+	include := func(bar *types.Kline) {
+		if bar == nil || bar.Trades == 0 || bar.OpenTime.Before(from) || !bar.OpenTime.Before(to) {
+			return
+		}
+		if k, ok := bars[bar.OpenTime.UnixMilli()]; ok {
+			merge(k, bar)
+			return
+		}
+		k := *bar
+		bars[bar.OpenTime.UnixMilli()] = &k
+	}
+	for _, p := range a.pending {
+		if p.symbol == symbol && p.iv == iv {
+			include(p.bar)
+		}
+	}
+	if open, ok := a.open[symbol]; ok {
+		for i := range Intervals {
+			if Intervals[i] == iv {
+				include(open[i])
+			}
+		}
+	}
+
+	out := make([]types.Kline, 0, len(bars))
+	for _, k := range bars {
+		k.Closed = !k.CloseTime.After(now)
+		out = append(out, *k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].OpenTime.Before(out[j].OpenTime) })
+	if len(out) > limit {
+		if start.IsZero() {
+			out = out[len(out)-limit:]
+		} else {
+			out = out[:limit]
+		}
+	}
+	return out, nil
+}
+
+// backfillHistory 查询早于完整时间段的 K 线时，先从成交表回补到 from。聚合器尚未恢复该交易对时不回补
+func (a *Aggregator) backfillHistory(ctx context.Context, symbol string, iv Interval, from time.Time) error {
+	rng, err := loadRange(a.db.WithContext(ctx), symbol, iv)
+	if err != nil || rng == nil || !from.Before(rng.StartTime) {
+		return err
+	}
+	return a.ensure(ctx, a.db, symbol, iv, from, rng.StartTime)
+}
+
+// recent 非写入实例统计 [from, to) 内完整时间段之后、尚未由写入实例写入的 K 线：
+// 1m 完整时间段内的部分合并已写入的 1m K 线，其后的部分从成交表统计。尚未有实例写入过时返回 nil
+func (a *Aggregator) recent(ctx context.Context, symbol string, iv Interval, from, to time.Time) ([]*types.Kline, error) {
+	db := a.db.WithContext(ctx)
+	rng, err := loadRange(db, symbol, iv)
+	if err != nil || rng == nil {
+		return nil, err
+	}
+	if rng.EndTime.After(from) {
+		from = rng.EndTime
+	}
+	if !from.Before(to) {
+		return nil, nil
+	}
+
+	bars := make(map[int64]*types.Kline)
+	put := func(o *types.Kline) {
+		start := iv.Start(o.OpenTime)
+		bar, ok := bars[start.UnixMilli()]
+		if !ok {
+			bar = newBar(symbol, iv, start)
+			bars[start.UnixMilli()] = bar
+		}
+		merge(bar, o)
+	}
+	split := from
+	if finest := Intervals[0]; iv != finest {
+		fine, err := loadRange(db, symbol, finest)
+		if err != nil {
+			return nil, err
+		}
+		if fine != nil && fine.EndTime.After(from) {
+			split = fine.EndTime
+			if split.After(to) {
+				split = to
+			}
+			rows, err := listBars(db, symbol, finest, from, split)
+			if err != nil {
+				return nil, err
+			}
+			for i := range rows {
+				put(&rows[i])
+			}
+		}
+	}
+	trades, err := aggregate(ctx, db, symbol, iv, split, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, bar := range trades {
+		put(bar)
+	}
+
+	out := make([]*types.Kline, 0, len(bars))
+	for _, bar := range bars {
+		out = append(out, bar)
+	}
+	return out, nil
+}
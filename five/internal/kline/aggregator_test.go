@@ -0,0 +1,220 @@
+package kline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/instrument"
+	"five/internal/repository"
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const testSymbol = "BTC/USDT"
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func newTestAggregator(t *testing.T, db *gorm.DB) *Aggregator {
+	t.Helper()
+	instruments := instrument.NewMemoryRegistry(types.Instrument{Symbol: testSymbol, BaseAsset: "BTC", QuoteAsset: "USDT"})
+	return NewAggregator(db, instruments, bus.NewMemoryBus(1), Options{Topic: "orders", Group: "kline"})
+}
+
+// insertTrade 写入一次撮合的 maker 与 taker 两条成交记录，返回 taker 一侧
+func insertTrade(t *testing.T, db *gorm.DB, price, amount string, at time.Time) *types.Trade {
+	t.Helper()
+	matchID := fmt.Sprintf("m-%d", at.UnixNano())
+	var taker *types.Trade
+	for _, role := range []types.LiquidityRole{types.LiquidityMaker, types.LiquidityTaker} {
+		trade := &types.Trade{
+			CreatedAt:      at,
+			TradeID:        matchID + "-" + string(role),
+			MatchID:        matchID,
+			OrderID:        "o-" + string(role),
+			CounterOrderID: "c-" + string(role),
+			Symbol:         testSymbol,
+			Price:          dec(price),
+			Amount:         dec(amount),
+			Role:           role,
+		}
+		if err := db.Create(trade).Error; err != nil {
+			t.Fatal(err)
+		}
+		taker = trade
+	}
+	return taker
+}
+
+// startWriter 运行聚合器直到取得写入锁并恢复当前周期，返回停止函数
+func startWriter(t *testing.T, a *Aggregator) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		a.Run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.mu.Lock()
+		ready := a.writer != nil && a.open[testSymbol] != nil
+		a.mu.Unlock()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("aggregator did not start writing")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+// totals 查询结果的成交量与成交笔数之和
+func totals(t *testing.T, a *Aggregator, interval string, start time.Time) (decimal.Decimal, int64) {
+	t.Helper()
+	bars, err := a.Query(context.Background(), testSymbol, interval, start, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var volume decimal.Decimal
+	var trades int64
+	for _, bar := range bars {
+		volume = volume.Add(bar.Volume)
+		trades += bar.Trades
+	}
+	return volume, trades
+}
+
+func TestAggregateUsesTakerSide(t *testing.T) {
+	db, err := repository.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := Intervals[3].Start(time.Now()).Add(-3 * time.Hour)
+	insertTrade(t, db, "100", "1", hour.Add(10*time.Minute))
+	insertTrade(t, db, "105", "2", hour.Add(20*time.Minute))
+	insertTrade(t, db, "95", "1", hour.Add(30*time.Minute))
+	insertTrade(t, db, "101", "0.5", hour.Add(40*time.Minute))
+	// 人工成交没有对手方，计入
+	manual := &types.Trade{CreatedAt: hour.Add(50 * time.Minute), TradeID: "manual", OrderID: "o", Symbol: testSymbol,
+		Price: dec("102"), Amount: dec("1"), Role: types.LiquidityMaker}
+	if err := db.Create(manual).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bars, err := aggregate(context.Background(), db, testSymbol, Intervals[3], hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 1 {
+		t.Fatalf("got %d bars, want 1", len(bars))
+	}
+	bar := bars[0]
+	if !bar.OpenTime.Equal(hour) || !bar.CloseTime.Equal(hour.Add(time.Hour)) {
+		t.Fatalf("bar spans %s - %s, want the hour from %s", bar.OpenTime, bar.CloseTime, hour)
+	}
+	for name, got := range map[string][2]decimal.Decimal{
+		"open":   {bar.Open, dec("100")},
+		"high":   {bar.High, dec("105")},
+		"low":    {bar.Low, dec("95")},
+		"close":  {bar.Close, dec("102")},
+		"volume": {bar.Volume, dec("5.5")},
+		"quote":  {bar.QuoteVolume, dec("557.5")},
+	} {
+		if !got[0].Equal(got[1]) {
+			t.Errorf("%s = %s, want %s", name, got[0], got[1])
+		}
+	}
+	if bar.Trades != 5 {
+		t.Errorf("trades = %d, want 5", bar.Trades)
+	}
+}
+
+func TestQueryBackfillsHistory(t *testing.T) {
+	db, err := repository.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := Intervals[3].Start(time.Now()).Add(-2 * time.Hour)
+	insertTrade(t, db, "100", "1", hour.Add(5*time.Minute))
+	insertTrade(t, db, "110", "2", hour.Add(65*time.Minute))
+
+	a := newTestAggregator(t, db)
+	stop := startWriter(t, a)
+	defer stop()
+
+	for _, interval := range []string{"1m", "5m", "1h", "4h"} {
+		volume, trades := totals(t, a, interval, hour)
+		if !volume.Equal(dec("3")) || trades != 2 {
+			t.Errorf("%s: volume %s over %d trades, want 3 over 2", interval, volume, trades)
+		}
+	}
+	// 回补的 K 线已写入，完整时间段向前延伸到查询起点
+	rng, err := loadRange(db, testSymbol, Intervals[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rng == nil || rng.StartTime.After(hour) {
+		t.Fatalf("1h range %+v does not cover %s", rng, hour)
+	}
+	var written int64
+	db.Model(&types.Kline{}).Where("symbol = ? AND period = ?", testSymbol, "1h").Count(&written)
+	if written != 2 {
+		t.Fatalf("wrote %d 1h bars, want 2", written)
+	}
+}
+
+// 多个实例共用数据库时，成交只由写入实例计入一次；其他实例与接替的写入实例查询结果一致
+func TestTradesCountedOnceAcrossInstances(t *testing.T) {
+	db, err := repository.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := Intervals[3].Start(time.Now()).Add(-time.Hour)
+	insertTrade(t, db, "100", "1", start.Add(time.Minute))
+
+	writer := newTestAggregator(t, db)
+	stop := startWriter(t, writer)
+	follower := newTestAggregator(t, db)
+
+	// 取得写入锁之后的成交由事件计入
+	trade := insertTrade(t, db, "101", "2", time.Now())
+	writer.onTrade(context.Background(), ptr(event.NewTrade(trade)))
+	follower.onTrade(context.Background(), ptr(event.NewTrade(trade)))
+
+	for _, a := range []*Aggregator{writer, follower} {
+		for _, interval := range []string{"1m", "1h", "1d"} {
+			volume, trades := totals(t, a, interval, start)
+			if !volume.Equal(dec("3")) || trades != 2 {
+				t.Errorf("%s: volume %s over %d trades, want 3 over 2", interval, volume, trades)
+			}
+		}
+	}
+
+	// 写入实例退出，其他实例接替后从成交表恢复当前周期
+	stop()
+	stop = startWriter(t, follower)
+	defer stop()
+	for _, interval := range []string{"1m", "1h", "1d", "1w"} {
+		volume, trades := totals(t, follower, interval, start)
+		if !volume.Equal(dec("3")) || trades != 2 {
+			t.Errorf("after takeover %s: volume %s over %d trades, want 3 over 2", interval, volume, trades)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
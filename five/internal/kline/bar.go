@@ -0,0 +1,57 @@
+package kline
+
+import (
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// newBar symbol 在 iv 周期中从 openTime 开始的空 K 线
+func newBar(symbol string, iv Interval, openTime time.Time) *types.Kline {
+	return &types.Kline{
+		Symbol:    symbol,
+		Interval:  iv.Name,
+		OpenTime:  openTime,
+		CloseTime: openTime.Add(iv.Duration),
+	}
+}
+
+// add 计入一笔成交。成交可能乱序到达，开盘价与收盘价按成交时间取最早与最晚的一笔
+func add(k *types.Kline, price, amount decimal.Decimal, at time.Time) {
+	merge(k, &types.Kline{
+		Open:         price,
+		High:         price,
+		Low:          price,
+		Close:        price,
+		Volume:       amount,
+		QuoteVolume:  price.Mul(amount),
+		Trades:       1,
+		FirstTradeAt: at,
+		LastTradeAt:  at,
+	})
+}
+
+// merge 将同一周期内（或更短周期）的 K 线 o 合并进 k
+func merge(k, o *types.Kline) {
+	if o.Trades == 0 {
+		return
+	}
+	if k.Trades == 0 {
+		k.Open, k.High, k.Low, k.Close = o.Open, o.High, o.Low, o.Close
+		k.FirstTradeAt, k.LastTradeAt = o.FirstTradeAt, o.LastTradeAt
+	} else {
+		if o.FirstTradeAt.Before(k.FirstTradeAt) {
+			k.Open, k.FirstTradeAt = o.Open, o.FirstTradeAt
+		}
+		if !o.LastTradeAt.Before(k.LastTradeAt) {
+			k.Close, k.LastTradeAt = o.Close, o.LastTradeAt
+		}
+		k.High = decimal.Max(k.High, o.High)
+		k.Low = decimal.Min(k.Low, o.Low)
+	}
+	k.Volume = k.Volume.Add(o.Volume)
+	k.QuoteVolume = k.QuoteVolume.Add(o.QuoteVolume)
+	k.Trades += o.Trades
+}
@@ -0,0 +1,50 @@
+package kline
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interval K 线周期
+type Interval struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Intervals 支持的周期，按时长升序，每个周期都是前一个周期的整数倍，较长周期可由较短周期合并得到
+var Intervals = []Interval{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"1h", time.Hour},
+	{"4h", 4 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"1w", 7 * 24 * time.Hour},
+}
+
+// 1970-01-01 是星期四，周线从其后的星期一起算
+const weekOffset = 4 * 24 * time.Hour
+
+// ParseInterval 按名称查找周期
+func ParseInterval(name string) (Interval, error) {
+	for _, iv := range Intervals {
+		if iv.Name == name {
+			return iv, nil
+		}
+	}
+	return Interval{}, fmt.Errorf("unsupported interval %q", name)
+}
+
+// Start t 所在周期的起始时间：按 UTC 对齐，日线从 0 点开始，周线从星期一 0 点开始
+func (iv Interval) Start(t time.Time) time.Time {
+	ms, size := t.UnixMilli(), iv.Duration.Milliseconds()
+	var offset int64
+	if iv.Duration == 7*24*time.Hour {
+		offset = weekOffset.Milliseconds()
+	}
+	rem := (ms - offset) % size
+	if rem < 0 {
+		rem += size
+	}
+	return time.UnixMilli(ms - rem)
+}
@@ -0,0 +1,120 @@
+package kline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"five/internal/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 按批读取成交与写入 K 线的批大小
+const batchSize = 1000
+
+// barColumns 写入 K 线时更新的列
+var barColumns = []string{"close_time", "open", "high", "low", "close", "volume", "quote_volume", "trades",
+	"first_trade_at", "last_trade_at", "updated_at"}
+
+// scanTrades 按批读取 symbol 在 [from, to) 内计入 K 线的成交：taker 一侧与人工成交
+func scanTrades(ctx context.Context, db *gorm.DB, symbol string, from, to time.Time, fn func(t *types.Trade)) error {
+	var batch []types.Trade
+	return db.WithContext(ctx).Select("id", "price", "amount", "created_at").
+		Where("symbol = ? AND created_at >= ? AND created_at < ?", symbol, from, to).
+		Where("role = ? OR counter_order_id = ''", types.LiquidityTaker).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				fn(&batch[i])
+			}
+			return nil
+		}).Error
+}
+
+// aggregate 从成交表统计 symbol 在 [from, to) 内 iv 周期的 K 线，按开盘时间升序返回
+func aggregate(ctx context.Context, db *gorm.DB, symbol string, iv Interval, from, to time.Time) ([]*types.Kline, error) {
+	bars := make(map[int64]*types.Kline)
+	err := scanTrades(ctx, db, symbol, from, to, func(t *types.Trade) {
+		start := iv.Start(t.CreatedAt)
+		bar, ok := bars[start.UnixMilli()]
+		if !ok {
+			bar = newBar(symbol, iv, start)
+			bars[start.UnixMilli()] = bar
+		}
+		add(bar, t.Price, t.Amount, t.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*types.Kline, 0, len(bars))
+	for _, bar := range bars {
+		out = append(out, bar)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].OpenTime.Before(out[j].OpenTime) })
+	return out, nil
+}
+
+// saveBars 写入 K 线，已存在的覆盖
+func saveBars(tx *gorm.DB, bars []*types.Kline) error {
+	if len(bars) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "period"}, {Name: "open_time"}},
+		DoUpdates: clause.AssignmentColumns(barColumns),
+	}).CreateInBatches(bars, batchSize).Error
+}
+
+// mergeBar 将 K 线合并进已持久化的同一根 K 线，不存在时直接写入
+func mergeBar(tx *gorm.DB, bar *types.Kline) error {
+	var existing types.Kline
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("symbol = ? AND period = ? AND open_time = ?", bar.Symbol, bar.Interval, bar.OpenTime).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		merged := *bar
+		return saveBars(tx, []*types.Kline{&merged})
+	}
+	if err != nil {
+		return err
+	}
+	merge(&existing, bar)
+	return tx.Save(&existing).Error
+}
+
+// listBars 查询 symbol 在 iv 周期中开盘时间位于 [from, to) 的 K 线
+func listBars(db *gorm.DB, symbol string, iv Interval, from, to time.Time) ([]types.Kline, error) {
+	var bars []types.Kline
+	err := db.Where("symbol = ? AND period = ? AND open_time >= ? AND open_time < ?", symbol, iv.Name, from, to).
+		Order("open_time ASC").Find(&bars).Error
+	return bars, err
+}
+
+// loadRange 查询 K 线完整的时间段，不存在时返回 nil
+func loadRange(db *gorm.DB, symbol string, iv Interval) (*types.KlineRange, error) {
+	var rng types.KlineRange
+	err := db.Where("symbol = ? AND period = ?", symbol, iv.Name).First(&rng).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rng, nil
+}
+
+// extendStart 将完整时间段的起点提前到 start
+func extendStart(tx *gorm.DB, symbol string, iv Interval, start time.Time) error {
+	return tx.Model(&types.KlineRange{}).
+		Where("symbol = ? AND period = ? AND start_time > ?", symbol, iv.Name, start).
+		Update("start_time", start).Error
+}
+
+// extendEnd 将完整时间段的终点推进到 end
+func extendEnd(tx *gorm.DB, symbol string, iv Interval, end time.Time) error {
+	return tx.Model(&types.KlineRange{}).
+		Where("symbol = ? AND period = ? AND end_time < ?", symbol, iv.Name, end).
+		Update("end_time", end).Error
+}
@@ -0,0 +1,85 @@
+package account
+
+import (
+	"context"
+	"errors"
+
+	"five/internal/account"
+	"five/internal/middleware"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+type AccountLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewAccountLogic(ctx context.Context, svcCtx *svc.ServiceContext) *AccountLogic {
+	return &AccountLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetBalances 查询当前登录用户各币种的可用与冻结余额
+func (l *AccountLogic) GetBalances() ([]types.Account, error) {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return account.List(l.svcCtx.MySQL, userID)
+}
+
+// SetFeeToken 当前登录用户开启或关闭平台币抵扣手续费
+func (l *AccountLogic) SetFeeToken(req *types.FeeTokenReq) (*types.FeeSetting, error) {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	if req.Enabled && l.svcCtx.Fees.Token() == "" {
+		return nil, errors.New("fee token is not configured")
+	}
+	return account.SetFeeToken(l.svcCtx.MySQL, userID, req.Enabled)
+}
+
+// Deposit 管理员为用户入金
+func (l *AccountLogic) Deposit(req *types.DepositReq) error {
+	err := l.svcCtx.MySQL.Transaction(func(tx *gorm.DB) error {
+		return account.Deposit(tx, req.UserID, req.Asset, req.Amount)
+	})
+	if err != nil {
+		return err
+	}
+	// 入金不产生订单事件，直接通知用户数据流
+	l.svcCtx.UserStream.NotifyBalance(l.ctx, req.UserID, req.Asset)
+	return nil
+}
+
+// Adjust 管理员人工调账
+func (l *AccountLogic) Adjust(req *types.AdjustReq) error {
+	err := l.svcCtx.MySQL.Transaction(func(tx *gorm.DB) error {
+		return account.Adjust(tx, req.UserID, req.Asset, req.Amount, req.Remark)
+	})
+	if err != nil {
+		return err
+	}
+	l.svcCtx.UserStream.NotifyBalance(l.ctx, req.UserID, req.Asset)
+	return nil
+}
+
+// GetLedger 查询当前登录用户的账本流水
+func (l *AccountLogic) GetLedger(asset string, limit int) ([]types.LedgerEntry, error) {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return account.History(l.svcCtx.MySQL, userID, asset, limit)
+}
+
+// AuditLedger 全量对账：每个币种分录之和为零且账户余额与分录一致
+func (l *AccountLogic) AuditLedger() (*account.AuditReport, error) {
+	return account.Audit(l.svcCtx.MySQL)
+}
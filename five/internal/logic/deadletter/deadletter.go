@@ -0,0 +1,36 @@
+package deadletter
+
+import (
+	"context"
+
+	"five/internal/deadletter"
+	"five/internal/svc"
+	"five/internal/types"
+)
+
+type DeadLetterLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeadLetterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeadLetterLogic {
+	return &DeadLetterLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListDeadLetters 查询死信，status 为 pending（未重放）、replayed（已重放）或空（全部）
+func (l *DeadLetterLogic) ListDeadLetters(status types.DeadLetterStatus, limit int) ([]types.DeadLetter, error) {
+	return deadletter.List(l.svcCtx.MySQL.WithContext(l.ctx), status, limit)
+}
+
+// Replay 将一条死信重新发布到最初的主题
+func (l *DeadLetterLogic) Replay(id uint) (*types.DeadLetter, error) {
+	return deadletter.Replay(l.ctx, l.svcCtx.MySQL, l.svcCtx.Bus, id)
+}
+
+// ReplayPending 批量重放尚未重放的死信，返回重放条数
+func (l *DeadLetterLogic) ReplayPending(limit int) (int, error) {
+	return deadletter.ReplayPending(l.ctx, l.svcCtx.MySQL, l.svcCtx.Bus, limit)
+}
@@ -0,0 +1,34 @@
+package fee
+
+import (
+	"context"
+	"errors"
+
+	"five/internal/fee"
+	"five/internal/middleware"
+	"five/internal/svc"
+)
+
+type FeeLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewFeeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *FeeLogic {
+	return &FeeLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetUserRates 查询当前登录用户在交易对上的 VIP 等级与 maker/taker 费率
+func (l *FeeLogic) GetUserRates(symbol string) (*fee.UserRates, error) {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	if _, err := l.svcCtx.Instruments.Get(symbol); err != nil {
+		return nil, err
+	}
+	return l.svcCtx.Fees.UserRates(userID, symbol)
+}
@@ -0,0 +1,33 @@
+package instrument
+
+import (
+	"context"
+
+	"five/internal/svc"
+	"five/internal/types"
+)
+
+type InstrumentLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewInstrumentLogic(ctx context.Context, svcCtx *svc.ServiceContext) *InstrumentLogic {
+	return &InstrumentLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListInstruments 查询交易对列表
+func (l *InstrumentLogic) ListInstruments(status types.InstrumentStatus) []types.Instrument {
+	return l.svcCtx.Instruments.List(status)
+}
+
+// UpdateInstrument 新增或更新交易对，并同步撮合引擎的价格与数量步长
+func (l *InstrumentLogic) UpdateInstrument(inst *types.Instrument) error {
+	if err := l.svcCtx.Instruments.Upsert(inst); err != nil {
+		return err
+	}
+	return l.svcCtx.Matcher.Configure(inst.Symbol, inst.PriceTick, inst.QtyStep)
+}
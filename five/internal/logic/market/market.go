@@ -0,0 +1,40 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"five/internal/svc"
+	"five/internal/types"
+)
+
+type MarketLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMarketLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MarketLogic {
+	return &MarketLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetKlines 查询交易对的K线
+func (l *MarketLogic) GetKlines(req *types.KlinesReq) ([]types.Kline, error) {
+	if req.Start < 0 || req.End < 0 {
+		return nil, errors.New("start and end must be unix milliseconds")
+	}
+	var start, end time.Time
+	if req.Start > 0 {
+		start = time.UnixMilli(req.Start)
+	}
+	if req.End > 0 {
+		end = time.UnixMilli(req.End)
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+	return l.svcCtx.Klines.Query(l.ctx, req.Symbol, req.Interval, start, end, req.Limit)
+}
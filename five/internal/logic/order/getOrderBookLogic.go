@@ -2,13 +2,20 @@ package order
 
 import (
 	"context"
+	"errors"
 
 	"five/internal/svc"
 	"five/internal/types"
 
+	"github.com/shopspring/decimal"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+const (
+	defaultBookDepth = 20
+	maxBookDepth     = 500
+)
+
 type GetOrderBookLogic struct {
 	logx.Logger
 	ctx    context.Context
@@ -23,8 +30,40 @@ func NewGetOrderBookLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetO
 	}
 }
 
+// GetOrderBook 从撮合引擎的内存订单簿读取聚合深度
 func (l *GetOrderBookLogic) GetOrderBook(req *types.SymbolReq) (resp *types.OrderBookResp, err error) {
-	// todo: add your logic here and delete this line
+	if req.Symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	precision := decimal.Zero
+	if req.Precision != "" {
+		var err error
+		if precision, err = decimal.NewFromString(req.Precision); err != nil {
+			return nil, errors.New("invalid precision")
+		}
+		if precision.IsNegative() {
+			return nil, errors.New("precision must not be negative")
+		}
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = defaultBookDepth
+	}
+	if depth > maxBookDepth {
+		depth = maxBookDepth
+	}
 
-	return
+	bids, asks := l.svcCtx.Matcher.Depth(req.Symbol, depth, precision)
+	resp = &types.OrderBookResp{
+		Bids: make([]types.OrderItem, 0, len(bids)),
+		Asks: make([]types.OrderItem, 0, len(asks)),
+	}
+	for _, level := range bids {
+		resp.Bids = append(resp.Bids, types.OrderItem{Price: level.Price.String(), Amount: level.Amount.String()})
+	}
+	for _, level := range asks {
+		resp.Asks = append(resp.Asks, types.OrderItem{Price: level.Price.String(), Amount: level.Amount.String()})
+	}
+	return resp, nil
 }
@@ -3,37 +3,116 @@ package order
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"five/internal/account"
+	"five/internal/event"
+	"five/internal/matching"
+	"five/internal/repository"
 	"five/internal/svc"
 	"five/internal/types"
 	"fmt"
 	"time"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/utils"
+)
+
+const (
+	maxClientOrderIDLen = 64 // 客户端订单号最大长度
+	maxTxRetries        = 3  // 乐观锁冲突、死锁或等待行锁超时时事务的最大重试次数
+	maxRepriceAttempts  = 3  // POST_ONLY 订单对手价变化时的最大改价次数
 )
 
 type OrderLogic struct {
+	logx.Logger
 	ctx    context.Context
 	svcCtx *svc.ServiceContext
 }
 
 func NewOrderLogic(ctx context.Context, svcCtx *svc.ServiceContext) *OrderLogic {
 	return &OrderLogic{
+		Logger: logx.WithContext(ctx),
 		ctx:    ctx,
 		svcCtx: svcCtx,
 	}
 }
 
-// CreateOrder 创建订单（挂单）
-func (l *OrderLogic) CreateOrder(order *types.Order) error {
+// CreateOrder 创建订单（挂单），订单号由服务端生成。
+// 同一用户重复提交相同 client_order_id 时直接返回已存在的订单，不会重复下单。
+func (l *OrderLogic) CreateOrder(order *types.Order) (*types.Order, error) {
 	// 确保所有字段都有值
-	if order.OrderID == "" {
-		return fmt.Errorf("order_id is required")
-	}
 	if order.UserID == 0 {
-		return fmt.Errorf("user_id is required")
+		return nil, fmt.Errorf("user_id is required")
 	}
 	if order.Symbol == "" {
-		order.Symbol = "BTC/USDT"
+		return nil, fmt.Errorf("symbol is required")
+	}
+	if len(order.ClientOrderID) > maxClientOrderIDLen {
+		return nil, fmt.Errorf("client_order_id must not exceed %d characters", maxClientOrderIDLen)
+	}
+
+	// 幂等：客户端订单号已存在时返回原订单
+	if order.ClientOrderID != "" {
+		existing, err := l.findByClientOrderID(order)
+		if existing != nil || err != nil {
+			return existing, err
+		}
+	}
+
+	if err := l.createOrder(order); err != nil {
+		// 并发重复提交时以唯一索引兜底，返回先写入的订单
+		if order.ClientOrderID != "" && errors.Is(err, repository.ErrDuplicate) {
+			if existing, ferr := l.findByClientOrderID(order); existing != nil || ferr != nil {
+				return existing, ferr
+			}
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// findByClientOrderID 查询同一客户端订单号的已有订单，请求参数与原订单不一致时返回错误
+func (l *OrderLogic) findByClientOrderID(req *types.Order) (*types.Order, error) {
+	existing, err := l.GetOrderByClientID(req.UserID, req.ClientOrderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sameRequest(existing, req) {
+		return nil, fmt.Errorf("client_order_id %s already used by order %s with different parameters",
+			req.ClientOrderID, existing.OrderID)
+	}
+	return existing, nil
+}
+
+// sameRequest 判断重复请求与原订单的下单参数是否一致，仅比较请求中指定了的字段
+func sameRequest(existing, req *types.Order) bool {
+	if req.Symbol != existing.Symbol {
+		return false
+	}
+	if req.OrderSide != "" && req.OrderSide != existing.OrderSide {
+		return false
+	}
+	if req.OrderType != "" && req.OrderType != existing.OrderType && req.OrderType != existing.TriggerType {
+		return false
+	}
+	if !req.Price.IsZero() && !req.Price.Equal(existing.Price) {
+		return false
+	}
+	if !req.Amount.IsZero() && !req.Amount.Equal(existing.Amount) {
+		return false
+	}
+	return req.QuoteAmount.IsZero() || req.QuoteAmount.Equal(existing.QuoteAmount)
+}
+
+// createOrder 校验并写入新订单，随后进入触发队列或撮合
+func (l *OrderLogic) createOrder(order *types.Order) error {
+	inst, err := l.svcCtx.Instruments.Get(order.Symbol)
+	if err != nil {
+		return err
 	}
 	if order.OrderType == "" {
 		order.OrderType = types.OrderTypeLimit
@@ -41,192 +120,833 @@ func (l *OrderLogic) CreateOrder(order *types.Order) error {
 	if order.OrderSide == "" {
 		order.OrderSide = types.OrderSideBuy
 	}
-	if order.Price <= 0 {
-		return fmt.Errorf("price must be positive")
+	if err := l.normalizeTimeInForce(order); err != nil {
+		return err
 	}
-	if order.Amount <= 0 {
-		return fmt.Errorf("amount must be positive")
+	if err := l.normalizeTrigger(order); err != nil {
+		return err
 	}
-	
+	if order.OrderType.ExecType() == types.OrderTypeMarket {
+		// 市价单不指定价格：买单按计价币金额，卖单按基础币数量
+		order.Price = decimal.Zero
+		if order.OrderSide == types.OrderSideBuy {
+			if !order.QuoteAmount.IsPositive() {
+				return fmt.Errorf("quote_amount must be positive for market buy")
+			}
+			order.Amount = decimal.Zero
+		} else {
+			if !order.Amount.IsPositive() {
+				return fmt.Errorf("amount must be positive")
+			}
+			order.QuoteAmount = decimal.Zero
+		}
+	} else {
+		if !order.Price.IsPositive() {
+			return fmt.Errorf("price must be positive")
+		}
+		if !order.Amount.IsPositive() {
+			return fmt.Errorf("amount must be positive")
+		}
+		order.QuoteAmount = decimal.Zero
+	}
+
+	// 按交易对规则校验价格步长、数量步长与最小金额
+	if err := inst.CheckOrder(order); err != nil {
+		return err
+	}
+
 	// 设置默认值
-	order.FilledAmount = 0
-	order.Fee = 0
-	order.FeeAsset = "USDT"
+	order.OrderID = utils.NewUuid()
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = order.OrderID
+	}
+	order.FilledAmount = decimal.Zero
+	order.FilledQuote = decimal.Zero
+	order.Fee = decimal.Zero
+	order.TokenFee = decimal.Zero
+	order.FeeAsset = receivedAsset(order, inst)
 	order.Status = types.OrderStatusPending
 	order.CancelReason = ""
+	order.TriggeredAt = nil
+	if order.OrderType.IsTrigger() {
+		order.TriggerType = order.OrderType
+		order.Status = types.OrderStatusUntriggered
+	} else {
+		order.TriggerType = ""
+	}
 
-	// 1. 写入MySQL - 直接使用结构体，让GORM处理字段映射
-	if err := l.svcCtx.MySQL.Create(order).Error; err != nil {
+	// 1. 冻结下单资金、写入订单并写入发件箱消息，余额不足时整单失败
+	asset, frozen := freezeRequirement(order, inst)
+	order.Frozen = frozen
+	err = l.svcCtx.Store.Transaction(func(tx repository.Store) error {
+		if err := tx.Accounts().Post(account.FreezeJournal(order.UserID, asset, frozen, order.OrderID)); err != nil {
+			return err
+		}
+		if err := tx.Orders().Create(order); err != nil {
+			return err
+		}
+		return l.publish(tx, order.OrderID, &event.OrderAccepted{Order: event.NewOrder(order)})
+	})
+	if err != nil {
 		return err
 	}
 
 	// 2. 写入Redis缓存
-	if err := l.updateOrderCache(order); err != nil {
+	l.updateOrderCache(order)
+
+	// 3. 条件单进入触发队列，普通订单进入撮合引擎；同一交易对的撮合与落库在写锁内串行执行
+	defer l.svcCtx.Matcher.Lock(order.Symbol)()
+	if order.Status == types.OrderStatusUntriggered {
+		return l.svcCtx.Matcher.AddTrigger(order)
+	}
+	return l.matchOrder(order)
+}
+
+// freezeRequirement 下单需冻结的资产与数量：买单冻结计价币金额，卖单冻结基础币数量。
+// 手续费从成交收到的资产中扣除，无需预留。
+func freezeRequirement(order *types.Order, inst *types.Instrument) (string, decimal.Decimal) {
+	if order.OrderSide == types.OrderSideSell {
+		return inst.BaseAsset, order.Amount
+	}
+	if order.IsMarketBuy() {
+		return inst.QuoteAsset, order.QuoteAmount
+	}
+	return inst.QuoteAsset, order.Price.Mul(order.Amount)
+}
+
+// receivedAsset 订单成交收到的资产：买单为基础币，卖单为计价币
+func receivedAsset(order *types.Order, inst *types.Instrument) string {
+	if order.OrderSide == types.OrderSideBuy {
+		return inst.BaseAsset
+	}
+	return inst.QuoteAsset
+}
+
+// normalizeTrigger 校验条件单的触发参数
+func (l *OrderLogic) normalizeTrigger(order *types.Order) error {
+	if !order.OrderType.IsTrigger() {
+		order.TriggerPrice = decimal.Zero
+		order.TrailingOffset = decimal.Zero
+		return nil
+	}
+
+	if order.OrderType == types.OrderTypeTrailingStop {
+		if !order.TrailingOffset.IsPositive() {
+			return fmt.Errorf("trailing_offset must be positive")
+		}
+		order.TriggerPrice = decimal.Zero
+		return nil
+	}
+
+	if !order.TriggerPrice.IsPositive() {
+		return fmt.Errorf("trigger_price must be positive")
+	}
+	order.TrailingOffset = decimal.Zero
+
+	// 已满足触发条件的止损单会立即成交，应直接下普通单
+	if last, ok := l.svcCtx.Matcher.LastPrice(order.Symbol); ok {
+		if order.OrderSide == types.OrderSideBuy && last.GreaterThanOrEqual(order.TriggerPrice) ||
+			order.OrderSide == types.OrderSideSell && last.LessThanOrEqual(order.TriggerPrice) {
+			return fmt.Errorf("trigger_price %v would trigger immediately at last price %v", order.TriggerPrice, last)
+		}
+	}
+	return nil
+}
+
+// triggerOrder 条件单被触发，转为普通限价/市价单进入撮合
+func (l *OrderLogic) triggerOrder(orderID string) error {
+	order, err := l.loadOrder(orderID)
+	if err != nil {
 		return err
 	}
+	if order.Status != types.OrderStatusUntriggered {
+		return nil
+	}
 
-	// 3. 发送Kafka消息
-	return l.sendOrderMessage("create", order)
+	err = l.transact(func(tx repository.Store) error {
+		if err := order.Transition(types.OrderStatusTriggered); err != nil {
+			return err
+		}
+		now := time.Now()
+		order.OrderType = order.TriggerType.ExecType()
+		order.TriggeredAt = &now
+		order.UpdatedAt = now
+
+		if err := tx.Orders().Save(order); err != nil {
+			return err
+		}
+		return l.publish(tx, order.OrderID, &event.OrderTriggered{Order: event.NewOrder(order)})
+	}, order)
+	if err != nil {
+		return err
+	}
+	l.updateOrderCache(order)
+	return l.matchOrder(order)
+}
+
+// normalizeTimeInForce 校验订单有效方式并补全默认值
+func (l *OrderLogic) normalizeTimeInForce(order *types.Order) error {
+	if order.OrderType.ExecType() == types.OrderTypeMarket {
+		// 市价单不会挂单，只支持 IOC 与 FOK
+		if order.TimeInForce == "" {
+			order.TimeInForce = types.TimeInForceIOC
+		}
+		if order.TimeInForce != types.TimeInForceIOC && order.TimeInForce != types.TimeInForceFOK {
+			return fmt.Errorf("market order does not support time_in_force %s", order.TimeInForce)
+		}
+		order.ExpireAt = nil
+		return nil
+	}
+
+	switch order.TimeInForce {
+	case "":
+		order.TimeInForce = types.TimeInForceGTC
+	case types.TimeInForceGTC, types.TimeInForceIOC, types.TimeInForceFOK, types.TimeInForcePostOnly:
+	case types.TimeInForceGTD:
+		if order.ExpireAt == nil || !order.ExpireAt.After(time.Now()) {
+			return fmt.Errorf("expire_at must be in the future for GTD order")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown time_in_force %s", order.TimeInForce)
+	}
+	order.ExpireAt = nil
+	return nil
+}
+
+// matchOrder 将订单提交给撮合引擎，并在同一事务内落库全部成交，调用方需持有交易对写锁。
+// 成交落库失败时撤销本次撮合并取消订单，订单簿与数据库保持一致
+func (l *OrderLogic) matchOrder(order *types.Order) error {
+	var result *matching.Result
+	for attempt := 0; ; attempt++ {
+		var err error
+		result, err = l.svcCtx.Matcher.Submit(order)
+		if err != nil {
+			return err
+		}
+
+		// 撮合时顺带清理的到期挂单
+		for _, orderID := range result.Expired {
+			if err := l.expireOrder(orderID); err != nil {
+				return err
+			}
+		}
+
+		if result.Rejected {
+			return l.closeOrder(order, types.OrderStatusRejected, result.CancelReason)
+		}
+		if !result.Repriced {
+			break
+		}
+
+		// POST_ONLY 会吃单：先落库新价格，再按新价格重新提交挂单；对手价持续变化时按 POST_ONLY 规则拒绝
+		if attempt == maxRepriceAttempts {
+			return l.closeOrder(order, types.OrderStatusRejected, types.CancelReasonPostOnly)
+		}
+		if err := l.repriceOrder(order, result.Price); err != nil {
+			return err
+		}
+	}
+
+	if err := l.settleMatches(order, result.Matches); err != nil {
+		return l.abortMatch(order, err)
+	}
+
+	// 未挂入订单簿的剩余部分直接取消（如市价单滑点保护、IOC）
+	if result.CancelReason != "" && !order.IsFullyFilled() {
+		if err := l.closeOrder(order, types.OrderStatusCancelled, result.CancelReason); err != nil {
+			return err
+		}
+	}
+
+	// 市价买单剩余金额不足一个最小数量单位：有成交时视为全部成交，一笔未成交时按流动性不足取消并释放冻结金额
+	if result.CancelReason == "" && order.IsMarketBuy() && order.FilledAmount.IsZero() {
+		if err := l.closeOrder(order, types.OrderStatusCancelled, types.CancelReasonNoLiquidity); err != nil {
+			return err
+		}
+	} else if result.CancelReason == "" && order.IsMarketBuy() && order.Status != types.OrderStatusFilled {
+		err := l.transact(func(tx repository.Store) error {
+			if err := order.Transition(types.OrderStatusFilled); err != nil {
+				return err
+			}
+			if err := l.releaseFrozen(tx, order); err != nil {
+				return err
+			}
+			if err := tx.Orders().Save(order); err != nil {
+				return err
+			}
+			return l.publish(tx, order.OrderID, &event.OrderFilled{Order: event.NewOrder(order)})
+		}, order)
+		if err != nil {
+			return err
+		}
+		l.updateOrderCache(order)
+	}
+
+	// 成交价触发的条件单依次进入撮合
+	for _, orderID := range result.Triggered {
+		if err := l.triggerOrder(orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repriceOrder POST_ONLY 订单改价落库，买单价格降低后释放多冻结的计价币
+func (l *OrderLogic) repriceOrder(order *types.Order, price decimal.Decimal) error {
+	err := l.transact(func(tx repository.Store) error {
+		order.Price = price
+		order.UpdatedAt = time.Now()
+
+		if order.OrderSide == types.OrderSideBuy {
+			inst, err := l.svcCtx.Instruments.Get(order.Symbol)
+			if err != nil {
+				return err
+			}
+			excess := order.Frozen.Sub(price.Mul(order.Amount.Sub(order.FilledAmount)))
+			if excess.IsPositive() {
+				if err := tx.Accounts().Post(account.UnfreezeJournal(order.UserID, inst.QuoteAsset, excess, order.OrderID)); err != nil {
+					return err
+				}
+				order.Frozen = order.Frozen.Sub(excess)
+			}
+		}
+
+		if err := tx.Orders().Save(order); err != nil {
+			return err
+		}
+		return l.publish(tx, order.OrderID, &event.OrderRepriced{Order: event.NewOrder(order)})
+	}, order)
+	if err != nil {
+		return err
+	}
+	l.updateOrderCache(order)
+	return nil
+}
+
+// settleMatches 在同一事务内落库一次撮合的全部成交：taker 与 maker 各生成一条成交记录
+func (l *OrderLogic) settleMatches(taker *types.Order, matches []matching.Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	inst, err := l.svcCtx.Instruments.Get(taker.Symbol)
+	if err != nil {
+		return err
+	}
+	makers := make([]*types.Order, len(matches))
+	matchIDs := make([]string, len(matches))
+	userIDs := []int64{taker.UserID}
+	for i, m := range matches {
+		maker, err := l.loadOrder(m.MakerOrderID)
+		if err != nil {
+			return err
+		}
+		makers[i] = maker
+		matchIDs[i] = utils.NewUuid()
+		userIDs = append(userIDs, maker.UserID)
+	}
+
+	// 双方订单、成交记录与资金结算在同一事务内完成，先按固定顺序锁定全部涉及的账户
+	err = l.transact(func(tx repository.Store) error {
+		if err := tx.Accounts().Lock(l.settlementAccounts(inst, userIDs...)...); err != nil {
+			return err
+		}
+		for i, m := range matches {
+			if err := l.fillOrder(tx, taker, m.Price, m.Amount, types.LiquidityTaker, matchIDs[i], makers[i].OrderID); err != nil {
+				return err
+			}
+			if err := l.fillOrder(tx, makers[i], m.Price, m.Amount, types.LiquidityMaker, matchIDs[i], taker.OrderID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, append([]*types.Order{taker}, makers...)...)
+	if err != nil {
+		return err
+	}
+
+	l.updateOrderCache(taker)
+	for _, maker := range makers {
+		l.updateOrderCache(maker)
+	}
+	return nil
+}
+
+// abortMatch 成交未能落库：撤销撮合引擎中的本次撮合，再按数据库中的状态取消 taker 并释放其冻结资金
+func (l *OrderLogic) abortMatch(order *types.Order, cause error) error {
+	if err := l.svcCtx.Matcher.Revert(order.Symbol); err != nil {
+		return errors.Join(cause, err)
+	}
+	// 失败的事务可能已修改内存中的订单
+	fresh, err := l.loadOrder(order.OrderID)
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	*order = *fresh
+	if err := l.closeOrder(order, types.OrderStatusCancelled, types.CancelReasonSettlement); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
 }
 
 // CancelOrder 取消订单（下架）
 func (l *OrderLogic) CancelOrder(orderID, reason string) error {
-	// 1. 查询订单
-	order, err := l.GetOrder(orderID)
+	// 1. 查询订单，取得交易对写锁后重新读取：撤单与撮合串行，撤单期间订单不会被成交
+	order, err := l.loadOrder(orderID)
 	if err != nil {
 		return err
 	}
+	defer l.svcCtx.Matcher.Lock(order.Symbol)()
+	if order, err = l.loadOrder(orderID); err != nil {
+		return err
+	}
 
 	// 检查订单状态是否可以取消
-	if order.Status != types.OrderStatusPending && order.Status != types.OrderStatusPartFilled {
-		return fmt.Errorf("订单状态 %s 不可取消", order.Status)
+	if !order.Status.CanTransition(types.OrderStatusCancelled) {
+		return &types.TransitionError{OrderID: order.OrderID, From: order.Status, To: types.OrderStatusCancelled}
 	}
 
-	// 2. 更新订单状态
-	order.Status = types.OrderStatusCancelled
-	order.CancelReason = reason
-	order.UpdatedAt = time.Now()
+	// 从触发队列或订单簿撤下
+	if order.Status == types.OrderStatusUntriggered {
+		_, err = l.svcCtx.Matcher.CancelTrigger(order.Symbol, order.OrderID)
+	} else {
+		_, err = l.svcCtx.Matcher.Cancel(order.Symbol, order.OrderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return l.closeOrder(order, types.OrderStatusCancelled, reason)
+}
+
+// expireOrder GTD 订单到期取消
+func (l *OrderLogic) expireOrder(orderID string) error {
+	order, err := l.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if !order.Status.IsOpen() {
+		return nil
+	}
+	return l.closeOrder(order, types.OrderStatusCancelled, types.CancelReasonExpired)
+}
+
+// closeOrder 将订单置为已取消或已拒绝并落库
+func (l *OrderLogic) closeOrder(order *types.Order, status types.OrderStatus, reason string) error {
+	err := l.transact(func(tx repository.Store) error {
+		// 2. 按状态机更新订单状态
+		if err := order.Transition(status); err != nil {
+			return err
+		}
+		order.CancelReason = reason
+		order.UpdatedAt = time.Now()
 
-	// 3. 更新数据库 - 使用结构体更新，让GORM处理字段映射
-	if err := l.svcCtx.MySQL.Model(order).
-		Where("order_id = ?", orderID).
-		Updates(order).Error; err != nil {
+		// 3. 释放剩余冻结资金、更新数据库并写入取消消息
+		if err := l.releaseFrozen(tx, order); err != nil {
+			return err
+		}
+		if err := tx.Orders().Save(order); err != nil {
+			return err
+		}
+		if status == types.OrderStatusRejected {
+			return l.publish(tx, order.OrderID, &event.OrderRejected{Order: event.NewOrder(order), Reason: reason})
+		}
+		return l.publish(tx, order.OrderID, &event.OrderCancelled{Order: event.NewOrder(order), Reason: reason})
+	}, order)
+	if err != nil {
 		return err
 	}
 
 	// 4. 更新缓存
-	if err := l.updateOrderCache(order); err != nil {
+	l.updateOrderCache(order)
+	return nil
+}
+
+// FillOrder 订单成交（部分或完全），用于引擎外的人工成交，对手方为平台账户，订单按 maker 计费。
+// 成交价与数量由调用方指定，只通过管理接口开放
+func (l *OrderLogic) FillOrder(orderID string, fillPrice, fillAmount decimal.Decimal) error {
+	// 1. 查询订单，取得交易对写锁后重新读取，与撮合串行
+	order, err := l.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	defer l.svcCtx.Matcher.Lock(order.Symbol)()
+	if order, err = l.loadOrder(orderID); err != nil {
+		return err
+	}
+
+	inst, err := l.svcCtx.Instruments.Get(order.Symbol)
+	if err != nil {
+		return err
+	}
+	err = l.transact(func(tx repository.Store) error {
+		if err := tx.Accounts().Lock(l.settlementAccounts(inst, order.UserID)...); err != nil {
+			return err
+		}
+		return l.fillOrder(tx, order, fillPrice, fillAmount, types.LiquidityMaker, "", "")
+	}, order)
+	if err != nil {
 		return err
 	}
 
-	// 5. 发送取消消息
-	return l.sendOrderMessage("cancel", order)
+	// 成交落库后同步订单簿上的剩余数量
+	if err := l.svcCtx.Matcher.Reduce(order.Symbol, order.OrderID, fillAmount); err != nil {
+		return err
+	}
+	l.updateOrderCache(order)
+	return nil
 }
 
-// FillOrder 订单成交（部分或完全）
-func (l *OrderLogic) FillOrder(orderID string, fillPrice, fillAmount, feeRate float64) error {
-	// 1. 查询订单
-	order, err := l.GetOrder(orderID)
+// fillOrder 在事务内更新订单成交数量与状态、写入成交记录并结算资金。
+// counterOrderID 为空表示人工成交，对手方为平台清算账户。
+func (l *OrderLogic) fillOrder(tx repository.Store, order *types.Order, fillPrice, fillAmount decimal.Decimal, role types.LiquidityRole, matchID, counterOrderID string) error {
+	orderID := order.OrderID
+	inst, err := l.svcCtx.Instruments.Get(order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按状态机累加成交：已完结订单的迟到成交与超量成交均被拒绝
+	notional := fillAmount.Mul(fillPrice)
+	if err := order.ApplyFill(fillAmount, notional); err != nil {
+		return err
+	}
+
+	// 计算手续费，费率由服务端按用户等级、交易对与流动性角色决定
+	// 手续费从收到的资产中按 types.RoundFee 向上取整扣除：买单扣基础币，卖单扣计价币
+	feeRate, err := l.svcCtx.Fees.Rate(tx.Trades(), order.UserID, order.Symbol, role)
 	if err != nil {
 		return err
 	}
+	received := notional
+	if order.OrderSide == types.OrderSideBuy {
+		received = fillAmount
+	}
+
+	trade := &types.Trade{
+		TradeID:        utils.NewUuid(),
+		MatchID:        matchID,
+		OrderID:        orderID,
+		CounterOrderID: counterOrderID,
+		UserID:         order.UserID,
+		Symbol:         order.Symbol,
+		Side:           order.OrderSide,
+		Price:          fillPrice,
+		Amount:         fillAmount,
+		Role:           role,
+		FeeRate:        feeRate,
+		Fee:            types.RoundFee(received.Mul(feeRate)),
+		FeeAsset:       receivedAsset(order, inst),
+	}
+
+	// 用户开启平台币抵扣时改为以平台币支付
+	if err := l.applyTokenFee(tx, order, inst, trade); err != nil {
+		return err
+	}
+	if trade.FeeAsset == order.FeeAsset {
+		order.Fee = order.Fee.Add(trade.Fee)
+	} else {
+		order.TokenFee = order.TokenFee.Add(trade.Fee)
+	}
 
-	// 计算手续费
-	fee := fillAmount * fillPrice * feeRate
-	order.FilledAmount += fillAmount
-	order.Fee += fee
+	// 资金结算：买单付出计价币与手续费、收入基础币；卖单付出基础币、收入扣除手续费后的计价币
+	if err := l.settleFill(tx, order, inst, trade, notional); err != nil {
+		return err
+	}
 
-	// 更新订单状态
-	if order.FilledAmount >= order.Amount {
-		order.Status = types.OrderStatusFilled
-	} else if order.FilledAmount > 0 {
-		order.Status = types.OrderStatusPartFilled
+	// 全部成交后释放多冻结的资金（如以更优价格成交的买单）
+	if order.Status == types.OrderStatusFilled {
+		if err := l.releaseFrozen(tx, order); err != nil {
+			return err
+		}
 	}
 	order.UpdatedAt = time.Now()
 
-	// 2. 更新数据库 - 使用结构体更新，让GORM处理字段映射
-	if err := l.svcCtx.MySQL.Model(order).
-		Where("order_id = ?", orderID).
-		Updates(order).Error; err != nil {
+	// 2. 更新数据库
+	if err := tx.Orders().Save(order); err != nil {
 		return err
 	}
 
 	// 3. 创建成交记录
-	trade := &types.Trade{
-		TradeID:  fmt.Sprintf("trade_%d", time.Now().UnixNano()),
-		OrderID:  orderID,
-		Symbol:   order.Symbol,
-		Price:    fillPrice,
-		Amount:   fillAmount,
-		Fee:      fee,
-		FeeAsset: "USDT", // 假设手续费币种
+	if err := tx.Trades().Create(trade); err != nil {
+		return err
+	}
+
+	// 4. 写入成交事件
+	return l.publish(tx, order.OrderID,
+		&event.TradeExecuted{Trade: event.NewTrade(trade)},
+		&event.OrderFilled{Order: event.NewOrder(order), TradeID: trade.TradeID},
+	)
+}
+
+// settleFill 记账一笔成交：交割分录以平台清算账户为对方科目，撮合成交的双方在清算账户上对冲为零；
+// 手续费单独记一笔分录，计入平台手续费账户。
+func (l *OrderLogic) settleFill(tx repository.Store, order *types.Order, inst *types.Instrument, trade *types.Trade, notional decimal.Decimal) error {
+	fill := account.Journal{Type: types.EntryTypeFill, OrderID: order.OrderID, TradeID: trade.TradeID}
+	fee := account.Journal{Type: types.EntryTypeFee, OrderID: order.OrderID, TradeID: trade.TradeID}
+
+	if order.OrderSide == types.OrderSideBuy {
+		fill.Postings = append(l.spend(order, inst.QuoteAsset, notional),
+			account.Available(account.HouseUserID, inst.QuoteAsset, notional),
+			account.Available(account.HouseUserID, inst.BaseAsset, trade.Amount.Neg()),
+			account.Available(order.UserID, inst.BaseAsset, trade.Amount),
+		)
+	} else {
+		fill.Postings = append(l.spend(order, inst.BaseAsset, trade.Amount),
+			account.Available(account.HouseUserID, inst.BaseAsset, trade.Amount),
+			account.Available(account.HouseUserID, inst.QuoteAsset, notional.Neg()),
+			account.Available(order.UserID, inst.QuoteAsset, notional),
+		)
+	}
+	fee.Postings = []account.Posting{
+		account.Available(order.UserID, trade.FeeAsset, trade.Fee.Neg()),
+		account.Available(account.FeeUserID, trade.FeeAsset, trade.Fee),
 	}
 
-	if err := l.svcCtx.MySQL.Create(trade).Error; err != nil {
+	if err := tx.Accounts().Post(fill); err != nil {
 		return err
 	}
+	return tx.Accounts().Post(fee)
+}
 
-	// 4. 更新缓存
-	if err := l.updateOrderCache(order); err != nil {
+// settlementAccounts 成交结算可能涉及的全部账户：各方与平台清算、手续费账户的基础币、计价币与平台币
+func (l *OrderLogic) settlementAccounts(inst *types.Instrument, userIDs ...int64) []account.Key {
+	assets := []string{inst.BaseAsset, inst.QuoteAsset}
+	if token := l.svcCtx.Fees.Token(); token != "" {
+		assets = append(assets, token)
+	}
+	var keys []account.Key
+	for _, userID := range append(userIDs, account.HouseUserID, account.FeeUserID) {
+		for _, asset := range assets {
+			keys = append(keys, account.Key{UserID: userID, Asset: asset})
+		}
+	}
+	return keys
+}
+
+// applyTokenFee 用户开启平台币抵扣时，将手续费按平台币参考价（平台币/计价币的最新成交价）折算并打折。
+// 未配置平台币、交易对本身涉及平台币、暂无参考价或平台币余额不足时，仍从收到的资产中扣除。
+func (l *OrderLogic) applyTokenFee(tx repository.Store, order *types.Order, inst *types.Instrument, trade *types.Trade) error {
+	token := l.svcCtx.Fees.Token()
+	if token == "" || !trade.Fee.IsPositive() || inst.BaseAsset == token || inst.QuoteAsset == token {
+		return nil
+	}
+	enabled, err := tx.Accounts().PaysFeeInToken(order.UserID)
+	if err != nil || !enabled {
+		return err
+	}
+	tokenPrice, ok := l.svcCtx.Matcher.LastPrice(token + "/" + inst.QuoteAsset)
+	if !ok {
+		return nil
+	}
+
+	// 先折算为计价币价值，买单的基础币手续费按成交价换算
+	value := trade.Fee
+	if trade.FeeAsset == inst.BaseAsset {
+		value = trade.Fee.Mul(trade.Price)
+	}
+	amount := l.svcCtx.Fees.TokenAmount(value, tokenPrice)
+	balance, err := tx.Accounts().Get(order.UserID, token)
+	if err != nil {
+		return err
+	}
+	if balance.Available.LessThan(amount) {
+		return nil
+	}
+	trade.Fee = amount
+	trade.FeeAsset = token
+	return nil
+}
+
+// spend 从订单冻结资金中扣款，冻结不足的部分从可用余额扣除
+func (l *OrderLogic) spend(order *types.Order, asset string, amount decimal.Decimal) []account.Posting {
+	fromFrozen := decimal.Min(amount, order.Frozen)
+	order.Frozen = order.Frozen.Sub(fromFrozen)
+	return []account.Posting{
+		account.Frozen(order.UserID, asset, fromFrozen.Neg()),
+		account.Available(order.UserID, asset, amount.Sub(fromFrozen).Neg()),
+	}
+}
+
+// releaseFrozen 订单完结时将剩余冻结资金退回可用余额
+func (l *OrderLogic) releaseFrozen(tx repository.Store, order *types.Order) error {
+	if !order.Frozen.IsPositive() {
+		return nil
+	}
+	inst, err := l.svcCtx.Instruments.Get(order.Symbol)
+	if err != nil {
 		return err
 	}
+	asset := inst.QuoteAsset
+	if order.OrderSide == types.OrderSideSell {
+		asset = inst.BaseAsset
+	}
+	if err := tx.Accounts().Post(account.UnfreezeJournal(order.UserID, asset, order.Frozen, order.OrderID)); err != nil {
+		return err
+	}
+	order.Frozen = decimal.Zero
+	return nil
+}
+
+// transact 在事务内执行订单变更。乐观锁冲突、死锁或等待行锁超时时从数据库重新加载 orders 后重试，
+// 因此 fn 必须在事务内基于订单当前状态完成全部修改。
+func (l *OrderLogic) transact(fn func(tx repository.Store) error, orders ...*types.Order) error {
+	for attempt := 0; ; attempt++ {
+		err := l.svcCtx.Store.Transaction(fn)
+		retryable := errors.Is(err, types.ErrVersionConflict) || errors.Is(err, repository.ErrLockConflict)
+		if !retryable || attempt == maxTxRetries {
+			return err
+		}
+		for _, order := range orders {
+			fresh, err := l.loadOrder(order.OrderID)
+			if err != nil {
+				return err
+			}
+			*order = *fresh
+		}
+	}
+}
 
-	// 5. 发送成交消息
-	return l.sendOrderMessage("fill", order)
+// GetOrderByClientID 按用户与客户端订单号查询订单
+func (l *OrderLogic) GetOrderByClientID(userID int64, clientOrderID string) (*types.Order, error) {
+	return l.svcCtx.Store.Orders().GetByClientID(userID, clientOrderID)
 }
 
-// GetOrder 获取订单（先查Redis缓存，没有再查MySQL）
+// GetOrder 获取订单（先查Redis缓存，没有再查数据库）
 func (l *OrderLogic) GetOrder(orderID string) (*types.Order, error) {
 	// 1. 先查Redis缓存
-	orderKey := fmt.Sprintf("order:%s", orderID)
-	cachedOrder, err := l.svcCtx.Redis.Get(l.ctx, orderKey).Bytes()
-	if err == nil {
-		var order types.Order
-		if json.Unmarshal(cachedOrder, &order) == nil {
-			return &order, nil
+	if l.svcCtx.Redis != nil {
+		orderKey := fmt.Sprintf("order:%s", orderID)
+		cachedOrder, err := l.svcCtx.Redis.Get(l.ctx, orderKey).Bytes()
+		if err == nil {
+			var order types.Order
+			if json.Unmarshal(cachedOrder, &order) == nil {
+				return &order, nil
+			}
 		}
 	}
 
-	// 2. 缓存未命中，查MySQL
-	var order types.Order
-	if err := l.svcCtx.MySQL.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+	// 2. 缓存未命中，查数据库
+	order, err := l.loadOrder(orderID)
+	if err != nil {
 		return nil, err
 	}
 
 	// 3. 回写缓存
-	if err := l.updateOrderCache(&order); err != nil {
-		return &order, err // 返回订单，即使缓存更新失败
-	}
+	l.updateOrderCache(order)
+	return order, nil
+}
 
-	return &order, nil
+// loadOrder 从数据库查询订单。变更订单前都从数据库读取：缓存中的订单不含创建时间等字段，
+// 据此写入的事件无法完整还原订单
+func (l *OrderLogic) loadOrder(orderID string) (*types.Order, error) {
+	return l.svcCtx.Store.Orders().Get(orderID)
 }
 
-// updateOrderCache 更新订单缓存
-func (l *OrderLogic) updateOrderCache(order *types.Order) error {
+// updateOrderCache 更新订单缓存，未配置Redis时（如使用内存仓储的单元测试）不缓存。
+// 缓存只是读优化，在订单已落库之后写入：写入失败只记录日志并删除旧缓存，不中断后续的撮合等流程
+func (l *OrderLogic) updateOrderCache(order *types.Order) {
+	if l.svcCtx.Redis == nil {
+		return
+	}
 	orderKey := fmt.Sprintf("order:%s", order.OrderID)
 	orderJSON, _ := json.Marshal(order)
-	return l.svcCtx.Redis.Set(l.ctx, orderKey, orderJSON, 24*time.Hour).Err()
+	if err := l.svcCtx.Redis.Set(l.ctx, orderKey, orderJSON, 24*time.Hour).Err(); err != nil {
+		l.Errorf("更新订单缓存失败: %s, %v", order.OrderID, err)
+		// 避免查询读到过期的订单
+		l.svcCtx.Redis.Del(l.ctx, orderKey)
+	}
 }
 
-// sendOrderMessage 发送Kafka消息
-func (l *OrderLogic) sendOrderMessage(action string, order *types.Order) error {
-	message := types.OrderMessage{
-		Action:  action,
-		OrderID: order.OrderID,
-		Data:    *order,
+// publish 在订单变更的事务内将订单事件追加到事件存储并写入发件箱，由发件箱转发任务发布到消息总线。
+// 以订单号为消息 Key 保证同一订单的事件有序
+func (l *OrderLogic) publish(tx repository.Store, orderID string, payloads ...event.Payload) error {
+	for _, payload := range payloads {
+		e := event.New(orderID, payload)
+		if err := tx.Events().Append(e); err != nil {
+			return err
+		}
+		data, headers, err := event.Encode(l.svcCtx.Events, e)
+		if err != nil {
+			return err
+		}
+		if err := tx.Outbox().Enqueue(l.svcCtx.Config.Kafka.Topic, orderID, data, headers); err != nil {
+			return err
+		}
 	}
-	msgJSON, _ := json.Marshal(message)
+	return nil
+}
 
-	return l.svcCtx.KafkaProd.WriteMessages(l.ctx, kafka.Message{
-		Key:   []byte(order.OrderID),
-		Value: msgJSON,
-	})
+// StartOutboxRelay 启动发件箱转发任务
+func (l *OrderLogic) StartOutboxRelay() {
+	go l.svcCtx.Outbox.Run(l.ctx)
 }
 
-// Kafka消费者处理消息
-func (l *OrderLogic) StartKafkaConsumer() {
+// StartSnapshotWorker 定期生成订单状态快照
+func (l *OrderLogic) StartSnapshotWorker() {
+	go l.svcCtx.Snapshots.Run(l.ctx)
+}
+
+// StartCheckpointWorker 定期将撮合引擎的订单簿写入本地快照，并删除快照已覆盖的预写日志
+func (l *OrderLogic) StartCheckpointWorker() {
 	go func() {
-		for {
-			msg, err := l.svcCtx.KafkaCons.ReadMessage(l.ctx)
-			if err != nil {
-				fmt.Printf("Kafka消费错误: %v\n", err)
-				continue
-			}
+		ticker := time.NewTicker(time.Duration(l.svcCtx.Config.Matching.Journal.SnapshotIntervalSec) * time.Second)
+		defer ticker.Stop()
 
-			var orderMsg types.OrderMessage
-			if err := json.Unmarshal(msg.Value, &orderMsg); err != nil {
-				fmt.Printf("消息解析错误: %v\n", err)
-				continue
+		for range ticker.C {
+			if _, err := l.svcCtx.Matcher.Checkpoint(); err != nil {
+				l.Errorf("生成订单簿快照失败: %v", err)
 			}
+		}
+	}()
+}
+
+// StartKafkaConsumer 订阅订单事件，构建读模型（用户未完结订单、交易对成交统计、用户订单数汇总）。
+// 处理失败的事件经重试主题进入死信主题，并落库供管理员查询与重放。
+func (l *OrderLogic) StartKafkaConsumer() {
+	if err := l.svcCtx.Projections.Start(l.ctx); err != nil {
+		l.Errorf("订阅订单事件失败: %v", err)
+	}
+	if err := l.svcCtx.DeadLetters.Start(l.ctx); err != nil {
+		l.Errorf("订阅死信主题失败: %v", err)
+	}
+}
 
-			switch orderMsg.Action {
-			case "create":
-				fmt.Printf("订单创建: %s, 状态: %s\n", orderMsg.OrderID, orderMsg.Data.Status)
-			case "cancel":
-				fmt.Printf("订单取消: %s, 原因: %s\n", orderMsg.OrderID, orderMsg.Data.CancelReason)
-			case "fill":
-				fmt.Printf("订单成交: %s, 成交数量: %.4f, 手续费: %.4f\n",
-					orderMsg.OrderID, orderMsg.Data.FilledAmount, orderMsg.Data.Fee)
+// StartMarketGateway 启动公开行情推送：订阅订单簿变更与订单事件中的成交
+func (l *OrderLogic) StartMarketGateway() {
+	if err := l.svcCtx.Market.Start(l.ctx); err != nil {
+		l.Errorf("启动行情推送失败: %v", err)
+	}
+}
+
+// StartKlineAggregator 启动K线聚合：取得写入锁后恢复当前周期并订阅订单事件中的成交
+func (l *OrderLogic) StartKlineAggregator() {
+	go l.svcCtx.Klines.Run(l.ctx)
+}
+
+// StartUserStream 启动用户数据流推送：订阅订单事件并检查 listen key 是否过期
+func (l *OrderLogic) StartUserStream() {
+	if err := l.svcCtx.UserStream.Start(l.ctx); err != nil {
+		l.Errorf("启动用户数据流失败: %v", err)
+	}
+}
+
+// StartExpiryWorker 定时清理到期的 GTD 挂单
+func (l *OrderLogic) StartExpiryWorker() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			// 出错时仍取消已移出订单簿的挂单
+			expired, err := l.svcCtx.Matcher.Expire(now)
+			if err != nil {
+				l.Errorf("清理到期挂单失败: %v", err)
+			}
+			for _, orderID := range expired {
+				if err := l.expireOrder(orderID); err != nil {
+					l.Errorf("订单到期取消失败: %s, %v", orderID, err)
+				}
 			}
 		}
 	}()
@@ -234,23 +954,10 @@ func (l *OrderLogic) StartKafkaConsumer() {
 
 // 获取用户的所有订单
 func (l *OrderLogic) GetUserOrders(userID int64, status types.OrderStatus) ([]types.Order, error) {
-	var orders []types.Order
-	query := l.svcCtx.MySQL.Where("user_id = ?", userID)
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
-
-	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
-		return nil, err
-	}
-	return orders, nil
+	return l.svcCtx.Store.Orders().ListByUser(userID, status)
 }
 
 // 获取订单的成交记录
 func (l *OrderLogic) GetOrderTrades(orderID string) ([]types.Trade, error) {
-	var trades []types.Trade
-	if err := l.svcCtx.MySQL.Where("order_id = ?", orderID).Order("created_at ASC").Find(&trades).Error; err != nil {
-		return nil, err
-	}
-	return trades, nil
+	return l.svcCtx.Store.Trades().ListByOrder(orderID)
 }
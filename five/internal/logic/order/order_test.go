@@ -0,0 +1,450 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"five/internal/account"
+	"five/internal/config"
+	"five/internal/event"
+	"five/internal/fee"
+	"five/internal/instrument"
+	"five/internal/matching"
+	"five/internal/repository"
+	"five/internal/svc"
+	"five/internal/types"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	buyer  int64 = 1001
+	seller int64 = 1002
+	symbol       = "BTC/USDT"
+)
+
+// newTestLogic 基于内存仓储、内存交易对与进程内撮合引擎构建订单逻辑，不依赖数据库与Redis
+func newTestLogic(t *testing.T) (*OrderLogic, *repository.MemoryStore) {
+	t.Helper()
+
+	store := repository.NewMemoryStore()
+	instruments := instrument.NewMemoryRegistry(instrument.Defaults()...)
+	matcher := matching.NewEngine(matching.Options{PostOnlyReprice: true})
+	for _, inst := range instruments.List("") {
+		if err := matcher.Configure(inst.Symbol, inst.PriceTick, inst.QtyStep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 配置 VIP 等级，成交结算时会查询用户成交额
+	fees, err := fee.NewSchedule(store.Trades(), fee.Options{
+		Base: fee.Rates{Maker: dec("0.001"), Taker: dec("0.002")},
+		Tiers: []fee.Tier{
+			{MinVolume: dec("1000000"), Rates: fee.Rates{Maker: dec("0.0005"), Taker: dec("0.001")}},
+		},
+		Window:      30 * 24 * time.Hour,
+		VolumeAsset: "USDT",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svcCtx := &svc.ServiceContext{
+		Config:      config.Config{},
+		Store:       store,
+		Events:      event.JSON,
+		Matcher:     matcher,
+		Instruments: instruments,
+		Fees:        fees,
+	}
+	svcCtx.Config.Kafka.Topic = "order-events"
+	return NewOrderLogic(context.Background(), svcCtx), store
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func deposit(t *testing.T, store *repository.MemoryStore, userID int64, asset, amount string) {
+	t.Helper()
+	if err := store.Accounts().Post(account.DepositJournal(userID, asset, dec(amount))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func limitOrder(userID int64, side types.OrderSide, price, amount string) *types.Order {
+	return &types.Order{
+		UserID:    userID,
+		Symbol:    symbol,
+		OrderSide: side,
+		OrderType: types.OrderTypeLimit,
+		Price:     dec(price),
+		Amount:    dec(amount),
+	}
+}
+
+func assertBalance(t *testing.T, store *repository.MemoryStore, userID int64, asset, available, frozen string) {
+	t.Helper()
+	acct, err := store.Accounts().Get(userID, asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acct.Available.Equal(dec(available)) || !acct.Frozen.Equal(dec(frozen)) {
+		t.Fatalf("user %d %s: available %s frozen %s, want %s %s",
+			userID, asset, acct.Available, acct.Frozen, available, frozen)
+	}
+}
+
+func assertStatus(t *testing.T, l *OrderLogic, orderID string, want types.OrderStatus) *types.Order {
+	t.Helper()
+	order, err := l.GetOrder(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != want {
+		t.Fatalf("order %s status %s, want %s", orderID, order.Status, want)
+	}
+	return order
+}
+
+// eventTypes 订单的事件类型，按写入顺序
+func eventTypes(store *repository.MemoryStore, orderID string) []string {
+	var list []string
+	for _, record := range store.EventRecords() {
+		if record.OrderID == orderID {
+			list = append(list, record.Type)
+		}
+	}
+	return list
+}
+
+func assertEvents(t *testing.T, store *repository.MemoryStore, orderID string, want ...event.Type) {
+	t.Helper()
+	got := eventTypes(store, orderID)
+	if len(got) != len(want) {
+		t.Fatalf("order %s events %v, want %v", orderID, got, want)
+	}
+	for i := range want {
+		if got[i] != string(want[i]) {
+			t.Fatalf("order %s events %v, want %v", orderID, got, want)
+		}
+	}
+}
+
+func TestCreateOrderFreezesFunds(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+
+	order, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, l, order.OrderID, types.OrderStatusPending)
+	assertBalance(t, store, buyer, "USDT", "500", "500")
+	assertEvents(t, store, order.OrderID, event.TypeOrderAccepted)
+	if len(store.OutboxMessages()) != 1 {
+		t.Fatalf("outbox has %d messages, want 1", len(store.OutboxMessages()))
+	}
+
+	// 相同客户端订单号重复提交返回原订单，不重复冻结
+	req := limitOrder(buyer, types.OrderSideBuy, "5000", "0.1")
+	req.ClientOrderID = order.ClientOrderID
+	again, err := l.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.OrderID != order.OrderID {
+		t.Fatalf("duplicate client order id created order %s", again.OrderID)
+	}
+	assertBalance(t, store, buyer, "USDT", "500", "500")
+}
+
+func TestCreateOrderInsufficientBalance(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "100")
+
+	if _, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1")); err == nil {
+		t.Fatal("expected insufficient balance error")
+	}
+	assertBalance(t, store, buyer, "USDT", "100", "0")
+	if len(store.EventRecords()) != 0 {
+		t.Fatalf("failed order wrote %d events", len(store.EventRecords()))
+	}
+}
+
+func TestMatchOrders(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	maker, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 买单以更高价格吃单，按挂单价成交，多冻结的计价币退回
+	taker, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5100", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStatus(t, l, maker.OrderID, types.OrderStatusFilled)
+	filled := assertStatus(t, l, taker.OrderID, types.OrderStatusFilled)
+	if !filled.FilledAmount.Equal(dec("0.1")) || !filled.FilledQuote.Equal(dec("500")) {
+		t.Fatalf("taker filled %s / %s, want 0.1 / 500", filled.FilledAmount, filled.FilledQuote)
+	}
+
+	// 买方付出 500 USDT，收到 0.1 BTC 扣除 taker 手续费 0.0002；卖方收到 500 USDT 扣除 maker 手续费 0.5
+	assertBalance(t, store, buyer, "USDT", "500", "0")
+	assertBalance(t, store, buyer, "BTC", "0.0998", "0")
+	assertBalance(t, store, seller, "BTC", "0.9", "0")
+	assertBalance(t, store, seller, "USDT", "499.5", "0")
+	assertBalance(t, store, account.FeeUserID, "BTC", "0.0002", "0")
+	assertBalance(t, store, account.FeeUserID, "USDT", "0.5", "0")
+	assertBalance(t, store, account.HouseUserID, "BTC", "0", "0")
+	assertBalance(t, store, account.HouseUserID, "USDT", "0", "0")
+
+	takerTrades, err := l.GetOrderTrades(taker.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	makerTrades, err := l.GetOrderTrades(maker.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(takerTrades) != 1 || len(makerTrades) != 1 {
+		t.Fatalf("got %d taker and %d maker trades, want 1 each", len(takerTrades), len(makerTrades))
+	}
+	if takerTrades[0].MatchID == "" || takerTrades[0].MatchID != makerTrades[0].MatchID {
+		t.Fatalf("trades not linked by match id: %q %q", takerTrades[0].MatchID, makerTrades[0].MatchID)
+	}
+	if takerTrades[0].Role != types.LiquidityTaker || makerTrades[0].Role != types.LiquidityMaker {
+		t.Fatalf("roles %s / %s", takerTrades[0].Role, makerTrades[0].Role)
+	}
+	assertEvents(t, store, taker.OrderID, event.TypeOrderAccepted, event.TypeTradeExecuted, event.TypeOrderFilled)
+	assertEvents(t, store, maker.OrderID, event.TypeOrderAccepted, event.TypeTradeExecuted, event.TypeOrderFilled)
+}
+
+func TestCancelOrder(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, seller, "BTC", "1")
+
+	order, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, store, seller, "BTC", "0.7", "0.3")
+
+	if err := l.CancelOrder(order.OrderID, "user"); err != nil {
+		t.Fatal(err)
+	}
+	cancelled := assertStatus(t, l, order.OrderID, types.OrderStatusCancelled)
+	if cancelled.CancelReason != "user" {
+		t.Fatalf("cancel reason %q", cancelled.CancelReason)
+	}
+	assertBalance(t, store, seller, "BTC", "1", "0")
+	assertEvents(t, store, order.OrderID, event.TypeOrderAccepted, event.TypeOrderCancelled)
+
+	// 已撤下的订单不再参与撮合
+	if _, asks := l.svcCtx.Matcher.Depth(symbol, 10, decimal.Zero); len(asks) != 0 {
+		t.Fatalf("cancelled order still on book: %+v", asks)
+	}
+	// 已完结的订单不能再次取消
+	if err := l.CancelOrder(order.OrderID, "user"); err == nil {
+		t.Fatal("expected error cancelling a cancelled order")
+	}
+}
+
+func TestFillOrder(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+
+	order, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 人工成交按 maker 计费，对手方为平台清算账户
+	if err := l.FillOrder(order.OrderID, dec("5000"), dec("0.04")); err != nil {
+		t.Fatal(err)
+	}
+	part := assertStatus(t, l, order.OrderID, types.OrderStatusPartFilled)
+	if !part.FilledAmount.Equal(dec("0.04")) {
+		t.Fatalf("filled amount %s, want 0.04", part.FilledAmount)
+	}
+	assertBalance(t, store, buyer, "USDT", "500", "300")
+	assertBalance(t, store, buyer, "BTC", "0.03996", "0")
+	assertBalance(t, store, account.HouseUserID, "BTC", "-0.04", "0")
+
+	// 订单簿上的剩余数量同步减少
+	bids, _ := l.svcCtx.Matcher.Depth(symbol, 10, decimal.Zero)
+	if len(bids) != 1 || !bids[0].Amount.Equal(dec("0.06")) {
+		t.Fatalf("book bids %+v, want 0.06 remaining", bids)
+	}
+
+	// 超出剩余数量的成交被拒绝
+	if err := l.FillOrder(order.OrderID, dec("5000"), dec("0.07")); err == nil {
+		t.Fatal("expected overfill error")
+	}
+	if err := l.FillOrder(order.OrderID, dec("5000"), dec("0.06")); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, l, order.OrderID, types.OrderStatusFilled)
+	assertBalance(t, store, buyer, "USDT", "500", "0")
+	assertBalance(t, store, buyer, "BTC", "0.0999", "0")
+	assertEvents(t, store, order.OrderID, event.TypeOrderAccepted,
+		event.TypeTradeExecuted, event.TypeOrderFilled,
+		event.TypeTradeExecuted, event.TypeOrderFilled)
+}
+
+func TestPostOnlyReprice(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	if _, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.1")); err != nil {
+		t.Fatal(err)
+	}
+	// 会吃单的 POST_ONLY 买单改价为卖一价下方一个价位后挂单，多冻结的计价币退回
+	req := limitOrder(buyer, types.OrderSideBuy, "5100", "0.1")
+	req.TimeInForce = types.TimeInForcePostOnly
+	order, err := l.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repriced := assertStatus(t, l, order.OrderID, types.OrderStatusPending)
+	if !repriced.Price.Equal(dec("4999.99")) {
+		t.Fatalf("price %s, want 4999.99", repriced.Price)
+	}
+	assertBalance(t, store, buyer, "USDT", "500.001", "499.999")
+	assertEvents(t, store, order.OrderID, event.TypeOrderAccepted, event.TypeOrderRepriced)
+
+	bids, _ := l.svcCtx.Matcher.Depth(symbol, 10, decimal.Zero)
+	if len(bids) != 1 || !bids[0].Price.Equal(dec("4999.99")) {
+		t.Fatalf("book bids %+v, want 4999.99", bids)
+	}
+}
+
+func TestMarketBuyBelowOneLot(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	if _, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "500000", "0.1")); err != nil {
+		t.Fatal(err)
+	}
+	// 卖一价下一个最小数量单位需要 50 USDT，20 USDT 一笔也成交不了，按流动性不足取消并退回冻结金额
+	order, err := l.CreateOrder(&types.Order{
+		UserID:      buyer,
+		Symbol:      symbol,
+		OrderSide:   types.OrderSideBuy,
+		OrderType:   types.OrderTypeMarket,
+		QuoteAmount: dec("20"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled := assertStatus(t, l, order.OrderID, types.OrderStatusCancelled)
+	if cancelled.CancelReason != types.CancelReasonNoLiquidity || !cancelled.FilledAmount.IsZero() {
+		t.Fatalf("cancel reason %q filled %s", cancelled.CancelReason, cancelled.FilledAmount)
+	}
+	assertBalance(t, store, buyer, "USDT", "1000", "0")
+	assertEvents(t, store, order.OrderID, event.TypeOrderAccepted, event.TypeOrderCancelled)
+}
+
+func TestSettlementFailureRevertsMatch(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	maker, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 挂单在数据库中已完结但仍在订单簿上，成交落库时被状态机拒绝
+	closed, err := l.loadOrder(maker.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.closeOrder(closed, types.OrderStatusCancelled, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	order := limitOrder(buyer, types.OrderSideBuy, "5000", "0.1")
+	if _, err := l.CreateOrder(order); err == nil {
+		t.Fatal("expected settlement error")
+	}
+
+	// 撮合被撤销：挂单回到订单簿，taker 按数据库状态取消并退回冻结资金
+	cancelled := assertStatus(t, l, order.OrderID, types.OrderStatusCancelled)
+	if cancelled.CancelReason != types.CancelReasonSettlement || !cancelled.FilledAmount.IsZero() {
+		t.Fatalf("cancel reason %q filled %s", cancelled.CancelReason, cancelled.FilledAmount)
+	}
+	assertBalance(t, store, buyer, "USDT", "1000", "0")
+	if trades, err := l.GetOrderTrades(order.OrderID); err != nil || len(trades) != 0 {
+		t.Fatalf("trades %v, err %v", trades, err)
+	}
+	bids, asks := l.svcCtx.Matcher.Depth(symbol, 10, decimal.Zero)
+	if len(bids) != 0 || len(asks) != 1 || !asks[0].Amount.Equal(dec("0.1")) {
+		t.Fatalf("book bids %+v asks %+v, want the maker restored", bids, asks)
+	}
+}
+
+func TestCacheFailureDoesNotBlockMatching(t *testing.T) {
+	l, store := newTestLogic(t)
+	// Redis 不可用：订单已落库后缓存写入失败，撮合仍继续
+	l.svcCtx.Redis = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	maker, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taker, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, l, maker.OrderID, types.OrderStatusFilled)
+	assertStatus(t, l, taker.OrderID, types.OrderStatusFilled)
+}
+
+// conflictStore 放行前 skip 个事务，之后的 fail 个事务以行锁冲突失败
+type conflictStore struct {
+	repository.Store
+	skip, fail int
+}
+
+func (s *conflictStore) Transaction(fn func(tx repository.Store) error) error {
+	if s.skip > 0 {
+		s.skip--
+	} else if s.fail > 0 {
+		s.fail--
+		return fmt.Errorf("%w: deadlock", repository.ErrLockConflict)
+	}
+	return s.Store.Transaction(fn)
+}
+
+func TestSettlementRetriesLockConflict(t *testing.T) {
+	l, store := newTestLogic(t)
+	deposit(t, store, buyer, "USDT", "1000")
+	deposit(t, store, seller, "BTC", "1")
+
+	maker, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 下单事务之后的成交事务连续两次死锁，重试后成功
+	l.svcCtx.Store = &conflictStore{Store: store, skip: 1, fail: 2}
+	taker, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, l, maker.OrderID, types.OrderStatusFilled)
+	assertStatus(t, l, taker.OrderID, types.OrderStatusFilled)
+	assertBalance(t, store, buyer, "BTC", "0.0998", "0")
+}
@@ -0,0 +1,126 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"five/internal/account"
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/outbox"
+	"five/internal/repository"
+	"five/internal/types"
+)
+
+// eventLog 按订单号记录消费到的事件类型
+type eventLog struct {
+	mu     sync.Mutex
+	types  map[string][]string
+	events int
+}
+
+func (l *eventLog) handle(ctx context.Context, msg bus.Message) error {
+	events, err := event.Decode(msg.Value, msg.Headers)
+	if err != nil {
+		return bus.Permanent(err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range events {
+		if e.OrderID != string(msg.Key) {
+			return bus.Permanent(fmt.Errorf("event of order %s published with key %s", e.OrderID, msg.Key))
+		}
+		l.types[e.OrderID] = append(l.types[e.OrderID], string(e.Type))
+		l.events++
+	}
+	return nil
+}
+
+func (l *eventLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.events
+}
+
+// 订单变更写入发件箱，由转发任务发布到内存总线，每个消费组按订单内的发生顺序收到全部事件
+func TestOutboxPublishesOrderEvents(t *testing.T) {
+	db, err := repository.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := newTestLogic(t)
+	store := repository.NewGormStore(db)
+	l.svcCtx.Store = store
+	l.svcCtx.Events = event.Protobuf
+	for _, journal := range []account.Journal{
+		account.DepositJournal(buyer, "USDT", dec("10000")),
+		account.DepositJournal(seller, "BTC", dec("1")),
+	} {
+		if err := store.Accounts().Post(journal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := bus.NewMemoryBus(2)
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	topic := l.svcCtx.Config.Kafka.Topic
+	logs := map[string]*eventLog{}
+	for _, group := range []string{"audit", "notify"} {
+		logs[group] = &eventLog{types: make(map[string][]string)}
+		if _, err := bus.NewConsumer(b, topic, group, bus.RetryPolicy{}, logs[group].handle).Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	maker, err := l.CreateOrder(limitOrder(seller, types.OrderSideSell, "5000", "0.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taker, err := l.CreateOrder(limitOrder(buyer, types.OrderSideBuy, "5000", "0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CancelOrder(maker.OrderID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 事件写入后才启动转发，消息全部来自发件箱
+	go outbox.NewRelay(db, b, outbox.Options{PollInterval: 10 * time.Millisecond}).Run(ctx)
+
+	var records []types.EventRecord
+	if err := db.Order("sequence ASC").Find(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string][]string)
+	for _, record := range records {
+		want[record.OrderID] = append(want[record.OrderID], record.Type)
+	}
+	if got := want[taker.OrderID]; !reflect.DeepEqual(got, []string{"OrderAccepted", "TradeExecuted", "OrderFilled"}) {
+		t.Fatalf("taker events %v", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for group, log := range logs {
+		for log.count() < len(records) {
+			if time.Now().After(deadline) {
+				t.Fatalf("group %s consumed %d of %d events", group, log.count(), len(records))
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if !reflect.DeepEqual(log.types, want) {
+			t.Fatalf("group %s consumed %v, want %v", group, log.types, want)
+		}
+	}
+
+	var pending int64
+	db.Model(&types.OutboxMessage{}).Where("sent_at IS NULL").Count(&pending)
+	if pending != 0 {
+		t.Fatalf("%d outbox messages still pending", pending)
+	}
+}
@@ -0,0 +1,43 @@
+package projection
+
+import (
+	"context"
+
+	"five/internal/projection"
+	"five/internal/svc"
+)
+
+type ProjectionLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewProjectionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ProjectionLogic {
+	return &ProjectionLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetOpenOrders 查询用户未完结订单的订单号（由订单事件构建，相对数据库有短暂延迟）
+func (l *ProjectionLogic) GetOpenOrders(userID int64) ([]string, error) {
+	return projection.NewOpenOrders(l.svcCtx.Redis).List(l.ctx, userID)
+}
+
+// GetOrderCounts 查询用户订单数汇总
+func (l *ProjectionLogic) GetOrderCounts(userID int64) (*projection.OrderCountSummary, error) {
+	return projection.NewOrderCounts(l.svcCtx.Redis).Summary(l.ctx, userID)
+}
+
+// GetSymbolStats 查询交易对累计成交统计
+func (l *ProjectionLogic) GetSymbolStats(symbol string) (*projection.SymbolStat, error) {
+	if _, err := l.svcCtx.Instruments.Get(symbol); err != nil {
+		return nil, err
+	}
+	return projection.NewSymbolStats(l.svcCtx.Redis).Get(l.ctx, symbol)
+}
+
+// Rebuild 清空全部读模型并从最早的订单事件重新构建
+func (l *ProjectionLogic) Rebuild() error {
+	return l.svcCtx.Projections.Rebuild(l.ctx)
+}
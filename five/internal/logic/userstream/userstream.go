@@ -0,0 +1,64 @@
+package userstream
+
+import (
+	"context"
+	"errors"
+
+	"five/internal/middleware"
+	"five/internal/svc"
+	"five/internal/types"
+)
+
+type UserStreamLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUserStreamLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UserStreamLogic {
+	return &UserStreamLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// CreateListenKey 为当前登录用户创建连接用户数据流的 listen key
+func (l *UserStreamLogic) CreateListenKey() (*types.ListenKeyResp, error) {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	key, expireAt, err := l.svcCtx.UserStream.ListenKeys().Create(l.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ListenKeyResp{ListenKey: key, ExpireAt: expireAt}, nil
+}
+
+// KeepaliveListenKey 延长 listen key 的有效期
+func (l *UserStreamLogic) KeepaliveListenKey(req *types.ListenKeyReq) (*types.ListenKeyResp, error) {
+	if err := l.checkOwner(req.ListenKey); err != nil {
+		return nil, err
+	}
+	expireAt, err := l.svcCtx.UserStream.ListenKeys().Keepalive(l.ctx, req.ListenKey)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ListenKeyResp{ListenKey: req.ListenKey, ExpireAt: expireAt}, nil
+}
+
+// CloseListenKey 关闭 listen key，使用它的连接随即断开
+func (l *UserStreamLogic) CloseListenKey(req *types.ListenKeyReq) error {
+	if err := l.checkOwner(req.ListenKey); err != nil {
+		return err
+	}
+	return l.svcCtx.UserStream.CloseListenKey(l.ctx, req.ListenKey)
+}
+
+// checkOwner 只允许续期或关闭当前登录用户自己的 listen key
+func (l *UserStreamLogic) checkOwner(key string) error {
+	userID, ok := middleware.UserID(l.ctx)
+	if !ok {
+		return errors.New("unauthorized")
+	}
+	return l.svcCtx.UserStream.ListenKeys().Check(l.ctx, key, userID)
+}
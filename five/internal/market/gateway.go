@@ -0,0 +1,481 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/instrument"
+	"five/internal/matching"
+	"five/internal/types"
+	"five/internal/ws"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 频道类型，频道名为 <类型>:<交易对>，如 depth:BTC/USDT
+const (
+	ChannelDepth  = "depth"  // 深度：订阅时推送快照，之后推送按序号连续的增量
+	ChannelTrades = "trades" // 逐笔成交
+	ChannelTicker = "ticker" // 最近 24 小时行情，有变化时按间隔推送
+)
+
+// 启动时按批从成交表加载最近 24 小时成交的批大小
+const loadBatchSize = 5000
+
+// Options 行情网关参数
+type Options struct {
+	Topic            string        // 订单事件主题，成交与 24 小时行情由其中的成交事件驱动
+	Group            string        // 消费组
+	DepthLevels      int           // 深度快照的档数，0 表示全部
+	TickerInterval   time.Duration // 24 小时行情的推送间隔
+	UpdateBuffer     int           // 引擎深度变更的缓冲队列长度，写满时丢弃，订阅者随后重新收到快照
+	MaxSubscriptions int           // 每个连接最多订阅的频道数
+	Conn             ws.Options
+}
+
+// DepthData 深度推送。快照之后的增量序号逐一递增，Amount 为档位的最新总量，为 0 表示删除该档位；
+// 快照只包含前 DepthLevels 档，增量可能涉及更深的档位。服务端发现增量缺失时会重新推送快照。
+type DepthData struct {
+	Symbol string            `json:"symbol"`
+	Seq    uint64            `json:"seq"`
+	Time   time.Time         `json:"time"`
+	Bids   []types.OrderItem `json:"bids"`
+	Asks   []types.OrderItem `json:"asks"`
+}
+
+// TradeData 逐笔成交推送，Side 为 taker 方向
+type TradeData struct {
+	Symbol  string          `json:"symbol"`
+	TradeID string          `json:"trade_id"` // 撮合编号，人工成交为成交记录编号
+	Price   decimal.Decimal `json:"price"`
+	Amount  decimal.Decimal `json:"amount"`
+	Side    types.OrderSide `json:"side"`
+	Time    time.Time       `json:"time"`
+}
+
+// client 一个行情连接及其订阅
+type client struct {
+	conn     *ws.Conn
+	channels map[string]bool
+	depth    map[string]uint64 // 已推送到的深度序号
+}
+
+// Gateway 公开行情 WebSocket 网关。深度增量来自撮合引擎的订单簿变更，逐笔成交与 24 小时行情来自订单事件。
+// 每个连接有独立的有界发送队列，读得慢的连接被断开，不会阻塞撮合引擎与其他连接。
+type Gateway struct {
+	db          *gorm.DB
+	matcher     *matching.Engine
+	instruments instrument.Catalog
+	bus         bus.Bus
+	opts        Options
+
+	updates chan matching.BookUpdate
+	dropped atomic.Bool // 有深度变更因队列写满被丢弃
+
+	mu       sync.Mutex
+	channels map[string]map[*client]bool
+	windows  map[string]*rolling
+	pushed   map[string]Ticker // 每个交易对最近一次推送的行情
+	since    time.Time         // 早于该时间的成交已在启动时从成交表加载
+}
+
+func NewGateway(db *gorm.DB, matcher *matching.Engine, instruments instrument.Catalog, b bus.Bus, opts Options) *Gateway {
+	if opts.TickerInterval <= 0 {
+		opts.TickerInterval = time.Second
+	}
+	if opts.UpdateBuffer <= 0 {
+		opts.UpdateBuffer = 4096
+	}
+	if opts.MaxSubscriptions <= 0 {
+		opts.MaxSubscriptions = 50
+	}
+	return &Gateway{
+		db:          db,
+		matcher:     matcher,
+		instruments: instruments,
+		bus:         b,
+		opts:        opts,
+		updates:     make(chan matching.BookUpdate, opts.UpdateBuffer),
+		channels:    make(map[string]map[*client]bool),
+		windows:     make(map[string]*rolling),
+		pushed:      make(map[string]Ticker),
+	}
+}
+
+// Start 加载最近 24 小时的成交，订阅订单簿变更与订单事件，并在后台推送，直到 ctx 结束
+func (g *Gateway) Start(ctx context.Context) error {
+	if err := g.loadTrades(ctx); err != nil {
+		return err
+	}
+
+	g.matcher.OnBookUpdate(func(u matching.BookUpdate) {
+		select {
+		case g.updates <- u:
+		default:
+			g.dropped.Store(true)
+		}
+	})
+	consumer := bus.NewConsumer(g.bus, g.opts.Topic, g.opts.Group, bus.RetryPolicy{}, g.handleEvent)
+	if _, err := consumer.Start(ctx); err != nil {
+		g.matcher.OnBookUpdate(nil)
+		return err
+	}
+	go g.run(ctx)
+	return nil
+}
+
+func (g *Gateway) run(ctx context.Context) {
+	ticker := time.NewTicker(g.opts.TickerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			g.matcher.OnBookUpdate(nil)
+			return
+		case u := <-g.updates:
+			g.publishDepth(u)
+		case now := <-ticker.C:
+			if g.dropped.Swap(false) {
+				g.resync()
+			}
+			g.publishTickers(now)
+		}
+	}
+}
+
+// loadTrades 从成交表加载最近 24 小时的成交，使重启后的 24 小时行情完整
+func (g *Gateway) loadTrades(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.since = time.Now()
+	var batch []types.Trade
+	return g.db.WithContext(ctx).Select("id", "symbol", "price", "amount", "created_at").
+		Where("created_at >= ? AND created_at < ?", g.since.Add(-tickerWindow), g.since).
+		Where("role = ? OR counter_order_id = ''", types.LiquidityTaker).
+		FindInBatches(&batch, loadBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				g.window(batch[i].Symbol).add(batch[i].Price, batch[i].Amount, batch[i].CreatedAt)
+			}
+			return nil
+		}).Error
+}
+
+// window 交易对的滚动窗口（调用方需持有锁）
+func (g *Gateway) window(symbol string) *rolling {
+	w, ok := g.windows[symbol]
+	if !ok {
+		w = &rolling{}
+		g.windows[symbol] = w
+	}
+	return w
+}
+
+// handleEvent 处理订单事件中的成交。行情推送尽力而为，无法解码的事件直接跳过，由订单读模型的消费者送入死信
+func (g *Gateway) handleEvent(ctx context.Context, msg bus.Message) error {
+	events, err := event.Decode(msg.Value, msg.Headers)
+	if err != nil {
+		return nil
+	}
+	for _, e := range events {
+		if trade := e.Trade(); trade != nil {
+			g.onTrade(trade)
+		}
+	}
+	return nil
+}
+
+// onTrade 一次撮合的 maker 与 taker 各有一条成交记录，只按 taker 一侧推送；人工成交没有对手方，直接推送
+func (g *Gateway) onTrade(t *event.Trade) {
+	if t.CounterOrderID != "" && t.Role != types.LiquidityTaker {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// 重启后消费组可能重新投递启动前的成交，这些成交已从成交表加载，且对订阅者已过时
+	if t.CreatedAt.Before(g.since) {
+		return
+	}
+	g.window(t.Symbol).add(t.Price, t.Amount, t.CreatedAt)
+
+	subs := g.channels[ws.Channel(ChannelTrades, t.Symbol)]
+	if len(subs) == 0 {
+		return
+	}
+	id := t.MatchID
+	if id == "" {
+		id = t.TradeID
+	}
+	msg, err := json.Marshal(ws.Push{
+		Channel: ws.Channel(ChannelTrades, t.Symbol),
+		Data: TradeData{
+			Symbol:  t.Symbol,
+			TradeID: id,
+			Price:   t.Price,
+			Amount:  t.Amount,
+			Side:    t.Side,
+			Time:    t.CreatedAt,
+		},
+	})
+	if err != nil {
+		return
+	}
+	for c := range subs {
+		c.conn.Send(msg)
+	}
+}
+
+// publishDepth 向订阅者推送深度增量。每个连接只收到序号紧接其上一次推送的增量，
+// 序号不连续（变更被丢弃）时改为推送新的快照
+func (g *Gateway) publishDepth(u matching.BookUpdate) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	channel := ws.Channel(ChannelDepth, u.Symbol)
+	subs := g.channels[channel]
+	if len(subs) == 0 {
+		return
+	}
+	var msg []byte
+	for c := range subs {
+		last := c.depth[u.Symbol]
+		switch {
+		case u.Seq <= last:
+			// 订阅时的快照已包含该变更
+		case u.Seq == last+1:
+			if msg == nil {
+				var err error
+				msg, err = json.Marshal(ws.Push{
+					Channel: channel,
+					Type:    ws.PushUpdate,
+					Data:    depthData(u.Symbol, u.Seq, u.Time, u.Bids, u.Asks),
+				})
+				if err != nil {
+					return
+				}
+			}
+			if c.conn.Send(msg) {
+				c.depth[u.Symbol] = u.Seq
+			}
+		default:
+			g.sendDepthSnapshot(c, u.Symbol)
+		}
+	}
+}
+
+// resync 深度变更被丢弃后，向全部深度订阅者重新推送快照
+func (g *Gateway) resync() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for channel, subs := range g.channels {
+		kind, symbol, _ := ws.ParseChannel(channel)
+		if kind != ChannelDepth {
+			continue
+		}
+		for c := range subs {
+			g.sendDepthSnapshot(c, symbol)
+		}
+	}
+}
+
+// sendDepthSnapshot 推送深度快照并记录其序号（调用方需持有锁）
+func (g *Gateway) sendDepthSnapshot(c *client, symbol string) {
+	seq, bids, asks := g.matcher.BookDepth(symbol, g.opts.DepthLevels)
+	c.depth[symbol] = seq
+	c.conn.SendJSON(ws.Push{
+		Channel: ws.Channel(ChannelDepth, symbol),
+		Type:    ws.PushSnapshot,
+		Data:    depthData(symbol, seq, time.Now(), bids, asks),
+	})
+}
+
+func depthData(symbol string, seq uint64, at time.Time, bids, asks []matching.DepthLevel) DepthData {
+	data := DepthData{
+		Symbol: symbol,
+		Seq:    seq,
+		Time:   at,
+		Bids:   make([]types.OrderItem, 0, len(bids)),
+		Asks:   make([]types.OrderItem, 0, len(asks)),
+	}
+	for _, level := range bids {
+		data.Bids = append(data.Bids, types.OrderItem{Price: level.Price.String(), Amount: level.Amount.String()})
+	}
+	for _, level := range asks {
+		data.Asks = append(data.Asks, types.OrderItem{Price: level.Price.String(), Amount: level.Amount.String()})
+	}
+	return data
+}
+
+// publishTickers 向订阅者推送有变化的 24 小时行情
+func (g *Gateway) publishTickers(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for channel, subs := range g.channels {
+		kind, symbol, _ := ws.ParseChannel(channel)
+		if kind != ChannelTicker || len(subs) == 0 {
+			continue
+		}
+		t := g.window(symbol).ticker(symbol, now)
+		if prev, ok := g.pushed[symbol]; ok && sameTicker(prev, t) {
+			continue
+		}
+		g.pushed[symbol] = t
+		msg, err := json.Marshal(ws.Push{Channel: channel, Data: t})
+		if err != nil {
+			continue
+		}
+		for c := range subs {
+			c.conn.Send(msg)
+		}
+	}
+}
+
+// sameTicker 行情数据是否未变（不比较窗口时间）
+func sameTicker(a, b Ticker) bool {
+	return a.Trades == b.Trades && a.Open.Equal(b.Open) && a.Last.Equal(b.Last) &&
+		a.High.Equal(b.High) && a.Low.Equal(b.Low) && a.Volume.Equal(b.Volume)
+}
+
+// ServeHTTP 将请求升级为 WebSocket 连接并处理订阅请求，直到连接断开
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := ws.Upgrade(w, r, g.opts.Conn)
+	if err != nil {
+		return
+	}
+	c := &client{
+		conn:     conn,
+		channels: make(map[string]bool),
+		depth:    make(map[string]uint64),
+	}
+	conn.Run(func(msg []byte) {
+		g.handle(c, msg)
+	})
+	g.remove(c)
+}
+
+func (g *Gateway) handle(c *client, msg []byte) {
+	var req ws.Request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		c.conn.SendJSON(ws.Response{Op: ws.OpError, Error: "invalid request"})
+		return
+	}
+	var err error
+	switch req.Op {
+	case ws.OpPing:
+		c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpPong})
+	case ws.OpSubscribe:
+		err = g.subscribe(c, &req)
+	case ws.OpUnsubscribe:
+		err = g.unsubscribe(c, &req)
+	default:
+		err = fmt.Errorf("unknown op %q", req.Op)
+	}
+	if err != nil {
+		c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpError, Error: err.Error()})
+	}
+}
+
+// validate 校验频道名，返回交易对
+func (g *Gateway) validate(channel string) (kind, symbol string, err error) {
+	kind, symbol, ok := ws.ParseChannel(channel)
+	if !ok || (kind != ChannelDepth && kind != ChannelTrades && kind != ChannelTicker) {
+		return "", "", fmt.Errorf("invalid channel %q", channel)
+	}
+	if _, err := g.instruments.Get(symbol); err != nil {
+		return "", "", fmt.Errorf("unknown symbol %q", symbol)
+	}
+	return kind, symbol, nil
+}
+
+// subscribe 订阅频道，任一频道无效时整个请求失败。应答之后依次推送深度快照与当前 24 小时行情
+func (g *Gateway) subscribe(c *client, req *ws.Request) error {
+	if len(req.Channels) == 0 {
+		return fmt.Errorf("channels is required")
+	}
+	for _, channel := range req.Channels {
+		if _, _, err := g.validate(channel); err != nil {
+			return err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	added := make(map[string]bool)
+	for _, channel := range req.Channels {
+		if !c.channels[channel] {
+			added[channel] = true
+		}
+	}
+	if len(c.channels)+len(added) > g.opts.MaxSubscriptions {
+		return fmt.Errorf("too many subscriptions, max %d", g.opts.MaxSubscriptions)
+	}
+	c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpSubscribe, Channels: req.Channels})
+
+	for channel := range added {
+		kind, symbol, _ := ws.ParseChannel(channel)
+		c.channels[channel] = true
+		subs, ok := g.channels[channel]
+		if !ok {
+			subs = make(map[*client]bool)
+			g.channels[channel] = subs
+		}
+		subs[c] = true
+
+		switch kind {
+		case ChannelDepth:
+			g.sendDepthSnapshot(c, symbol)
+		case ChannelTicker:
+			c.conn.SendJSON(ws.Push{Channel: channel, Data: g.window(symbol).ticker(symbol, time.Now())})
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) unsubscribe(c *client, req *ws.Request) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, channel := range req.Channels {
+		g.drop(c, channel)
+	}
+	c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpUnsubscribe, Channels: req.Channels})
+	return nil
+}
+
+// remove 连接断开后移除其全部订阅
+func (g *Gateway) remove(c *client) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for channel := range c.channels {
+		g.drop(c, channel)
+	}
+}
+
+// drop 移除连接的一个订阅（调用方需持有锁）
+func (g *Gateway) drop(c *client, channel string) {
+	if !c.channels[channel] {
+		return
+	}
+	delete(c.channels, channel)
+	if kind, symbol, _ := ws.ParseChannel(channel); kind == ChannelDepth {
+		delete(c.depth, symbol)
+	}
+	subs := g.channels[channel]
+	delete(subs, c)
+	if len(subs) == 0 {
+		delete(g.channels, channel)
+	}
+}
@@ -0,0 +1,103 @@
+package market
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 24 小时行情按分钟分桶滚动统计
+const (
+	tickerWindow  = 24 * time.Hour
+	tickerBuckets = int(tickerWindow / time.Minute)
+)
+
+// Ticker 交易对最近 24 小时的行情
+type Ticker struct {
+	Symbol        string          `json:"symbol"`
+	Open          decimal.Decimal `json:"open"` // 窗口内第一笔成交价
+	High          decimal.Decimal `json:"high"`
+	Low           decimal.Decimal `json:"low"`
+	Last          decimal.Decimal `json:"last"`
+	Change        decimal.Decimal `json:"change"`         // Last - Open
+	ChangePercent decimal.Decimal `json:"change_percent"` // 涨跌幅，百分比保留两位小数
+	Volume        decimal.Decimal `json:"volume"`         // 成交量（基础币）
+	QuoteVolume   decimal.Decimal `json:"quote_volume"`   // 成交额（计价币）
+	Trades        int64           `json:"trades"`
+	OpenTime      time.Time       `json:"open_time"` // 窗口起点
+	CloseTime     time.Time       `json:"close_time"`
+}
+
+// bucket 一分钟内的成交汇总
+type bucket struct {
+	minute      int64 // Unix 分钟数，0 表示空桶
+	open, close decimal.Decimal
+	openAt      time.Time
+	closeAt     time.Time
+	high, low   decimal.Decimal
+	volume      decimal.Decimal
+	quoteVolume decimal.Decimal
+	trades      int64
+}
+
+// rolling 单个交易对的滚动窗口，按分钟下标循环复用桶（非并发安全，由 Gateway 加锁）
+type rolling struct {
+	buckets [tickerBuckets]bucket
+}
+
+// add 计入一笔成交，早于窗口的成交被忽略
+func (w *rolling) add(price, amount decimal.Decimal, at time.Time) {
+	minute := at.Unix() / 60
+	b := &w.buckets[minute%int64(tickerBuckets)]
+	if b.minute > minute {
+		return
+	}
+	if b.minute < minute {
+		*b = bucket{minute: minute, open: price, openAt: at, high: price, low: price}
+	}
+
+	if at.Before(b.openAt) {
+		b.open, b.openAt = price, at
+	}
+	if !at.Before(b.closeAt) {
+		b.close, b.closeAt = price, at
+	}
+	b.high = decimal.Max(b.high, price)
+	b.low = decimal.Min(b.low, price)
+	b.volume = b.volume.Add(amount)
+	b.quoteVolume = b.quoteVolume.Add(price.Mul(amount))
+	b.trades++
+}
+
+// ticker 汇总 now 之前 24 小时内的桶
+func (w *rolling) ticker(symbol string, now time.Time) Ticker {
+	t := Ticker{Symbol: symbol, OpenTime: now.Add(-tickerWindow), CloseTime: now}
+	oldest := now.Add(-tickerWindow).Unix()/60 + 1
+	var openAt, closeAt time.Time
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if b.minute < oldest || b.trades == 0 {
+			continue
+		}
+		if openAt.IsZero() || b.openAt.Before(openAt) {
+			t.Open, openAt = b.open, b.openAt
+		}
+		if !b.closeAt.Before(closeAt) {
+			t.Last, closeAt = b.close, b.closeAt
+		}
+		if t.Trades == 0 || b.high.GreaterThan(t.High) {
+			t.High = b.high
+		}
+		if t.Trades == 0 || b.low.LessThan(t.Low) {
+			t.Low = b.low
+		}
+		t.Volume = t.Volume.Add(b.volume)
+		t.QuoteVolume = t.QuoteVolume.Add(b.quoteVolume)
+		t.Trades += b.trades
+	}
+	if t.Open.IsPositive() {
+		t.Change = t.Last.Sub(t.Open)
+		t.ChangePercent = t.Change.Div(t.Open).Mul(decimal.NewFromInt(100)).Round(2)
+	}
+	return t
+}
@@ -0,0 +1,118 @@
+package matching
+
+import (
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// DepthLevel 聚合后的价格档位
+type DepthLevel struct {
+	Price  decimal.Decimal
+	Amount decimal.Decimal
+}
+
+// Depth 返回交易对的聚合深度，precision 为价格分组步长（<=0 表示不分组）
+func (e *Engine) Depth(symbol string, depth int, precision decimal.Decimal) (bids, asks []DepthLevel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[symbol]
+	if !ok {
+		return nil, nil
+	}
+	bids = aggregate(b.bids, types.OrderSideBuy, depth, precision)
+	asks = aggregate(b.asks, types.OrderSideSell, depth, precision)
+	return bids, asks
+}
+
+// aggregate 按价格步长合并档位：买盘向下取整，卖盘向上取整，避免聚合后买卖价交叉
+func aggregate(levels []*PriceLevel, side types.OrderSide, depth int, precision decimal.Decimal) []DepthLevel {
+	result := make([]DepthLevel, 0, depth)
+	for _, level := range levels {
+		price := groupPrice(level.Price, side, precision)
+		amount := level.Total()
+
+		if n := len(result); n > 0 && result[n-1].Price.Equal(price) {
+			result[n-1].Amount = result[n-1].Amount.Add(amount)
+			continue
+		}
+		if len(result) == depth {
+			break
+		}
+		result = append(result, DepthLevel{Price: price, Amount: amount})
+	}
+	return result
+}
+
+func groupPrice(price decimal.Decimal, side types.OrderSide, precision decimal.Decimal) decimal.Decimal {
+	if !precision.IsPositive() {
+		return price
+	}
+	steps := price.Div(precision)
+	if side == types.OrderSideBuy {
+		steps = steps.Floor()
+	} else {
+		steps = steps.Ceil()
+	}
+	return steps.Mul(precision)
+}
+
+// BookUpdate 一条命令引起的订单簿档位变化。Amount 为档位变化后的总量，为 0 表示档位已移除
+type BookUpdate struct {
+	Symbol string
+	Seq    uint64 // 交易对内连续递增，与 BookDepth 返回的序号衔接
+	Time   time.Time
+	Bids   []DepthLevel
+	Asks   []DepthLevel
+}
+
+// OnBookUpdate 设置订单簿档位变化的回调。回调在引擎锁内按序号顺序同步调用，不能阻塞，也不能再调用引擎
+func (e *Engine) OnBookUpdate(fn func(BookUpdate)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onUpdate = fn
+}
+
+// BookDepth 返回交易对不分组的前 depth 档深度（depth<=0 表示全部）及其对应的变更序号，
+// 序号大于该序号的 BookUpdate 依次应用到该深度上即得到最新的订单簿
+func (e *Engine) BookDepth(symbol string, depth int) (seq uint64, bids, asks []DepthLevel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[symbol]
+	if !ok {
+		return 0, nil, nil
+	}
+	if depth <= 0 {
+		depth = max(len(b.bids), len(b.asks))
+	}
+	bids = aggregate(b.bids, types.OrderSideBuy, depth, decimal.Zero)
+	asks = aggregate(b.asks, types.OrderSideSell, depth, decimal.Zero)
+	return b.updateSeq, bids, asks
+}
+
+// notify 为本条命令改变了档位的订单簿分配变更序号并回调（调用方需持有锁）
+func (e *Engine) notify() {
+	for _, b := range e.books {
+		if len(b.touched) == 0 {
+			continue
+		}
+		b.updateSeq++
+		update := BookUpdate{Symbol: b.Symbol, Seq: b.updateSeq, Time: time.Now()}
+		for _, ref := range b.touched {
+			level := DepthLevel{Price: ref.price, Amount: b.levelTotal(ref.side, ref.price)}
+			if ref.side == types.OrderSideBuy {
+				update.Bids = append(update.Bids, level)
+			} else {
+				update.Asks = append(update.Asks, level)
+			}
+		}
+		b.touched = b.touched[:0]
+		if e.onUpdate != nil {
+			e.onUpdate(update)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Match 一次撮合成交，maker 为订单簿上的挂单，taker 为新进入的订单
+type Match struct {
+	Symbol       string
+	TakerOrderID string
+	TakerUserID  int64
+	TakerSide    types.OrderSide
+	MakerOrderID string
+	MakerUserID  int64
+	Price        decimal.Decimal
+	Amount       decimal.Decimal
+}
+
+// Result 订单提交到撮合引擎后的结果
+type Result struct {
+	Matches        []Match
+	Expired        []string        // 撮合过程中发现已到期并移出订单簿的 GTD 挂单
+	Triggered      []string        // 本次成交价触发的条件单，按挂单先后排序
+	Price          decimal.Decimal // POST_ONLY 改价后的价格，见 Repriced
+	Remaining      decimal.Decimal // taker 剩余未成交数量
+	RemainingQuote decimal.Decimal // 市价买单剩余未花费的计价币金额
+	Rested         bool            // 剩余部分是否已挂入订单簿
+	Repriced       bool            // POST_ONLY 订单会吃单，需改价为 Price 后重新提交；本次未成交也未挂单
+	Rejected       bool            // 整单被拒绝，未产生任何成交
+	CancelReason   string          // 被拒绝或剩余部分被取消的原因
+}
+
+// Options 撮合引擎参数
+type Options struct {
+	MaxSlippage     decimal.Decimal // 市价单相对最优价的最大滑点，<=0 表示不限制
+	PostOnlyReprice bool            // POST_ONLY 订单会吃单时改价为对手价内一个价位，否则直接拒绝
+	PriceTick       decimal.Decimal // 交易对未配置时改价使用的最小价格变动单位
+	AmountScale     int32           // 交易对未配置时市价买单换算数量保留的小数位
+}
+
+// Engine 进程内撮合引擎，每个交易对一个订单簿。
+// 启用预写日志后，每条改变状态的命令先写入日志再执行，写入失败的命令不会执行。
+type Engine struct {
+	mu       sync.Mutex
+	opts     Options
+	books    map[string]*OrderBook
+	triggers map[string]*triggerBook
+	journal  *journal // 由 Recover 启用
+	onUpdate func(BookUpdate)
+
+	writersMu sync.Mutex
+	writers   map[string]*sync.Mutex // 交易对写锁，见 Lock
+
+	checkpointMu sync.Mutex // 串行化快照
+}
+
+func NewEngine(opts Options) *Engine {
+	return &Engine{
+		opts:     opts,
+		books:    make(map[string]*OrderBook),
+		triggers: make(map[string]*triggerBook),
+		writers:  make(map[string]*sync.Mutex),
+	}
+}
+
+// book 获取交易对的订单簿，不存在则创建（调用方需持有锁）
+func (e *Engine) book(symbol string) *OrderBook {
+	b, ok := e.books[symbol]
+	if !ok {
+		b = NewOrderBook(symbol)
+		b.priceTick = e.opts.PriceTick
+		b.amountScale = e.opts.AmountScale
+		e.books[symbol] = b
+	}
+	return b
+}
+
+// write 将命令写入预写日志（调用方需持有锁）。未指定时间的命令以当前时间为准
+func (e *Engine) write(cmd *command) error {
+	if cmd.Time.IsZero() {
+		cmd.Time = time.Now()
+	}
+	e.discard(cmd)
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.append(cmd)
+}
+
+// Configure 设置交易对的价格步长与数量步长
+func (e *Engine) Configure(symbol string, priceTick, lotStep decimal.Decimal) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.write(&command{Op: opConfigure, Symbol: symbol, PriceTick: &priceTick, LotStep: &lotStep}); err != nil {
+		return err
+	}
+	e.configure(symbol, priceTick, lotStep)
+	return nil
+}
+
+func (e *Engine) configure(symbol string, priceTick, lotStep decimal.Decimal) {
+	b := e.book(symbol)
+	b.priceTick = priceTick
+	b.lotStep = lotStep
+}
+
+// Submit 提交订单进行撮合，按订单类型与有效方式决定剩余部分挂单还是取消。
+// 调用方需持有交易对写锁，成交落库失败时用 Revert 撤销本次撮合
+func (e *Engine) Submit(order *types.Order) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd := &command{Op: opSubmit, Order: order}
+	if err := e.write(cmd); err != nil {
+		return nil, err
+	}
+	defer e.notify()
+	return e.submit(order, cmd.Time), nil
+}
+
+// submit 撮合订单，now 用于判断对手盘挂单是否已到期。改动记入撤销日志，供紧接着的 Revert 使用
+func (e *Engine) submit(order *types.Order, now time.Time) *Result {
+	b := e.book(order.Symbol)
+	b.undo = &undoLog{}
+	var result *Result
+	if order.OrderType == types.OrderTypeMarket {
+		result = e.submitMarket(b, order, now)
+	} else {
+		result = e.submitLimit(b, order, now)
+	}
+	if result.Rested {
+		b.undo.rested = order.OrderID
+	}
+
+	// 用本次成交价检查条件单
+	if len(result.Matches) > 0 {
+		tb := e.triggerBook(order.Symbol)
+		b.undo.recordTriggers(tb)
+		for _, m := range result.Matches {
+			result.Triggered = append(result.Triggered, tb.onTrade(m.Price)...)
+		}
+	}
+	return result
+}
+
+// submitLimit 限价单撮合
+func (e *Engine) submitLimit(b *OrderBook, order *types.Order, now time.Time) *Result {
+	taker := newEntry(order)
+	result := &Result{}
+	result.Expired = b.purgeExpired(oppositeSide(taker.Side), now)
+
+	switch order.TimeInForce {
+	case types.TimeInForceFOK:
+		if !b.fillable(taker) {
+			return e.reject(result, taker, types.CancelReasonFOK)
+		}
+	case types.TimeInForcePostOnly:
+		best := b.best(oppositeSide(taker.Side))
+		if best != nil && taker.crosses(best.Price) {
+			price, ok := e.reprice(taker.Side, best.Price, b.priceTick)
+			if !ok {
+				return e.reject(result, taker, types.CancelReasonPostOnly)
+			}
+			// 先由调用方落库新价格，再按新价格重新提交挂单，避免订单簿与数据库中的价格不一致
+			result.Price = price
+			result.Remaining = taker.Remaining
+			result.Repriced = true
+			return result
+		}
+	}
+
+	result.Matches = b.match(taker)
+	result.Remaining = taker.Remaining
+	if !taker.Remaining.IsPositive() {
+		return result
+	}
+
+	switch order.TimeInForce {
+	case types.TimeInForceIOC, types.TimeInForceFOK:
+		result.CancelReason = types.CancelReasonIOC
+	default:
+		b.add(taker)
+		result.Rested = true
+	}
+	return result
+}
+
+// submitMarket 市价单扫单，直到成交完毕或超出滑点保护价
+func (e *Engine) submitMarket(b *OrderBook, order *types.Order, now time.Time) *Result {
+	taker := newEntry(order)
+	result := &Result{}
+	opposite := oppositeSide(order.OrderSide)
+	result.Expired = b.purgeExpired(opposite, now)
+
+	best := b.best(opposite)
+	if best == nil {
+		result.Remaining = taker.Remaining
+		result.RemainingQuote = taker.Quote
+		result.CancelReason = types.CancelReasonNoLiquidity
+		return result
+	}
+	e.applySlippageBound(taker, best.Price)
+
+	if order.TimeInForce == types.TimeInForceFOK && !b.fillable(taker) {
+		return e.reject(result, taker, types.CancelReasonFOK)
+	}
+
+	result.Matches = b.match(taker)
+	result.Remaining = taker.Remaining
+	result.RemainingQuote = taker.Quote
+	if taker.open() {
+		if b.best(opposite) != nil {
+			result.CancelReason = types.CancelReasonSlippage
+		} else {
+			result.CancelReason = types.CancelReasonNoLiquidity
+		}
+	}
+	return result
+}
+
+func (e *Engine) reject(result *Result, taker *Entry, reason string) *Result {
+	result.Remaining = taker.Remaining
+	result.RemainingQuote = taker.Quote
+	result.Rejected = true
+	result.CancelReason = reason
+	return result
+}
+
+// reprice POST_ONLY 改价：买单改为卖一价下方一个价位，卖单改为买一价上方一个价位
+func (e *Engine) reprice(side types.OrderSide, bestOpposite, tick decimal.Decimal) (decimal.Decimal, bool) {
+	if !e.opts.PostOnlyReprice || !tick.IsPositive() {
+		return decimal.Zero, false
+	}
+	if side == types.OrderSideBuy {
+		price := bestOpposite.Sub(tick)
+		return price, price.IsPositive()
+	}
+	return bestOpposite.Add(tick), true
+}
+
+// applySlippageBound 根据最优对手价设置市价单可接受的最差成交价
+func (e *Engine) applySlippageBound(taker *Entry, bestPrice decimal.Decimal) {
+	if !e.opts.MaxSlippage.IsPositive() {
+		taker.unbounded = true
+		return
+	}
+	if taker.Side == types.OrderSideBuy {
+		taker.Price = bestPrice.Mul(decimal.NewFromInt(1).Add(e.opts.MaxSlippage))
+	} else {
+		taker.Price = bestPrice.Mul(decimal.NewFromInt(1).Sub(e.opts.MaxSlippage))
+	}
+}
+
+// Expire 移出所有已到期的 GTD 挂单，返回其订单号。逐个交易对取得写锁后清理，调用时不能持有写锁；
+// 出错时仍返回出错前已移出的挂单
+func (e *Engine) Expire(now time.Time) ([]string, error) {
+	var expired []string
+	for _, symbol := range e.dueSymbols(now) {
+		orderIDs, err := e.expireSymbol(symbol, now)
+		expired = append(expired, orderIDs...)
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+// dueSymbols 有到期挂单的交易对
+func (e *Engine) dueSymbols(now time.Time) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var symbols []string
+	for symbol, b := range e.books {
+		if b.hasExpired(now) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func (e *Engine) expireSymbol(symbol string, now time.Time) ([]string, error) {
+	defer e.Lock(symbol)()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// 没有到期挂单时不写日志，避免定时清理产生大量空命令
+	if !e.book(symbol).hasExpired(now) {
+		return nil, nil
+	}
+	if err := e.write(&command{Op: opExpire, Symbol: symbol, Time: now}); err != nil {
+		return nil, err
+	}
+	defer e.notify()
+	return e.expire(symbol, now), nil
+}
+
+// expire 清理交易对上已到期的挂单，symbol 为空时清理全部交易对（早期日志中的清理命令不指定交易对）
+func (e *Engine) expire(symbol string, now time.Time) []string {
+	var expired []string
+	for _, b := range e.books {
+		if symbol != "" && b.Symbol != symbol {
+			continue
+		}
+		expired = append(expired, b.purgeExpired(types.OrderSideBuy, now)...)
+		expired = append(expired, b.purgeExpired(types.OrderSideSell, now)...)
+	}
+	return expired
+}
+
+// ErrCrossedBook 恢复的挂单与对手盘可以成交，恢复后订单簿会交叉
+var ErrCrossedBook = errors.New("order crosses the opposite side of the book")
+
+// Restore 将已存在的挂单恢复到订单簿，不参与撮合（按数据库重建订单簿时使用）。
+// 挂单与对手盘可以成交时返回 ErrCrossedBook，不恢复
+func (e *Engine) Restore(order *types.Order) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry := newEntry(order)
+	if best := e.book(order.Symbol).best(oppositeSide(entry.Side)); best != nil && entry.Remaining.IsPositive() && entry.crosses(best.Price) {
+		return fmt.Errorf("order %s at %s: %w", order.OrderID, order.Price, ErrCrossedBook)
+	}
+	if err := e.write(&command{Op: opRestore, Order: order}); err != nil {
+		return err
+	}
+	e.restore(order)
+	e.notify()
+	return nil
+}
+
+func (e *Engine) restore(order *types.Order) {
+	entry := newEntry(order)
+	if !entry.Remaining.IsPositive() {
+		return
+	}
+	e.book(order.Symbol).add(entry)
+}
+
+// Cancel 从订单簿撤单，返回订单是否在簿上
+func (e *Engine) Cancel(symbol, orderID string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b := e.book(symbol)
+	if _, ok := b.orders[orderID]; !ok {
+		return false, nil
+	}
+	if err := e.write(&command{Op: opCancel, Symbol: symbol, OrderID: orderID}); err != nil {
+		return false, err
+	}
+	b.remove(orderID)
+	e.notify()
+	return true, nil
+}
+
+// Reduce 减少挂单剩余数量（订单在引擎外成交时同步订单簿）
+func (e *Engine) Reduce(symbol, orderID string, amount decimal.Decimal) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.book(symbol).orders[orderID]; !ok {
+		return nil
+	}
+	if err := e.write(&command{Op: opReduce, Symbol: symbol, OrderID: orderID, Amount: &amount}); err != nil {
+		return err
+	}
+	e.reduce(symbol, orderID, amount)
+	e.notify()
+	return nil
+}
+
+func (e *Engine) reduce(symbol, orderID string, amount decimal.Decimal) {
+	b := e.book(symbol)
+	entry, ok := b.orders[orderID]
+	if !ok {
+		return
+	}
+	entry.Remaining = entry.Remaining.Sub(amount)
+	b.touch(entry.Side, entry.Price)
+	if !entry.Remaining.IsPositive() {
+		b.remove(orderID)
+	}
+}
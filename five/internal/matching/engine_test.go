@@ -0,0 +1,234 @@
+package matching
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+func marketBuy(orderID, quote string, tif types.TimeInForce) *types.Order {
+	return &types.Order{
+		OrderID:     orderID,
+		UserID:      2,
+		Symbol:      testSymbol,
+		OrderType:   types.OrderTypeMarket,
+		OrderSide:   types.OrderSideBuy,
+		QuoteAmount: dec(quote),
+		TimeInForce: tif,
+	}
+}
+
+func newTestEngine(t *testing.T, opts Options, makers ...*types.Order) *Engine {
+	t.Helper()
+	e := NewEngine(opts)
+	configure(t, e)
+	for _, order := range makers {
+		submit(t, e, order)
+	}
+	return e
+}
+
+// fills 成交的 maker 订单号、价格与数量
+func fills(result *Result) []string {
+	var list []string
+	for _, m := range result.Matches {
+		list = append(list, m.MakerOrderID+" "+m.Price.String()+" "+m.Amount.String())
+	}
+	return list
+}
+
+func assertFills(t *testing.T, result *Result, want ...string) {
+	t.Helper()
+	if got := fills(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("fills %q, want %q", got, want)
+	}
+}
+
+func TestLimitPriceTimePriority(t *testing.T) {
+	e := newTestEngine(t, Options{},
+		limit("s1", types.OrderSideSell, "101", "1"),
+		limit("s2", types.OrderSideSell, "100", "1"),
+		limit("s3", types.OrderSideSell, "100", "1"),
+	)
+	result := submit(t, e, limit("b1", types.OrderSideBuy, "101", "2.5"))
+	assertFills(t, result, "s2 100 1", "s3 100 1", "s1 101 0.5")
+	if !result.Remaining.IsZero() || result.Rested {
+		t.Fatalf("remaining %s rested %v", result.Remaining, result.Rested)
+	}
+
+	// 不能成交的剩余部分挂单
+	result = submit(t, e, limit("b2", types.OrderSideBuy, "100.5", "1"))
+	if len(result.Matches) != 0 || !result.Rested {
+		t.Fatalf("b2 %+v, want rested without fills", result)
+	}
+	if state := restingState(e, testSymbol); len(state) != 2 || !strings.HasPrefix(state["s1"], "sell 101 0.5 ") {
+		t.Fatalf("book %v", state)
+	}
+}
+
+func TestPostOnlyReprice(t *testing.T) {
+	sell := limit("s1", types.OrderSideSell, "100", "1")
+	postOnly := func() *types.Order {
+		order := limit("b1", types.OrderSideBuy, "100", "1")
+		order.TimeInForce = types.TimeInForcePostOnly
+		return order
+	}
+
+	e := newTestEngine(t, Options{PostOnlyReprice: true}, sell)
+	result := submit(t, e, postOnly())
+	if !result.Repriced || !result.Price.Equal(dec("99.99")) || len(result.Matches) != 0 {
+		t.Fatalf("result %+v, want repriced to 99.99", result)
+	}
+
+	e = newTestEngine(t, Options{}, limit("s1", types.OrderSideSell, "100", "1"))
+	result = submit(t, e, postOnly())
+	if !result.Rejected || result.CancelReason != types.CancelReasonPostOnly {
+		t.Fatalf("result %+v, want rejected as post only", result)
+	}
+}
+
+func TestFOKAndIOC(t *testing.T) {
+	e := newTestEngine(t, Options{}, limit("s1", types.OrderSideSell, "100", "1"))
+
+	fok := limit("b1", types.OrderSideBuy, "100", "2")
+	fok.TimeInForce = types.TimeInForceFOK
+	result := submit(t, e, fok)
+	if !result.Rejected || result.CancelReason != types.CancelReasonFOK || len(result.Matches) != 0 {
+		t.Fatalf("FOK %+v, want rejected without fills", result)
+	}
+
+	ioc := limit("b2", types.OrderSideBuy, "100", "2")
+	ioc.TimeInForce = types.TimeInForceIOC
+	result = submit(t, e, ioc)
+	assertFills(t, result, "s1 100 1")
+	if result.Rested || result.CancelReason != types.CancelReasonIOC || !result.Remaining.Equal(dec("1")) {
+		t.Fatalf("IOC %+v, want remaining 1 cancelled", result)
+	}
+	if state := restingState(e, testSymbol); len(state) != 0 {
+		t.Fatalf("book %v, want empty", state)
+	}
+}
+
+// 市价买单按金额换算的数量向下取整到最小数量单位（0.0001），FOK 预检查与撮合结果一致
+func TestMarketBuyFOKFloorsToLot(t *testing.T) {
+	book := func() *Engine {
+		return newTestEngine(t, Options{},
+			limit("s1", types.OrderSideSell, "500000", "0.001"),
+			limit("s2", types.OrderSideSell, "600000", "1"),
+		)
+	}
+
+	for _, c := range []struct {
+		quote    string
+		rejected bool
+		fills    []string
+	}{
+		// 一个单位需要 50，一笔也成交不了
+		{quote: "20", rejected: true},
+		// 0.0002 之外剩余 4 不足一个单位，视为成交完毕
+		{quote: "104", fills: []string{"s1 500000 0.0002"}},
+		// 吃完第一档后剩余 30 在第二档买不到一个单位
+		{quote: "530", fills: []string{"s1 500000 0.001"}},
+		{quote: "650", fills: []string{"s1 500000 0.001", "s2 600000 0.0002"}},
+		// 对手盘全部吃完仍有剩余金额
+		{quote: "700000", rejected: true},
+	} {
+		result := submit(t, book(), marketBuy("b1", c.quote, types.TimeInForceFOK))
+		if result.Rejected != c.rejected {
+			t.Fatalf("quote %s: rejected %v, want %v (%+v)", c.quote, result.Rejected, c.rejected, result)
+		}
+		if c.rejected {
+			if result.CancelReason != types.CancelReasonFOK || !result.RemainingQuote.Equal(dec(c.quote)) {
+				t.Fatalf("quote %s: %+v", c.quote, result)
+			}
+			continue
+		}
+		assertFills(t, result, c.fills...)
+		if result.CancelReason != "" || result.RemainingQuote.IsPositive() {
+			t.Fatalf("quote %s: cancel %q remaining quote %s", c.quote, result.CancelReason, result.RemainingQuote)
+		}
+	}
+}
+
+func TestMarketSlippageBound(t *testing.T) {
+	e := newTestEngine(t, Options{MaxSlippage: dec("0.01")},
+		limit("s1", types.OrderSideSell, "100", "1"),
+		limit("s2", types.OrderSideSell, "102", "1"),
+	)
+	result := submit(t, e, marketBuy("b1", "300", types.TimeInForceGTC))
+	assertFills(t, result, "s1 100 1")
+	if result.CancelReason != types.CancelReasonSlippage || !result.RemainingQuote.Equal(dec("200")) {
+		t.Fatalf("result %+v, want 200 cancelled for slippage", result)
+	}
+
+	result = submit(t, NewEngine(Options{}), marketBuy("b2", "100", types.TimeInForceGTC))
+	if result.CancelReason != types.CancelReasonNoLiquidity {
+		t.Fatalf("empty book: cancel reason %q", result.CancelReason)
+	}
+}
+
+func TestTriggersFireOnTradePrice(t *testing.T) {
+	e := newTestEngine(t, Options{},
+		limit("s1", types.OrderSideSell, "95", "1"),
+		limit("s2", types.OrderSideSell, "89", "1"),
+	)
+	for _, order := range []*types.Order{
+		{OrderID: "t1", Symbol: testSymbol, OrderType: types.OrderTypeStopMarket, OrderSide: types.OrderSideBuy, TriggerPrice: dec("90")},
+		{OrderID: "t2", Symbol: testSymbol, OrderType: types.OrderTypeStopMarket, OrderSide: types.OrderSideSell, TriggerPrice: dec("90")},
+		{OrderID: "t3", Symbol: testSymbol, OrderType: types.OrderTypeStopMarket, OrderSide: types.OrderSideSell, TriggerPrice: dec("80")},
+	} {
+		order.UserID, order.Amount = 1, dec("1")
+		addTrigger(t, e, order)
+	}
+
+	// 成交价 89 触发止损卖单 t2；止损买单 t1 要涨到 90，止损卖单 t3 要跌到 80，继续等待
+	result := submit(t, e, limit("b1", types.OrderSideBuy, "89", "1"))
+	if !reflect.DeepEqual(result.Triggered, []string{"t2"}) {
+		t.Fatalf("triggered %v, want [t2]", result.Triggered)
+	}
+	result = submit(t, e, limit("b2", types.OrderSideBuy, "95", "1"))
+	if !reflect.DeepEqual(result.Triggered, []string{"t1"}) {
+		t.Fatalf("triggered %v, want [t1]", result.Triggered)
+	}
+	if ids := triggerIDs(e, testSymbol); !reflect.DeepEqual(ids, []string{"t3"}) {
+		t.Fatalf("queued triggers %v, want [t3]", ids)
+	}
+	if price, ok := e.LastPrice(testSymbol); !ok || !price.Equal(dec("95")) {
+		t.Fatalf("last price %s", price)
+	}
+}
+
+func TestReduceAndRevert(t *testing.T) {
+	e := newTestEngine(t, Options{}, limit("s1", types.OrderSideSell, "100", "1"))
+	if err := e.Reduce(testSymbol, "s1", dec("0.4")); err != nil {
+		t.Fatal(err)
+	}
+	if state := restingState(e, testSymbol); !strings.HasPrefix(state["s1"], "sell 100 0.6 ") {
+		t.Fatalf("book %v, want s1 reduced to 0.6", state)
+	}
+
+	// 撤销最近一次提交，订单簿恢复到提交之前
+	unlock := e.Lock(testSymbol)
+	if _, err := e.Submit(limit("b1", types.OrderSideBuy, "100", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Revert(testSymbol); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	bids, asks := e.Depth(testSymbol, 10, decimal.Zero)
+	if len(bids) != 0 || len(asks) != 1 || !asks[0].Amount.Equal(dec("0.6")) {
+		t.Fatalf("book bids %+v asks %+v after revert", bids, asks)
+	}
+
+	if err := e.Reduce(testSymbol, "s1", dec("0.6")); err != nil {
+		t.Fatal(err)
+	}
+	if state := restingState(e, testSymbol); len(state) != 0 {
+		t.Fatalf("book %v, want empty", state)
+	}
+}
@@ -0,0 +1,268 @@
+package matching
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// 预写日志中的命令类型
+const (
+	opConfigure     = "configure"
+	opSubmit        = "submit"
+	opExpire        = "expire"
+	opRestore       = "restore"
+	opCancel        = "cancel"
+	opReduce        = "reduce"
+	opAddTrigger    = "add_trigger"
+	opCancelTrigger = "cancel_trigger"
+	opRevert        = "revert"
+)
+
+// command 引擎接受的一条命令，先写入日志再执行。
+// Time 为命令被接受的时间，重放时代替当前时间，保证到期清理等结果与原先一致。
+type command struct {
+	Seq       uint64           `json:"seq"`
+	Op        string           `json:"op"`
+	Time      time.Time        `json:"time"`
+	Symbol    string           `json:"symbol,omitempty"`
+	OrderID   string           `json:"order_id,omitempty"`
+	Order     *types.Order     `json:"order,omitempty"`
+	Amount    *decimal.Decimal `json:"amount,omitempty"`
+	PriceTick *decimal.Decimal `json:"price_tick,omitempty"`
+	LotStep   *decimal.Decimal `json:"lot_step,omitempty"`
+}
+
+// 日志与快照文件名，序号补零使文件名按字典序即按序号排列
+const (
+	segmentPrefix  = "journal-"
+	segmentSuffix  = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json"
+)
+
+func segmentName(first uint64) string {
+	return fmt.Sprintf("%s%020d%s", segmentPrefix, first, segmentSuffix)
+}
+
+func snapshotName(seq uint64) string {
+	return fmt.Sprintf("%s%020d%s", snapshotPrefix, seq, snapshotSuffix)
+}
+
+// JournalOptions 预写日志参数
+type JournalOptions struct {
+	Dir  string // 日志与快照所在目录
+	Sync bool   // 每条命令写入后 fsync，关闭时进程崩溃不丢命令，但机器掉电可能丢失最后几条
+	Keep int    // 保留的快照个数，至少为 1
+}
+
+// journal 按序号追加的命令日志，分为多个文件，每次快照后切换到新文件
+type journal struct {
+	opts     JournalOptions
+	file     *os.File // 当前写入的文件，恢复完成前为空，此时不记录命令
+	seq      uint64   // 最后写入的命令序号
+	snapshot uint64   // 最近一次快照对应的命令序号
+	err      error    // 写入失败后拒绝之后的所有命令，需重启从日志恢复
+}
+
+// append 为命令分配序号并写入日志
+func (j *journal) append(cmd *command) error {
+	if j.err != nil {
+		return j.err
+	}
+	if j.file == nil {
+		return nil
+	}
+
+	cmd.Seq = j.seq + 1
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	line := make([]byte, 0, len(data)+10)
+	line = fmt.Appendf(line, "%08x ", crc32.ChecksumIEEE(data))
+	line = append(line, data...)
+	line = append(line, '\n')
+
+	if _, err := j.file.Write(line); err != nil {
+		j.err = fmt.Errorf("write journal: %w", err)
+		return j.err
+	}
+	if j.opts.Sync {
+		if err := j.file.Sync(); err != nil {
+			j.err = fmt.Errorf("sync journal: %w", err)
+			return j.err
+		}
+	}
+	j.seq = cmd.Seq
+	return nil
+}
+
+// rotate 切换到从下一个序号开始的新文件
+func (j *journal) rotate() error {
+	if j.err != nil {
+		return j.err
+	}
+	file, err := os.OpenFile(filepath.Join(j.opts.Dir, segmentName(j.seq+1)), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(j.opts.Dir); err != nil {
+		file.Close()
+		return err
+	}
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file = file
+	return nil
+}
+
+// segment 一个日志文件及其第一条命令的序号
+type segment struct {
+	first uint64
+	path  string
+}
+
+// listFiles 列出目录中按序号升序排列的日志或快照文件
+func listFiles(dir, prefix, suffix string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, segment{first: n, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(files, func(i, k int) bool { return files[i].first < files[k].first })
+	return files, nil
+}
+
+// errTornWrite 日志末尾写了一半的命令
+var errTornWrite = errors.New("torn write")
+
+// readJournal 依次读取序号大于 after 的命令交给 fn，返回最后一条命令的序号。
+// 序号必须从 after+1 起连续；最后一个文件末尾写了一半的命令是崩溃所致，截断后忽略。
+func readJournal(dir string, after uint64, fn func(cmd *command) error) (uint64, error) {
+	segments, err := listFiles(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return 0, err
+	}
+	last := after
+	for i, seg := range segments {
+		// 后一个文件的起始序号不大于 after+1 时，本文件的命令均已包含在快照中
+		if i+1 < len(segments) && segments[i+1].first <= after+1 {
+			continue
+		}
+		valid, err := readSegment(seg.path, func(cmd *command) error {
+			if cmd.Seq <= after {
+				return nil
+			}
+			if cmd.Seq != last+1 {
+				return fmt.Errorf("journal gap: expected seq %d, got %d", last+1, cmd.Seq)
+			}
+			last = cmd.Seq
+			return fn(cmd)
+		})
+		if errors.Is(err, errTornWrite) && i == len(segments)-1 {
+			fmt.Printf("撮合日志 %s 末尾的命令不完整，已截断到 %d 字节\n", seg.path, valid)
+			if err := os.Truncate(seg.path, valid); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", seg.path, err)
+		}
+	}
+	return last, nil
+}
+
+// readSegment 读取一个日志文件，返回最后一条完整命令之后的偏移量
+func readSegment(path string, fn func(cmd *command) error) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return offset, errTornWrite
+			}
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		cmd, err := decodeCommand(line)
+		if err != nil {
+			// 校验失败的行只可能是最后一行，否则说明日志已损坏
+			if _, peek := r.Peek(1); peek == io.EOF {
+				return offset, errTornWrite
+			}
+			return offset, fmt.Errorf("offset %d: %w", offset, err)
+		}
+		if err := fn(cmd); err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+	}
+}
+
+// decodeCommand 解析一行日志："<crc32> <json>\n"
+func decodeCommand(line []byte) (*command, error) {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return nil, errors.New("malformed journal line")
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil {
+		return nil, errors.New("malformed journal checksum")
+	}
+	if crc32.ChecksumIEEE(data) != uint32(want) {
+		return nil, errors.New("journal checksum mismatch")
+	}
+	var cmd command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// syncDir 持久化目录项，保证新建与改名的文件在掉电后仍可见
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
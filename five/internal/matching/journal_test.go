@@ -0,0 +1,312 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+const testSymbol = "BTC/USDT"
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func configure(t *testing.T, e *Engine) {
+	t.Helper()
+	if err := e.Configure(testSymbol, dec("0.01"), dec("0.0001")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func limit(orderID string, side types.OrderSide, price, amount string) *types.Order {
+	return &types.Order{
+		OrderID:     orderID,
+		UserID:      1,
+		Symbol:      testSymbol,
+		OrderType:   types.OrderTypeLimit,
+		OrderSide:   side,
+		Price:       dec(price),
+		Amount:      dec(amount),
+		TimeInForce: types.TimeInForceGTC,
+	}
+}
+
+// submit 按调用方的用法在写锁内提交订单
+func submit(t *testing.T, e *Engine, order *types.Order) *Result {
+	t.Helper()
+	defer e.Lock(order.Symbol)()
+	result, err := e.Submit(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func addTrigger(t *testing.T, e *Engine, order *types.Order) {
+	t.Helper()
+	defer e.Lock(order.Symbol)()
+	if err := e.AddTrigger(order); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cancel(t *testing.T, e *Engine, orderID string) {
+	t.Helper()
+	defer e.Lock(testSymbol)()
+	if ok, err := e.Cancel(testSymbol, orderID); err != nil || !ok {
+		t.Fatalf("cancel %s: ok=%v err=%v", orderID, ok, err)
+	}
+}
+
+// newJournaled 在 dir 上恢复并开始记录日志的引擎
+func newJournaled(t *testing.T, dir string) (*Engine, *Recovery) {
+	t.Helper()
+	e := NewEngine(Options{})
+	rec, err := e.Recover(JournalOptions{Dir: dir, Keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	return e, rec
+}
+
+// restingState 订单簿上的挂单：订单号 -> 方向、价格、剩余数量与排队序号
+func restingState(e *Engine, symbol string) map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state := make(map[string]string)
+	for orderID, entry := range e.book(symbol).orders {
+		state[orderID] = fmt.Sprintf("%s %s %s #%d", entry.Side, entry.Price, entry.Remaining, entry.Seq)
+	}
+	return state
+}
+
+func triggerIDs(e *Engine, symbol string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var ids []string
+	for orderID := range e.triggerBook(symbol).triggers {
+		ids = append(ids, orderID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func assertSameBook(t *testing.T, want, got *Engine) {
+	t.Helper()
+	if w, g := restingState(want, testSymbol), restingState(got, testSymbol); !reflect.DeepEqual(w, g) {
+		t.Fatalf("recovered book %v, want %v", g, w)
+	}
+	if w, g := triggerIDs(want, testSymbol), triggerIDs(got, testSymbol); !reflect.DeepEqual(w, g) {
+		t.Fatalf("recovered triggers %v, want %v", g, w)
+	}
+	wp, _ := want.LastPrice(testSymbol)
+	gp, _ := got.LastPrice(testSymbol)
+	if !wp.Equal(gp) {
+		t.Fatalf("recovered last price %s, want %s", gp, wp)
+	}
+}
+
+// segments 日志文件路径，按序号升序
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := listFiles(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	return paths
+}
+
+func TestRecoverReplaysJournal(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := newJournaled(t, dir)
+	configure(t, e)
+
+	submit(t, e, limit("s1", types.OrderSideSell, "100", "1"))
+	submit(t, e, limit("s2", types.OrderSideSell, "101", "2"))
+	submit(t, e, limit("b1", types.OrderSideBuy, "101", "1.5"))
+	submit(t, e, limit("b2", types.OrderSideBuy, "99", "1"))
+	stop := limit("t1", types.OrderSideSell, "0", "1")
+	stop.OrderType, stop.TriggerPrice = types.OrderTypeStopMarket, dec("90")
+	addTrigger(t, e, stop)
+	cancel(t, e, "b2")
+
+	got, rec := newJournaled(t, dir)
+	if rec.Snapshot != 0 || rec.Replayed != 7 || rec.Seq != 7 {
+		t.Fatalf("recovery %+v, want 7 commands replayed without snapshot", rec)
+	}
+	assertSameBook(t, e, got)
+
+	// 与数据库中的订单核对一致
+	s2 := limit("s2", types.OrderSideSell, "101", "2")
+	s2.FilledAmount = dec("0.5")
+	if diffs := got.Verify([]types.Order{*s2}, []types.Order{*stop}); len(diffs) != 0 {
+		t.Fatalf("unexpected differences: %v", diffs)
+	}
+}
+
+func TestRecoverFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := newJournaled(t, dir)
+	configure(t, e)
+	submit(t, e, limit("s1", types.OrderSideSell, "100", "1"))
+	submit(t, e, limit("s2", types.OrderSideSell, "100", "1"))
+	seq, err := e.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	submit(t, e, limit("b1", types.OrderSideBuy, "100", "1.5"))
+
+	got, rec := newJournaled(t, dir)
+	if rec.Snapshot != seq || rec.Replayed != 1 {
+		t.Fatalf("recovery %+v, want snapshot %d and 1 command replayed", rec, seq)
+	}
+	assertSameBook(t, e, got)
+}
+
+func TestRecoverTruncatesTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := newJournaled(t, dir)
+	configure(t, e)
+	submit(t, e, limit("s1", types.OrderSideSell, "100", "1"))
+
+	// 崩溃时最后一条命令只写了一半
+	paths := segments(t, dir)
+	f, err := os.OpenFile(paths[len(paths)-1], os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`0badc0de {"seq":3,"op":"sub`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got, rec := newJournaled(t, dir)
+	if rec.Seq != 2 {
+		t.Fatalf("recovered up to seq %d, want 2", rec.Seq)
+	}
+	assertSameBook(t, e, got)
+
+	// 截断后继续追加的命令可以再次恢复
+	submit(t, got, limit("s2", types.OrderSideSell, "101", "1"))
+	again, _ := newJournaled(t, dir)
+	assertSameBook(t, got, again)
+}
+
+func TestRecoverRejectsCorruptJournal(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := newJournaled(t, dir)
+	configure(t, e)
+	submit(t, e, limit("s1", types.OrderSideSell, "100", "1"))
+	submit(t, e, limit("s2", types.OrderSideSell, "101", "1"))
+	submit(t, e, limit("s3", types.OrderSideSell, "102", "1"))
+
+	// 中间的命令校验失败说明日志已损坏，不能跳过
+	paths := segments(t, dir)
+	data, err := os.ReadFile(paths[len(paths)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := strings.Replace(string(data), `"s1"`, `"sX"`, 1)
+	if err := os.WriteFile(paths[len(paths)-1], []byte(corrupt), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEngine(Options{}).Recover(JournalOptions{Dir: dir}); err == nil {
+		t.Fatal("expected checksum error")
+	}
+}
+
+func TestRecoverReplaysRevert(t *testing.T) {
+	dir := t.TempDir()
+	e, _ := newJournaled(t, dir)
+	configure(t, e)
+	submit(t, e, limit("s1", types.OrderSideSell, "100", "1"))
+
+	unlock := e.Lock(testSymbol)
+	if _, err := e.Submit(limit("b1", types.OrderSideBuy, "100", "2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Revert(testSymbol); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	got, _ := newJournaled(t, dir)
+	assertSameBook(t, e, got)
+	if state := restingState(got, testSymbol); len(state) != 1 || !strings.HasPrefix(state["s1"], "sell 100 1 ") {
+		t.Fatalf("book %v, want only s1 with its full amount", state)
+	}
+}
+
+func TestVerifyReportsDifferences(t *testing.T) {
+	e := NewEngine(Options{})
+	configure(t, e)
+	for _, order := range []*types.Order{
+		limit("s1", types.OrderSideSell, "100", "1"),
+		limit("s2", types.OrderSideSell, "101", "1"),
+		limit("s3", types.OrderSideSell, "102", "1"),
+	} {
+		if err := e.Restore(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop := limit("t1", types.OrderSideSell, "0", "1")
+	stop.OrderType, stop.TriggerPrice = types.OrderTypeStopMarket, dec("90")
+	addTrigger(t, e, stop)
+
+	// 数据库：s1 一致，s2 已部分成交，s3 已完结，b1 未在订单簿上，t1 已完结，t2 未在触发队列中
+	s2 := limit("s2", types.OrderSideSell, "101", "1")
+	s2.FilledAmount = dec("0.4")
+	t2 := limit("t2", types.OrderSideBuy, "0", "1")
+	diffs := e.Verify(
+		[]types.Order{*limit("s1", types.OrderSideSell, "100", "1"), *s2, *limit("b1", types.OrderSideBuy, "90", "1")},
+		[]types.Order{*t2},
+	)
+	want := []string{
+		"order b1: missing from book",
+		"order s2: remaining 1 on book, database has 0.6",
+		"order s3: on BTC/USDT book but not open in database",
+		"trigger t1: queued for BTC/USDT but not untriggered in database",
+		"trigger t2: missing from trigger queue",
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("diffs %q, want %q", diffs, want)
+	}
+}
+
+func TestRestoreRejectsCrossedOrder(t *testing.T) {
+	e := NewEngine(Options{})
+	configure(t, e)
+	if err := e.Restore(limit("s1", types.OrderSideSell, "100", "1")); err != nil {
+		t.Fatal(err)
+	}
+	// 崩溃发生在撮合之后、成交落库之前时，数据库中的挂单彼此可以成交
+	err := e.Restore(limit("b1", types.OrderSideBuy, "100", "1"))
+	if !errors.Is(err, ErrCrossedBook) {
+		t.Fatalf("got %v, want ErrCrossedBook", err)
+	}
+	if err := e.Restore(limit("b2", types.OrderSideBuy, "99.99", "1")); err != nil {
+		t.Fatal(err)
+	}
+	bids, asks := e.Depth(testSymbol, 10, decimal.Zero)
+	if len(bids) != 1 || !bids[0].Price.Equal(dec("99.99")) || len(asks) != 1 {
+		t.Fatalf("book bids %+v asks %+v", bids, asks)
+	}
+}
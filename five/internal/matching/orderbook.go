@@ -0,0 +1,363 @@
+package matching
+
+import (
+	"sort"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Entry 挂在订单簿上的一笔订单
+type Entry struct {
+	OrderID   string          `json:"order_id"`
+	UserID    int64           `json:"user_id"`
+	Side      types.OrderSide `json:"side"`
+	Price     decimal.Decimal `json:"price"`
+	Remaining decimal.Decimal `json:"remaining"` // 剩余未成交数量
+	Quote     decimal.Decimal `json:"quote"`     // 剩余可用计价币金额，仅市价买单使用
+	ExpireAt  time.Time       `json:"expire_at"` // GTD 挂单的到期时间，零值表示不过期
+	Seq       uint64          `json:"seq"`       // 进入订单簿的顺序，用于时间优先
+	unbounded bool            // 无价格限制的市价单
+}
+
+func newEntry(order *types.Order) *Entry {
+	entry := &Entry{
+		OrderID: order.OrderID,
+		UserID:  order.UserID,
+		Side:    order.OrderSide,
+		Price:   order.Price,
+	}
+	if order.IsMarketBuy() {
+		entry.Quote = order.QuoteAmount.Sub(order.FilledQuote)
+	} else {
+		entry.Remaining = order.Amount.Sub(order.FilledAmount)
+	}
+	if order.TimeInForce == types.TimeInForceGTD && order.ExpireAt != nil {
+		entry.ExpireAt = *order.ExpireAt
+	}
+	return entry
+}
+
+// expired 挂单在 now 时刻是否已到期
+func (e *Entry) expired(now time.Time) bool {
+	return !e.ExpireAt.IsZero() && !now.Before(e.ExpireAt)
+}
+
+// open taker 是否还有未成交部分
+func (e *Entry) open() bool {
+	if e.Quote.IsPositive() {
+		return true
+	}
+	return e.Remaining.IsPositive()
+}
+
+// crosses 判断 taker 的限价能否与对手价成交
+func (e *Entry) crosses(makerPrice decimal.Decimal) bool {
+	if e.unbounded {
+		return true
+	}
+	if e.Side == types.OrderSideBuy {
+		return makerPrice.LessThanOrEqual(e.Price)
+	}
+	return makerPrice.GreaterThanOrEqual(e.Price)
+}
+
+// PriceLevel 同一价格档位，按时间先后排队
+type PriceLevel struct {
+	Price  decimal.Decimal
+	Orders []*Entry
+}
+
+// Total 档位上的挂单总量
+func (pl *PriceLevel) Total() decimal.Decimal {
+	total := decimal.Zero
+	for _, e := range pl.Orders {
+		total = total.Add(e.Remaining)
+	}
+	return total
+}
+
+// OrderBook 单个交易对的订单簿（非并发安全，由 Engine 加锁）
+type OrderBook struct {
+	Symbol string
+	bids   []*PriceLevel // 买盘，价格从高到低
+	asks   []*PriceLevel // 卖盘，价格从低到高
+	orders map[string]*Entry
+	seq    uint64
+	gtd    int // 簿上带到期时间的挂单数，为 0 时跳过到期扫描
+
+	updateSeq uint64     // 深度变更序号，每条改变了档位总量的命令加一
+	touched   []levelRef // 当前命令改变了总量的档位
+	undo      *undoLog   // 最近一次 Submit 的改动，交易对上执行其他命令后清空
+
+	priceTick   decimal.Decimal // 最小价格变动单位，POST_ONLY 改价使用
+	lotStep     decimal.Decimal // 最小数量变动单位，市价买单换算数量使用
+	amountScale int32           // 未配置 lotStep 时换算数量保留的小数位
+}
+
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{
+		Symbol: symbol,
+		orders: make(map[string]*Entry),
+	}
+}
+
+// levels 返回某一方向的档位切片指针
+func (b *OrderBook) levels(side types.OrderSide) *[]*PriceLevel {
+	if side == types.OrderSideBuy {
+		return &b.bids
+	}
+	return &b.asks
+}
+
+// better 判断价格 a 是否比 b 更优（买盘价高优先，卖盘价低优先）
+func better(side types.OrderSide, a, b decimal.Decimal) bool {
+	if side == types.OrderSideBuy {
+		return a.GreaterThan(b)
+	}
+	return a.LessThan(b)
+}
+
+// levelRef 一个价格档位
+type levelRef struct {
+	side  types.OrderSide
+	price decimal.Decimal
+}
+
+// touch 记录档位总量发生了变化
+func (b *OrderBook) touch(side types.OrderSide, price decimal.Decimal) {
+	for _, ref := range b.touched {
+		if ref.side == side && ref.price.Equal(price) {
+			return
+		}
+	}
+	b.touched = append(b.touched, levelRef{side: side, price: price})
+}
+
+// levelTotal 某一价格档位的挂单总量，档位不存在时为 0
+func (b *OrderBook) levelTotal(side types.OrderSide, price decimal.Decimal) decimal.Decimal {
+	levels := *b.levels(side)
+	i := sort.Search(len(levels), func(i int) bool {
+		return !better(side, levels[i].Price, price)
+	})
+	if i < len(levels) && levels[i].Price.Equal(price) {
+		return levels[i].Total()
+	}
+	return decimal.Zero
+}
+
+// add 将订单挂到订单簿尾部
+func (b *OrderBook) add(e *Entry) {
+	b.seq++
+	if e.Seq == 0 {
+		e.Seq = b.seq
+	} else if e.Seq > b.seq {
+		b.seq = e.Seq
+	}
+	b.insert(e)
+}
+
+// insert 按进入订单簿的顺序将订单放入所在档位，撤销撮合时将成交完的挂单放回原位
+func (b *OrderBook) insert(e *Entry) {
+	b.touch(e.Side, e.Price)
+	levels := b.levels(e.Side)
+	i := sort.Search(len(*levels), func(i int) bool {
+		return !better(e.Side, (*levels)[i].Price, e.Price)
+	})
+	if i < len(*levels) && (*levels)[i].Price.Equal(e.Price) {
+		level := (*levels)[i]
+		k := sort.Search(len(level.Orders), func(k int) bool { return level.Orders[k].Seq > e.Seq })
+		level.Orders = append(level.Orders, nil)
+		copy(level.Orders[k+1:], level.Orders[k:])
+		level.Orders[k] = e
+	} else {
+		level := &PriceLevel{Price: e.Price, Orders: []*Entry{e}}
+		*levels = append(*levels, nil)
+		copy((*levels)[i+1:], (*levels)[i:])
+		(*levels)[i] = level
+	}
+	b.orders[e.OrderID] = e
+	if !e.ExpireAt.IsZero() {
+		b.gtd++
+	}
+}
+
+// forget 清除订单索引（档位上的引用由调用方处理）
+func (b *OrderBook) forget(e *Entry) {
+	delete(b.orders, e.OrderID)
+	if !e.ExpireAt.IsZero() {
+		b.gtd--
+	}
+}
+
+// remove 从订单簿中移除订单
+func (b *OrderBook) remove(orderID string) (*Entry, bool) {
+	e, ok := b.orders[orderID]
+	if !ok {
+		return nil, false
+	}
+	b.forget(e)
+	b.touch(e.Side, e.Price)
+
+	levels := b.levels(e.Side)
+	for i, level := range *levels {
+		if !level.Price.Equal(e.Price) {
+			continue
+		}
+		for j, o := range level.Orders {
+			if o.OrderID == orderID {
+				level.Orders = append(level.Orders[:j], level.Orders[j+1:]...)
+				break
+			}
+		}
+		if len(level.Orders) == 0 {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+		}
+		break
+	}
+	return e, true
+}
+
+// best 对手方最优档位
+func (b *OrderBook) best(side types.OrderSide) *PriceLevel {
+	levels := *b.levels(side)
+	if len(levels) == 0 {
+		return nil
+	}
+	return levels[0]
+}
+
+// floorAmount 将数量向下取整到最小数量单位
+func (b *OrderBook) floorAmount(amount decimal.Decimal) decimal.Decimal {
+	if b.lotStep.IsPositive() {
+		return amount.Div(b.lotStep).Floor().Mul(b.lotStep)
+	}
+	return amount.Truncate(b.amountScale)
+}
+
+// match 用 taker 吃掉对手盘，价格优先、时间优先，成交价为 maker 价格。
+// 市价买单按金额换算数量时向下取整到最小数量单位，剩余不足一个单位的金额不再成交。
+func (b *OrderBook) match(taker *Entry) []Match {
+	var matches []Match
+	opposite := oppositeSide(taker.Side)
+	levels := b.levels(opposite)
+
+	for taker.open() && len(*levels) > 0 {
+		level := (*levels)[0]
+		if !taker.crosses(level.Price) {
+			break
+		}
+
+		for taker.open() && len(level.Orders) > 0 {
+			maker := level.Orders[0]
+			var amount decimal.Decimal
+			if taker.Quote.IsPositive() {
+				amount = decimal.Min(b.floorAmount(taker.Quote.Div(level.Price)), maker.Remaining)
+				if !amount.IsPositive() {
+					taker.Quote = decimal.Zero // 剩余金额不足一个最小数量单位
+					break
+				}
+				taker.Quote = taker.Quote.Sub(amount.Mul(level.Price))
+			} else {
+				amount = decimal.Min(taker.Remaining, maker.Remaining)
+				taker.Remaining = taker.Remaining.Sub(amount)
+			}
+			b.recordMaker(maker)
+			maker.Remaining = maker.Remaining.Sub(amount)
+			b.touch(opposite, level.Price)
+
+			matches = append(matches, Match{
+				Symbol:       b.Symbol,
+				TakerOrderID: taker.OrderID,
+				TakerUserID:  taker.UserID,
+				TakerSide:    taker.Side,
+				MakerOrderID: maker.OrderID,
+				MakerUserID:  maker.UserID,
+				Price:        level.Price,
+				Amount:       amount,
+			})
+
+			if !maker.Remaining.IsPositive() {
+				level.Orders = level.Orders[1:]
+				b.forget(maker)
+			}
+		}
+
+		if len(level.Orders) == 0 {
+			*levels = (*levels)[1:]
+		}
+	}
+	return matches
+}
+
+// fillable 判断 taker 在限价内能否被对手盘全部成交（FOK 预检查，不修改订单簿）。
+// 市价买单与 match 一样按金额换算数量并向下取整到最小数量单位：剩余金额在某一档买不到一个单位即视为成交完毕，
+// 但至少要成交一个单位；对手盘吃完后仍有剩余金额则不能全部成交
+func (b *OrderBook) fillable(taker *Entry) bool {
+	remaining, quote := taker.Remaining, taker.Quote
+	filled := false
+	for _, level := range *b.levels(oppositeSide(taker.Side)) {
+		if !taker.crosses(level.Price) {
+			break
+		}
+		total := level.Total()
+		if quote.IsPositive() {
+			amount := decimal.Min(b.floorAmount(quote.Div(level.Price)), total)
+			if amount.LessThan(total) {
+				return filled || amount.IsPositive()
+			}
+			quote = quote.Sub(amount.Mul(level.Price))
+			filled = true
+			if !quote.IsPositive() {
+				return true
+			}
+		} else {
+			remaining = remaining.Sub(total)
+			if !remaining.IsPositive() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasExpired 订单簿上是否有在 now 时刻已到期的挂单
+func (b *OrderBook) hasExpired(now time.Time) bool {
+	if b.gtd == 0 {
+		return false
+	}
+	for _, e := range b.orders {
+		if e.expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// purgeExpired 移出某一方向所有已到期的挂单
+func (b *OrderBook) purgeExpired(side types.OrderSide, now time.Time) []string {
+	if b.gtd == 0 {
+		return nil
+	}
+	var expired []string
+	for _, level := range *b.levels(side) {
+		for _, e := range level.Orders {
+			if e.expired(now) {
+				expired = append(expired, e.OrderID)
+			}
+		}
+	}
+	for _, orderID := range expired {
+		b.remove(orderID)
+	}
+	return expired
+}
+
+func oppositeSide(side types.OrderSide) types.OrderSide {
+	if side == types.OrderSideBuy {
+		return types.OrderSideSell
+	}
+	return types.OrderSideBuy
+}
@@ -0,0 +1,431 @@
+package matching
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// snapshot 订单簿快照，包含序号不大于 Seq 的所有命令执行后的状态
+type snapshot struct {
+	Seq   uint64      `json:"seq"`
+	Time  time.Time   `json:"time"`
+	Books []bookState `json:"books"`
+}
+
+// bookState 单个交易对的订单簿与触发队列
+type bookState struct {
+	Symbol      string          `json:"symbol"`
+	Seq         uint64          `json:"seq"`
+	PriceTick   decimal.Decimal `json:"price_tick"`
+	LotStep     decimal.Decimal `json:"lot_step"`
+	AmountScale int32           `json:"amount_scale"`
+	Orders      []Entry         `json:"orders"` // 按进入订单簿的顺序排列
+	LastPrice   decimal.Decimal `json:"last_price"`
+	TriggerSeq  uint64          `json:"trigger_seq"`
+	Triggers    []triggerState  `json:"triggers"` // 按进入触发队列的顺序排列
+}
+
+type triggerState struct {
+	OrderID      string          `json:"order_id"`
+	Side         types.OrderSide `json:"side"`
+	Type         types.OrderType `json:"type"`
+	TriggerPrice decimal.Decimal `json:"trigger_price"`
+	Offset       decimal.Decimal `json:"offset"`
+	Extreme      decimal.Decimal `json:"extreme"`
+	Seq          uint64          `json:"seq"`
+}
+
+// capture 复制当前状态（调用方需持有锁）
+func (e *Engine) capture(seq uint64) *snapshot {
+	symbols := make(map[string]bool, len(e.books))
+	for symbol := range e.books {
+		symbols[symbol] = true
+	}
+	for symbol := range e.triggers {
+		symbols[symbol] = true
+	}
+
+	snap := &snapshot{Seq: seq, Time: time.Now()}
+	for symbol := range symbols {
+		b := e.book(symbol)
+		state := bookState{
+			Symbol:      symbol,
+			Seq:         b.seq,
+			PriceTick:   b.priceTick,
+			LotStep:     b.lotStep,
+			AmountScale: b.amountScale,
+			Orders:      make([]Entry, 0, len(b.orders)),
+		}
+		for _, entry := range b.orders {
+			state.Orders = append(state.Orders, *entry)
+		}
+		sort.Slice(state.Orders, func(i, k int) bool { return state.Orders[i].Seq < state.Orders[k].Seq })
+
+		if tb, ok := e.triggers[symbol]; ok {
+			state.LastPrice = tb.lastPrice
+			state.TriggerSeq = tb.seq
+			for _, t := range tb.triggers {
+				state.Triggers = append(state.Triggers, triggerState{
+					OrderID:      t.OrderID,
+					Side:         t.Side,
+					Type:         t.Type,
+					TriggerPrice: t.TriggerPrice,
+					Offset:       t.Offset,
+					Extreme:      t.extreme,
+					Seq:          t.seq,
+				})
+			}
+			sort.Slice(state.Triggers, func(i, k int) bool { return state.Triggers[i].Seq < state.Triggers[k].Seq })
+		}
+		snap.Books = append(snap.Books, state)
+	}
+	sort.Slice(snap.Books, func(i, k int) bool { return snap.Books[i].Symbol < snap.Books[k].Symbol })
+	return snap
+}
+
+// load 用快照替换当前状态（调用方需持有锁）
+func (e *Engine) load(snap *snapshot) {
+	e.books = make(map[string]*OrderBook, len(snap.Books))
+	e.triggers = make(map[string]*triggerBook, len(snap.Books))
+	for _, state := range snap.Books {
+		b := e.book(state.Symbol)
+		b.priceTick = state.PriceTick
+		b.lotStep = state.LotStep
+		b.amountScale = state.AmountScale
+		for i := range state.Orders {
+			entry := state.Orders[i]
+			b.add(&entry)
+		}
+		b.seq = state.Seq
+
+		tb := e.triggerBook(state.Symbol)
+		tb.lastPrice = state.LastPrice
+		tb.seq = state.TriggerSeq
+		for _, t := range state.Triggers {
+			tb.triggers[t.OrderID] = &trigger{
+				OrderID:      t.OrderID,
+				Side:         t.Side,
+				Type:         t.Type,
+				TriggerPrice: t.TriggerPrice,
+				Offset:       t.Offset,
+				extreme:      t.Extreme,
+				seq:          t.Seq,
+			}
+		}
+	}
+}
+
+// writeSnapshot 先写临时文件再改名，崩溃时不会留下写了一半的快照
+func writeSnapshot(dir string, snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, snapshotName(snap.Seq))
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// readSnapshot 读取最新的可用快照，最新的快照损坏时依次尝试更早的快照；没有快照时返回 nil
+func readSnapshot(dir string) (*snapshot, error) {
+	files, err := listFiles(dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(files[i].path)
+		if err == nil {
+			var snap snapshot
+			if err = json.Unmarshal(data, &snap); err == nil {
+				return &snap, nil
+			}
+		}
+		fmt.Printf("撮合快照 %s 不可用，尝试更早的快照: %v\n", files[i].path, err)
+	}
+	return nil, nil
+}
+
+// Recovery 撮合引擎的恢复结果
+type Recovery struct {
+	Snapshot uint64 // 加载的快照对应的命令序号，0 表示没有快照
+	Replayed int    // 快照之后重放的命令数
+	Seq      uint64 // 恢复后的最后一个命令序号
+}
+
+// Recover 加载 Dir 中最新的快照并按序重放其后的日志，恢复崩溃前的订单簿与触发队列。
+// 恢复期间不记录日志；恢复结果与数据库核对无误后调用 Checkpoint 开始记录。
+func (e *Engine) Recover(opts JournalOptions) (*Recovery, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if opts.Keep <= 0 {
+		opts.Keep = 1
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	j := &journal{opts: opts}
+	e.journal = j
+
+	rec := &Recovery{}
+	snap, err := readSnapshot(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if snap != nil {
+		e.load(snap)
+		e.notify()
+		rec.Snapshot = snap.Seq
+	}
+	rec.Seq, err = readJournal(opts.Dir, rec.Snapshot, func(cmd *command) error {
+		rec.Replayed++
+		return e.apply(cmd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	j.seq, j.snapshot = rec.Seq, rec.Snapshot
+	return rec, nil
+}
+
+// apply 重放一条命令（调用方需持有锁）
+func (e *Engine) apply(cmd *command) error {
+	e.discard(cmd)
+	switch cmd.Op {
+	case opConfigure:
+		if cmd.PriceTick == nil || cmd.LotStep == nil {
+			return fmt.Errorf("command %d: missing steps", cmd.Seq)
+		}
+		e.configure(cmd.Symbol, *cmd.PriceTick, *cmd.LotStep)
+	case opSubmit, opRestore, opAddTrigger:
+		if cmd.Order == nil {
+			return fmt.Errorf("command %d: missing order", cmd.Seq)
+		}
+		switch cmd.Op {
+		case opSubmit:
+			e.submit(cmd.Order, cmd.Time)
+		case opRestore:
+			e.restore(cmd.Order)
+		default:
+			e.addTrigger(cmd.Order)
+		}
+	case opExpire:
+		e.expire(cmd.Symbol, cmd.Time)
+	case opCancel:
+		e.book(cmd.Symbol).remove(cmd.OrderID)
+	case opReduce:
+		if cmd.Amount == nil {
+			return fmt.Errorf("command %d: missing amount", cmd.Seq)
+		}
+		e.reduce(cmd.Symbol, cmd.OrderID, *cmd.Amount)
+	case opCancelTrigger:
+		delete(e.triggerBook(cmd.Symbol).triggers, cmd.OrderID)
+	case opRevert:
+		b := e.book(cmd.Symbol)
+		if b.undo == nil {
+			return fmt.Errorf("command %d: %w", cmd.Seq, ErrNothingToRevert)
+		}
+		e.revert(b)
+	default:
+		return fmt.Errorf("command %d: unknown op %q", cmd.Seq, cmd.Op)
+	}
+	e.notify()
+	return nil
+}
+
+// Reset 清空订单簿与触发队列，并将现有的快照与日志移入 discarded-<时间> 子目录留档，
+// 命令序号从 0 重新开始。恢复失败或与数据库不一致、改为按数据库重建订单簿时使用。
+func (e *Engine) Reset() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.books = make(map[string]*OrderBook)
+	e.triggers = make(map[string]*triggerBook)
+
+	j := e.journal
+	if j == nil {
+		return nil
+	}
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	j.seq, j.snapshot, j.err = 0, 0, nil
+
+	archive := filepath.Join(j.opts.Dir, "discarded-"+time.Now().Format("20060102150405"))
+	for _, kind := range [][2]string{{segmentPrefix, segmentSuffix}, {snapshotPrefix, snapshotSuffix}} {
+		files, err := listFiles(j.opts.Dir, kind[0], kind[1])
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			if err := os.MkdirAll(archive, 0o755); err != nil {
+				return err
+			}
+		}
+		for _, f := range files {
+			if err := os.Rename(f.path, filepath.Join(archive, filepath.Base(f.path))); err != nil {
+				return err
+			}
+		}
+	}
+	return syncDir(j.opts.Dir)
+}
+
+// Checkpoint 将当前状态写入快照并切换到新的日志文件，之后删除多余的旧快照以及
+// 已被保留的快照覆盖的日志。自上次快照以来没有新命令时不做任何事。
+// 恢复完成后调用一次以开始记录日志，之后定期调用以缩短恢复时需要重放的日志。
+// 复制状态时持有全部交易对的写锁，快照中不会有尚待落库、可能被撤销的撮合；调用时不能持有写锁。
+func (e *Engine) Checkpoint() (uint64, error) {
+	e.checkpointMu.Lock()
+	defer e.checkpointMu.Unlock()
+
+	unlock := e.lockAll()
+	e.mu.Lock()
+	j := e.journal
+	if j == nil {
+		e.mu.Unlock()
+		unlock()
+		return 0, errors.New("journal is not enabled")
+	}
+	if j.file != nil && j.seq == j.snapshot {
+		e.mu.Unlock()
+		unlock()
+		return j.snapshot, nil
+	}
+	snap := e.capture(j.seq)
+	if err := j.rotate(); err != nil {
+		// 日志未开始记录时无法继续；否则继续写入当前文件，快照照常生成
+		if j.file == nil {
+			e.mu.Unlock()
+			unlock()
+			return 0, err
+		}
+		fmt.Printf("切换撮合日志文件失败: %v\n", err)
+	}
+	e.mu.Unlock()
+	unlock()
+
+	if err := writeSnapshot(j.opts.Dir, snap); err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	j.snapshot = snap.Seq
+	e.mu.Unlock()
+	return snap.Seq, prune(j.opts.Dir, j.opts.Keep)
+}
+
+// prune 保留最近 keep 个快照，删除更早的快照以及命令均已包含在最早保留的快照中的日志文件
+func prune(dir string, keep int) error {
+	snapshots, err := listFiles(dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+	if len(snapshots) > keep {
+		for _, f := range snapshots[:len(snapshots)-keep] {
+			if err := os.Remove(f.path); err != nil {
+				return err
+			}
+		}
+		snapshots = snapshots[len(snapshots)-keep:]
+	}
+
+	covered := snapshots[0].first
+	segments, err := listFiles(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(segments) && segments[i+1].first <= covered+1; i++ {
+		if err := os.Remove(segments[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify 核对订单簿与数据库中的订单，返回不一致之处。
+// resting 为数据库中未完结的订单，只核对会挂在订单簿上的限价单；untriggered 为等待触发的条件单。
+func (e *Engine) Verify(resting, untriggered []types.Order) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var diffs []string
+	seen := make(map[string]bool)
+	for i := range resting {
+		order := &resting[i]
+		if order.OrderType != types.OrderTypeLimit {
+			continue
+		}
+		want := newEntry(order)
+		if !want.Remaining.IsPositive() {
+			continue
+		}
+		seen[order.OrderID] = true
+		got, ok := e.book(order.Symbol).orders[order.OrderID]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("order %s: missing from book", order.OrderID))
+		case got.Side != want.Side || !got.Price.Equal(want.Price):
+			diffs = append(diffs, fmt.Sprintf("order %s: %s %s on book, database has %s %s",
+				order.OrderID, got.Side, got.Price, want.Side, want.Price))
+		case !got.Remaining.Equal(want.Remaining):
+			diffs = append(diffs, fmt.Sprintf("order %s: remaining %s on book, database has %s",
+				order.OrderID, got.Remaining, want.Remaining))
+		}
+	}
+	for symbol, b := range e.books {
+		for orderID := range b.orders {
+			if !seen[orderID] {
+				diffs = append(diffs, fmt.Sprintf("order %s: on %s book but not open in database", orderID, symbol))
+			}
+		}
+	}
+
+	seen = make(map[string]bool, len(untriggered))
+	for i := range untriggered {
+		order := &untriggered[i]
+		seen[order.OrderID] = true
+		if _, ok := e.triggerBook(order.Symbol).triggers[order.OrderID]; !ok {
+			diffs = append(diffs, fmt.Sprintf("trigger %s: missing from trigger queue", order.OrderID))
+		}
+	}
+	for symbol, tb := range e.triggers {
+		for orderID := range tb.triggers {
+			if !seen[orderID] {
+				diffs = append(diffs, fmt.Sprintf("trigger %s: queued for %s but not untriggered in database", orderID, symbol))
+			}
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
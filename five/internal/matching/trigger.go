@@ -0,0 +1,136 @@
+package matching
+
+import (
+	"sort"
+
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// trigger 触发队列中的一笔条件单
+type trigger struct {
+	OrderID      string
+	Side         types.OrderSide
+	Type         types.OrderType
+	TriggerPrice decimal.Decimal
+	Offset       decimal.Decimal // 追踪止损的回撤价差
+	extreme      decimal.Decimal // 追踪期间的最高价（卖）或最低价（买），0 表示尚未有成交价
+	seq          uint64
+}
+
+// update 用最新成交价更新追踪极值，并判断是否触发
+func (t *trigger) update(price decimal.Decimal) bool {
+	if t.Type == types.OrderTypeTrailingStop {
+		if t.extreme.IsZero() {
+			t.extreme = price
+		}
+		if t.Side == types.OrderSideSell {
+			t.extreme = decimal.Max(t.extreme, price)
+			return price.LessThanOrEqual(t.extreme.Sub(t.Offset))
+		}
+		t.extreme = decimal.Min(t.extreme, price)
+		return price.GreaterThanOrEqual(t.extreme.Add(t.Offset))
+	}
+
+	// 止损买单在价格涨到触发价时触发，止损卖单在价格跌到触发价时触发
+	if t.Side == types.OrderSideBuy {
+		return price.GreaterThanOrEqual(t.TriggerPrice)
+	}
+	return price.LessThanOrEqual(t.TriggerPrice)
+}
+
+// triggerBook 单个交易对的触发队列与最新成交价
+type triggerBook struct {
+	lastPrice decimal.Decimal
+	triggers  map[string]*trigger
+	seq       uint64
+}
+
+func newTriggerBook() *triggerBook {
+	return &triggerBook{triggers: make(map[string]*trigger)}
+}
+
+// onTrade 处理一笔成交价，返回按挂单先后排序的已触发订单
+func (tb *triggerBook) onTrade(price decimal.Decimal) []string {
+	tb.lastPrice = price
+
+	var fired []*trigger
+	for _, t := range tb.triggers {
+		if t.update(price) {
+			fired = append(fired, t)
+		}
+	}
+	sort.Slice(fired, func(i, j int) bool { return fired[i].seq < fired[j].seq })
+
+	orderIDs := make([]string, 0, len(fired))
+	for _, t := range fired {
+		delete(tb.triggers, t.OrderID)
+		orderIDs = append(orderIDs, t.OrderID)
+	}
+	return orderIDs
+}
+
+// triggerBook 获取交易对的触发队列，不存在则创建（调用方需持有锁）
+func (e *Engine) triggerBook(symbol string) *triggerBook {
+	tb, ok := e.triggers[symbol]
+	if !ok {
+		tb = newTriggerBook()
+		e.triggers[symbol] = tb
+	}
+	return tb
+}
+
+// AddTrigger 将条件单放入触发队列
+func (e *Engine) AddTrigger(order *types.Order) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.write(&command{Op: opAddTrigger, Order: order}); err != nil {
+		return err
+	}
+	e.addTrigger(order)
+	return nil
+}
+
+func (e *Engine) addTrigger(order *types.Order) {
+	tb := e.triggerBook(order.Symbol)
+	tb.seq++
+	tb.triggers[order.OrderID] = &trigger{
+		OrderID:      order.OrderID,
+		Side:         order.OrderSide,
+		Type:         order.OrderType,
+		TriggerPrice: order.TriggerPrice,
+		Offset:       order.TrailingOffset,
+		extreme:      tb.lastPrice,
+		seq:          tb.seq,
+	}
+}
+
+// CancelTrigger 从触发队列撤销条件单
+func (e *Engine) CancelTrigger(symbol, orderID string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tb := e.triggerBook(symbol)
+	if _, ok := tb.triggers[orderID]; !ok {
+		return false, nil
+	}
+	if err := e.write(&command{Op: opCancelTrigger, Symbol: symbol, OrderID: orderID}); err != nil {
+		return false, err
+	}
+	delete(tb.triggers, orderID)
+	return true, nil
+}
+
+// LastPrice 交易对的最新成交价
+func (e *Engine) LastPrice(symbol string) (decimal.Decimal, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tb, ok := e.triggers[symbol]
+	if !ok || tb.lastPrice.IsZero() {
+		return decimal.Zero, false
+	}
+	return tb.lastPrice, true
+}
@@ -0,0 +1,144 @@
+package matching
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNothingToRevert 交易对上最近一条命令不是可撤销的 Submit
+var ErrNothingToRevert = errors.New("no submit to revert")
+
+// undoLog 一次 Submit 对订单簿与触发队列的改动，用于 Revert。撮合时顺带移出的到期挂单不撤销
+type undoLog struct {
+	rested    string             // 挂入订单簿的 taker，未挂单时为空
+	makers    []makerUndo        // 被成交的挂单，按成交顺序
+	lastPrice decimal.Decimal    // 成交前的最新成交价
+	triggers  map[string]trigger // 成交前的触发队列（含追踪极值），未成交时为 nil
+}
+
+// makerUndo 被成交的挂单及其成交前的剩余数量
+type makerUndo struct {
+	entry     *Entry
+	remaining decimal.Decimal
+}
+
+// writer 交易对的写锁，不存在则创建
+func (e *Engine) writer(symbol string) *sync.Mutex {
+	e.writersMu.Lock()
+	defer e.writersMu.Unlock()
+
+	w, ok := e.writers[symbol]
+	if !ok {
+		w = &sync.Mutex{}
+		e.writers[symbol] = w
+	}
+	return w
+}
+
+// Lock 取得交易对的写锁，返回释放函数。
+// 改变订单簿的操作（Submit、Revert、Cancel、Reduce、AddTrigger、CancelTrigger）须在持有写锁时调用，
+// 并在释放前完成落库：同一交易对的撮合与落库由此串行执行，落库失败时可用 Revert 撤销刚才的撮合。
+// Expire 与 Checkpoint 自行取得写锁，调用时不能持有任何交易对的写锁。
+func (e *Engine) Lock(symbol string) func() {
+	w := e.writer(symbol)
+	w.Lock()
+	return w.Unlock
+}
+
+// lockAll 取得全部交易对的写锁，期间不能创建新的写锁
+func (e *Engine) lockAll() func() {
+	e.writersMu.Lock()
+	symbols := make([]string, 0, len(e.writers))
+	for symbol := range e.writers {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		e.writers[symbol].Lock()
+	}
+	return func() {
+		for _, symbol := range symbols {
+			e.writers[symbol].Unlock()
+		}
+		e.writersMu.Unlock()
+	}
+}
+
+// Revert 撤销交易对上紧接着的上一次 Submit：恢复被成交的挂单与触发队列，撤下挂入订单簿的 taker。
+// 用于成交落库失败时使订单簿与数据库保持一致，须在 Submit 之后、释放写锁之前调用
+func (e *Engine) Revert(symbol string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b := e.book(symbol)
+	if b.undo == nil {
+		return ErrNothingToRevert
+	}
+	if err := e.write(&command{Op: opRevert, Symbol: symbol}); err != nil {
+		return err
+	}
+	e.revert(b)
+	e.notify()
+	return nil
+}
+
+func (e *Engine) revert(b *OrderBook) {
+	u := b.undo
+	b.undo = nil
+
+	if u.rested != "" {
+		b.remove(u.rested)
+	}
+	for i := len(u.makers) - 1; i >= 0; i-- {
+		m := u.makers[i]
+		m.entry.Remaining = m.remaining
+		if b.orders[m.entry.OrderID] == m.entry {
+			b.touch(m.entry.Side, m.entry.Price)
+		} else {
+			b.insert(m.entry)
+		}
+	}
+	if u.triggers != nil {
+		tb := e.triggerBook(b.Symbol)
+		tb.lastPrice = u.lastPrice
+		tb.triggers = make(map[string]*trigger, len(u.triggers))
+		for orderID, t := range u.triggers {
+			tb.triggers[orderID] = &t
+		}
+	}
+}
+
+// discard 交易对上执行了 Submit 与 Revert 之外的命令后，之前的 Submit 不再可撤销（调用方需持有锁）
+func (e *Engine) discard(cmd *command) {
+	if cmd.Op == opSubmit || cmd.Op == opRevert {
+		return
+	}
+	symbol := cmd.Symbol
+	if cmd.Order != nil {
+		symbol = cmd.Order.Symbol
+	}
+	for _, b := range e.books {
+		if symbol == "" || b.Symbol == symbol {
+			b.undo = nil
+		}
+	}
+}
+
+// recordMaker 记录挂单成交前的剩余数量（Submit 期间）
+func (b *OrderBook) recordMaker(maker *Entry) {
+	if b.undo != nil {
+		b.undo.makers = append(b.undo.makers, makerUndo{entry: maker, remaining: maker.Remaining})
+	}
+}
+
+// recordTriggers 记录成交前的触发队列（Submit 期间）
+func (u *undoLog) recordTriggers(tb *triggerBook) {
+	u.lastPrice = tb.lastPrice
+	u.triggers = make(map[string]trigger, len(tb.triggers))
+	for orderID, t := range tb.triggers {
+		u.triggers[orderID] = *t
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// AdminMiddleware 管理接口鉴权，校验请求头 X-Admin-Token
+type AdminMiddleware struct {
+	token string
+}
+
+func NewAdminMiddleware(token string) *AdminMiddleware {
+	return &AdminMiddleware{token: token}
+}
+
+func (m *AdminMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Admin-Token")
+		if m.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(m.token)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
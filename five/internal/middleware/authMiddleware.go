@@ -1,19 +1,73 @@
 package middleware
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
 
+	"github.com/zeromicro/go-zero/rest/handler"
+)
+
+// ClaimUserID JWT 中携带用户 ID 的声明
+const ClaimUserID = "userId"
+
+type userIDKey struct{}
+
+// AuthMiddleware 用户接口鉴权：校验请求头 Authorization: Bearer <JWT>，
+// 并将令牌中的用户 ID 写入请求上下文，接口以此为准而不是请求参数
 type AuthMiddleware struct {
+	secret    string
+	authorize func(http.Handler) http.Handler
 }
 
-func NewAuthMiddleware() *AuthMiddleware {
-	return &AuthMiddleware{}
+func NewAuthMiddleware(secret string) *AuthMiddleware {
+	return &AuthMiddleware{
+		secret:    secret,
+		authorize: handler.Authorize(secret),
+	}
 }
 
 func (m *AuthMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	withUser := func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := parseUserID(r.Context().Value(ClaimUserID))
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userIDKey{}, userID)))
+	}
+	authorized := m.authorize(http.HandlerFunc(withUser))
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO generate middleware implement function, delete after code implementation
+		// 未配置密钥时禁用需登录的接口
+		if m.secret == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		authorized.ServeHTTP(w, r)
+	}
+}
+
+// UserID 鉴权通过的请求对应的用户 ID
+func UserID(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	return userID, ok
+}
 
-		// Passthrough to next handler if need
-		next(w, r)
+// parseUserID 解析 JWT 中的用户 ID 声明，数字与字符串形式均可
+func parseUserID(v any) (int64, bool) {
+	var userID int64
+	var err error
+	switch id := v.(type) {
+	case json.Number:
+		userID, err = id.Int64()
+	case float64:
+		userID = int64(id)
+	case string:
+		userID, err = strconv.ParseInt(id, 10, 64)
+	default:
+		return 0, false
 	}
+	return userID, err == nil && userID > 0
 }
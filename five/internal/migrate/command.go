@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"five/internal/config"
+
+	"github.com/zeromicro/go-zero/core/conf"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const usage = `usage: order migrate [-f config] <command> [n]
+
+commands:
+  up [n]     执行 n 个未执行的迁移，省略 n 时执行全部；执行中断的迁移从中断处继续
+  down [n]   回滚最近的 n 个迁移，省略 n 时回滚一个
+  status     列出全部迁移及其执行状态
+  version    输出当前结构版本与最新版本
+`
+
+// RunCommand 执行 migrate 子命令，args 为 migrate 之后的命令行参数
+func RunCommand(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configFile := fs.String("f", "etc/order-api.yaml", "the config file")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	_ = fs.Parse(args)
+
+	if err := run(*configFile, fs.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configFile string, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid step count %q", args[1])
+		}
+		steps = n
+	}
+
+	var c config.Config
+	conf.MustLoad(configFile, &c)
+	if c.Storage.Driver == "sqlite" {
+		// 迁移脚本只适用于 MySQL，SQLite 在服务启动时按模型建表
+		fmt.Printf("storage driver is sqlite: %s is created from the models at startup, nothing to migrate\n", c.Storage.SQLite)
+		return nil
+	}
+	db, err := gorm.Open(mysql.Open(c.MySQL.DSN), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(steps)
+		for _, mig := range done {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		done, err := m.Down(steps)
+		for _, mig := range done {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("no migration to revert")
+		}
+		return err
+	case "status":
+		list, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			applied := "pending"
+			switch {
+			case s.AppliedAt != nil:
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			case s.Interrupted != "":
+				applied = fmt.Sprintf("interrupted during %s after %d statements", s.Interrupted, s.Step)
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	case "version":
+		current, err := m.Current()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current: %d, latest: %d\n", current, m.Latest())
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
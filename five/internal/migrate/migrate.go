@@ -0,0 +1,378 @@
+package migrate
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var scripts embed.FS
+
+const (
+	lockName       = "order.schema_migration" // 迁移期间持有的 MySQL 命名锁
+	lockTimeoutSec = 60                       // 等待其他实例完成迁移的最长时间
+)
+
+// Migration 一个版本的结构变更，文件名格式为 <版本号>_<名称>.up.sql / .down.sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// 迁移版本的执行状态
+const (
+	stateApplied = "applied" // 已执行完成
+	stateUp      = "up"      // up 脚本执行中断
+	stateDown    = "down"    // down 脚本执行中断
+)
+
+// SchemaVersion 已执行或执行中断的迁移版本。执行中断时 Step 为当前脚本已执行的语句数，
+// 再次执行同一方向时从下一条语句继续
+type SchemaVersion struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:200;not null"`
+	State     string    `gorm:"size:10;not null;default:'applied'"`
+	Step      int       `gorm:"not null;default:0"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// done 迁移已执行完成。State 列加入前记录的版本均已完成
+func (v SchemaVersion) done() bool {
+	return v.State == "" || v.State == stateApplied
+}
+
+// Status 迁移版本及其执行状态
+type Status struct {
+	Migration
+	AppliedAt   *time.Time
+	Interrupted string // 执行中断的方向（up 或 down），未中断时为空
+	Step        int    // 执行中断前已执行的语句数
+}
+
+// Migrator 按版本号顺序执行数据库结构迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(scripts)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load 读取内嵌的迁移脚本，每个版本必须同时有 up 与 down 脚本
+func load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: must end with .up.sql or .down.sql", base)
+		}
+		stem := strings.TrimSuffix(base, "."+direction+".sql")
+		prefix, name, ok := strings.Cut(stem, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>", base)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d has conflicting names %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down scripts are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest 内嵌迁移脚本的最新版本号
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current 数据库当前的结构版本，schema_version 表不存在时为 0
+func (m *Migrator) Current() (int64, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	for version, v := range applied {
+		if v.done() {
+			current = max(current, version)
+		}
+	}
+	return current, nil
+}
+
+// Verify 校验数据库结构已迁移到最新版本，服务启动时调用
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		v, ok := applied[mig.Version]
+		if !ok {
+			return fmt.Errorf("schema migration %d_%s is not applied, run `order migrate up`", mig.Version, mig.Name)
+		}
+		if !v.done() {
+			return fmt.Errorf("schema migration %d_%s was interrupted after %d statements, run `order migrate %s` to finish it",
+				mig.Version, mig.Name, v.Step, v.State)
+		}
+	}
+	for version := range applied {
+		if version > m.Latest() {
+			return fmt.Errorf("schema version %d is newer than this binary supports (%d)", version, m.Latest())
+		}
+	}
+	return nil
+}
+
+// Status 列出全部迁移及其执行时间
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if v, ok := applied[mig.Version]; ok && v.done() {
+			s.AppliedAt = &v.AppliedAt
+		} else if ok {
+			s.Interrupted, s.Step = v.State, v.Step
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Up 按版本号升序执行未执行的迁移，steps <= 0 表示全部执行；上次执行中断的迁移从中断处继续。
+// 多个实例同时执行时，后取得迁移锁的实例只执行剩余未执行的迁移
+func (m *Migrator) Up(steps int) (done []Migration, err error) {
+	err = m.withLock(func(locked *Migrator) error {
+		done, err = locked.up(steps)
+		return err
+	})
+	return done, err
+}
+
+func (m *Migrator) up(steps int) ([]Migration, error) {
+	if err := m.db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		v, ok := applied[mig.Version]
+		switch {
+		case ok && v.done():
+			continue
+		case ok && v.State == stateDown:
+			return done, fmt.Errorf("migration %d_%s was interrupted while reverting, run `order migrate down` to finish it",
+				mig.Version, mig.Name)
+		case !ok:
+			v = SchemaVersion{Version: mig.Version, Name: mig.Name, State: stateUp, AppliedAt: time.Now()}
+			if err := m.db.Create(&v).Error; err != nil {
+				return done, err
+			}
+		}
+		if err := m.exec(mig, stateUp, mig.Up, v.Step); err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+		err := m.db.Model(&SchemaVersion{}).Where("version = ?", mig.Version).
+			Updates(map[string]interface{}{"state": stateApplied, "step": 0, "applied_at": time.Now()}).Error
+		if err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down 按版本号降序回滚已执行的迁移，steps <= 0 时回滚一个版本；上次回滚中断的迁移从中断处继续
+func (m *Migrator) Down(steps int) (done []Migration, err error) {
+	err = m.withLock(func(locked *Migrator) error {
+		done, err = locked.down(steps)
+		return err
+	})
+	return done, err
+}
+
+func (m *Migrator) down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	if err := m.db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		v, ok := applied[mig.Version]
+		switch {
+		case !ok:
+			continue
+		case v.State == stateUp:
+			return done, fmt.Errorf("migration %d_%s was interrupted while applying, run `order migrate up` to finish it",
+				mig.Version, mig.Name)
+		case v.done():
+			v.Step = 0
+			err := m.db.Model(&SchemaVersion{}).Where("version = ?", mig.Version).
+				Updates(map[string]interface{}{"state": stateDown, "step": 0}).Error
+			if err != nil {
+				return done, err
+			}
+		}
+		if err := m.exec(mig, stateDown, mig.Down, v.Step); err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		if err := m.db.Delete(&SchemaVersion{}, mig.Version).Error; err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// withLock 在同一数据库连接上持有迁移锁执行 fn，防止多个实例同时迁移。
+// 命名锁随连接关闭自动释放，迁移进程异常退出时不会残留
+func (m *Migrator) withLock(fn func(locked *Migrator) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		var got sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, lockTimeoutSec).Row().Scan(&got); err != nil {
+			return err
+		}
+		if !got.Valid || got.Int64 != 1 {
+			return fmt.Errorf("timed out waiting for migration lock %s", lockName)
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", lockName)
+
+		return fn(&Migrator{db: conn, migrations: m.migrations})
+	})
+}
+
+// applied 查询已执行的迁移版本
+func (m *Migrator) applied() (map[int64]SchemaVersion, error) {
+	applied := make(map[int64]SchemaVersion)
+	if !m.db.Migrator().HasTable(&SchemaVersion{}) {
+		return applied, nil
+	}
+	var rows []SchemaVersion
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// exec 从第 from 条起逐条执行脚本中以分号结尾的语句，每条执行后立即记录进度。
+// MySQL 的 DDL 会隐式提交，无法与进度放入同一事务；执行失败时再次运行从失败的语句继续，
+// 已生效的语句不会重复执行
+func (m *Migrator) exec(mig Migration, state, script string, from int) error {
+	stmts := statements(script)
+	for i := from; i < len(stmts); i++ {
+		if err := m.db.Exec(stmts[i]).Error; err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		err := m.db.Model(&SchemaVersion{}).Where("version = ?", mig.Version).
+			Updates(map[string]interface{}{"state": state, "step": i + 1}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statements 按行尾分号拆分脚本，忽略 -- 注释行与空语句
+func statements(script string) []string {
+	var stmts []string
+	var buf strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if stmt := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(buf.String()), ";")); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			buf.Reset()
+		}
+	}
+	if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
@@ -0,0 +1,149 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestMigrator 在 SQLite 上执行给定脚本的迁移器。迁移锁只适用于 MySQL，测试直接调用 up 与 down
+func newTestMigrator(t *testing.T, files map[string]string) (*Migrator, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys["sql/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Migrator{db: db, migrations: migrations}, db
+}
+
+func status(t *testing.T, m *Migrator, version int64) Status {
+	t.Helper()
+	list, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range list {
+		if s.Version == version {
+			return s
+		}
+	}
+	t.Fatalf("migration %d not found", version)
+	return Status{}
+}
+
+func TestUpResumesAfterFailedStatement(t *testing.T) {
+	m, db := newTestMigrator(t, map[string]string{
+		"0001_create_a.up.sql":   "CREATE TABLE a (id INTEGER);\nINSERT INTO c VALUES (1);\n",
+		"0001_create_a.down.sql": "DROP TABLE a;\n",
+	})
+
+	// 第二条语句失败，第一条已生效并记录
+	if _, err := m.up(0); err == nil || !strings.Contains(err.Error(), "statement 2") {
+		t.Fatalf("got %v, want statement 2 to fail", err)
+	}
+	if s := status(t, m, 1); s.AppliedAt != nil || s.Interrupted != stateUp || s.Step != 1 {
+		t.Fatalf("status %+v, want interrupted during up after 1 statement", s)
+	}
+	if err := m.Verify(); err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("verify: got %v, want interrupted migration", err)
+	}
+	if _, err := m.down(1); err == nil {
+		t.Fatal("reverting a half-applied migration should fail")
+	}
+
+	// 修复后再次执行，从失败的语句继续，不重复建表
+	if err := db.Exec("CREATE TABLE c (id INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.up(0)
+	if err != nil || len(done) != 1 {
+		t.Fatalf("resume: done %v, err %v", done, err)
+	}
+	if s := status(t, m, 1); s.AppliedAt == nil || s.Interrupted != "" {
+		t.Fatalf("status %+v, want applied", s)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := m.Current(); current != 1 {
+		t.Fatalf("current version %d, want 1", current)
+	}
+}
+
+func TestDownResumesAfterFailedStatement(t *testing.T) {
+	m, db := newTestMigrator(t, map[string]string{
+		"0001_create_ab.up.sql":   "CREATE TABLE a (id INTEGER);\nCREATE TABLE b (id INTEGER);\n",
+		"0001_create_ab.down.sql": "DROP TABLE a;\nDROP TABLE b;\n",
+	})
+	if _, err := m.up(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("DROP TABLE b").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.down(1); err == nil {
+		t.Fatal("expected down to fail on the missing table")
+	}
+	if s := status(t, m, 1); s.Interrupted != stateDown || s.Step != 1 {
+		t.Fatalf("status %+v, want interrupted during down after 1 statement", s)
+	}
+	if _, err := m.up(0); err == nil {
+		t.Fatal("applying a half-reverted migration should fail")
+	}
+
+	if err := db.Exec("CREATE TABLE b (id INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.down(1)
+	if err != nil || len(done) != 1 {
+		t.Fatalf("resume: done %v, err %v", done, err)
+	}
+	if current, _ := m.Current(); current != 0 {
+		t.Fatalf("current version %d, want 0", current)
+	}
+}
+
+// 加入执行状态之前记录的版本视为已完成
+func TestLegacyVersionsAreApplied(t *testing.T) {
+	m, db := newTestMigrator(t, map[string]string{
+		"0001_create_a.up.sql":   "CREATE TABLE a (id INTEGER);\n",
+		"0001_create_a.down.sql": "DROP TABLE a;\n",
+		"0002_create_b.up.sql":   "CREATE TABLE b (id INTEGER);\n",
+		"0002_create_b.down.sql": "DROP TABLE b;\n",
+	})
+	for _, stmt := range []string{
+		"CREATE TABLE a (id INTEGER)",
+		"CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at DATETIME NOT NULL)",
+		"INSERT INTO schema_version (version, name, applied_at) VALUES (1, 'create_a', CURRENT_TIMESTAMP)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done, err := m.up(0)
+	if err != nil || len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("done %v, err %v, want only 0002 applied", done, err)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
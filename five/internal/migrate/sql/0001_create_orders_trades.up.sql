@@ -0,0 +1,59 @@
+-- 订单与成交记录
+-- 旧版本启动时按模型自动建表（金额为浮点列），表已存在时本迁移失败而不是沿用旧结构，需先删除旧表
+CREATE TABLE orders (
+    id              BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at      DATETIME(3)     NULL,
+    updated_at      DATETIME(3)     NULL,
+    order_id        VARCHAR(100)    NULL,
+    client_order_id VARCHAR(64)     NOT NULL,
+    user_id         BIGINT          NULL,
+    symbol          VARCHAR(20)     NOT NULL,
+    order_type      VARCHAR(20)     NOT NULL,
+    order_side      VARCHAR(10)     NOT NULL,
+    price           DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    amount          DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    quote_amount    DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    filled_amount   DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    filled_quote    DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    fee             DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    fee_asset       VARCHAR(10)     NULL DEFAULT 'USDT',
+    token_fee       DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    frozen          DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    trigger_type    VARCHAR(20)     NULL DEFAULT '',
+    trigger_price   DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    trailing_offset DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    triggered_at    DATETIME(3)     NULL,
+    time_in_force   VARCHAR(10)     NOT NULL DEFAULT 'GTC',
+    expire_at       DATETIME(3)     NULL,
+    status          VARCHAR(20)     NOT NULL DEFAULT 'pending',
+    cancel_reason   VARCHAR(200)    NULL DEFAULT '',
+    version         BIGINT          NOT NULL DEFAULT 0,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_orders_order_id (order_id),
+    UNIQUE INDEX idx_user_client_order (user_id, client_order_id),
+    INDEX idx_orders_expire_at (expire_at),
+    INDEX idx_orders_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE trades (
+    id               BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at       DATETIME(3)     NULL,
+    trade_id         VARCHAR(100)    NULL,
+    match_id         VARCHAR(100)    NULL,
+    order_id         VARCHAR(100)    NULL,
+    counter_order_id VARCHAR(100)    NULL,
+    user_id          BIGINT          NULL,
+    symbol           VARCHAR(20)     NOT NULL,
+    side             VARCHAR(10)     NULL,
+    price            DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    amount           DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    role             VARCHAR(10)     NOT NULL DEFAULT '',
+    fee_rate         DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    fee              DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    fee_asset        VARCHAR(10)     NULL DEFAULT 'USDT',
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_trades_trade_id (trade_id),
+    INDEX idx_trades_match_id (match_id),
+    INDEX idx_trades_order_id (order_id),
+    INDEX idx_trades_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,17 @@
+-- 交易对规则
+CREATE TABLE instruments (
+    id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at   DATETIME(3)     NULL,
+    updated_at   DATETIME(3)     NULL,
+    symbol       VARCHAR(20)     NULL,
+    base_asset   VARCHAR(10)     NOT NULL,
+    quote_asset  VARCHAR(10)     NOT NULL,
+    price_tick   DECIMAL(36,18)  NOT NULL,
+    qty_step     DECIMAL(36,18)  NOT NULL,
+    min_qty      DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    max_qty      DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    min_notional DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    status       VARCHAR(20)     NOT NULL DEFAULT 'pre_trading',
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_instruments_symbol (symbol)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS fee_settings;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,40 @@
+-- 资产账户、复式记账分录与手续费偏好
+CREATE TABLE accounts (
+    id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at DATETIME(3)     NULL,
+    updated_at DATETIME(3)     NULL,
+    user_id    BIGINT          NOT NULL,
+    asset      VARCHAR(10)     NOT NULL,
+    available  DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    frozen     DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_user_asset (user_id, asset)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE ledger_entries (
+    id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at DATETIME(3)     NULL,
+    journal_id VARCHAR(64)     NOT NULL,
+    entry_type VARCHAR(20)     NOT NULL,
+    user_id    BIGINT          NOT NULL,
+    asset      VARCHAR(10)     NOT NULL,
+    bucket     VARCHAR(10)     NOT NULL,
+    amount     DECIMAL(36,18)  NOT NULL,
+    order_id   VARCHAR(100)    NULL,
+    trade_id   VARCHAR(100)    NULL,
+    remark     VARCHAR(200)    NULL DEFAULT '',
+    PRIMARY KEY (id),
+    INDEX idx_ledger_entries_journal_id (journal_id),
+    INDEX idx_ledger_user_asset (user_id, asset),
+    INDEX idx_ledger_entries_order_id (order_id),
+    INDEX idx_ledger_entries_trade_id (trade_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE fee_settings (
+    id             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    updated_at     DATETIME(3)     NULL,
+    user_id        BIGINT          NOT NULL,
+    pay_with_token BOOLEAN         NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_fee_settings_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
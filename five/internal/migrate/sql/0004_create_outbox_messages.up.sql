@@ -0,0 +1,13 @@
+-- 事务发件箱
+CREATE TABLE outbox_messages (
+    id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at DATETIME(3)     NULL,
+    topic      VARCHAR(100)    NOT NULL,
+    `key`      VARCHAR(100)    NOT NULL,
+    payload    BLOB            NOT NULL,
+    attempts   BIGINT          NOT NULL DEFAULT 0,
+    last_error VARCHAR(500)    NULL DEFAULT '',
+    sent_at    DATETIME(3)     NULL,
+    PRIMARY KEY (id),
+    INDEX idx_outbox_messages_sent_at (sent_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,20 @@
+-- 死信消息
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at     DATETIME(3)     NULL,
+    dead_letter_id VARCHAR(64)     NOT NULL,
+    original_topic VARCHAR(100)    NOT NULL,
+    consumer_group VARCHAR(100)    NOT NULL DEFAULT '',
+    `key`          VARCHAR(100)    NOT NULL DEFAULT '',
+    payload        BLOB            NOT NULL,
+    headers        TEXT            NULL,
+    error          VARCHAR(1000)   NULL DEFAULT '',
+    attempts       BIGINT          NOT NULL DEFAULT 0,
+    failed_at      DATETIME(3)     NULL,
+    replays        BIGINT          NOT NULL DEFAULT 0,
+    replayed_at    DATETIME(3)     NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_dead_letters_dead_letter_id (dead_letter_id),
+    INDEX idx_dead_letters_original_topic (original_topic),
+    INDEX idx_dead_letters_replayed_at (replayed_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
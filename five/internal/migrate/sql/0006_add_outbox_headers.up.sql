@@ -0,0 +1,2 @@
+-- 发件箱消息头：记录事件编码方式等随消息发布的元数据
+ALTER TABLE outbox_messages ADD COLUMN headers TEXT NULL AFTER payload;
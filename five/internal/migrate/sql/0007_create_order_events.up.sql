@@ -0,0 +1,27 @@
+-- 订单事件存储与状态快照
+CREATE TABLE IF NOT EXISTS order_events (
+    sequence    BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id    VARCHAR(64)     NOT NULL,
+    type        VARCHAR(50)     NOT NULL,
+    version     BIGINT          NOT NULL,
+    order_id    VARCHAR(100)    NOT NULL,
+    occurred_at DATETIME(3)     NOT NULL,
+    payload     BLOB            NOT NULL,
+    PRIMARY KEY (sequence),
+    UNIQUE INDEX idx_order_events_event_id (event_id),
+    INDEX idx_order_events_order_id (order_id),
+    INDEX idx_order_events_occurred_at (occurred_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS order_snapshots (
+    id          BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    created_at  DATETIME(3)     NULL,
+    sequence    BIGINT UNSIGNED NOT NULL,
+    occurred_at DATETIME(3)     NOT NULL,
+    orders      BIGINT          NOT NULL DEFAULT 0,
+    trades      BIGINT          NOT NULL DEFAULT 0,
+    data        LONGBLOB        NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_order_snapshots_sequence (sequence),
+    INDEX idx_order_snapshots_occurred_at (occurred_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
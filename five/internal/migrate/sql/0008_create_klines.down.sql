@@ -0,0 +1,3 @@
+ALTER TABLE trades DROP INDEX idx_trades_symbol_created_at;
+DROP TABLE IF EXISTS kline_ranges;
+DROP TABLE IF EXISTS klines;
@@ -0,0 +1,33 @@
+-- K 线与 K 线完整时间段；成交表按交易对与时间建索引，供 K 线回补与行情启动时加载成交
+CREATE TABLE IF NOT EXISTS klines (
+    id             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    updated_at     DATETIME(3)     NULL,
+    symbol         VARCHAR(20)     NOT NULL,
+    period         VARCHAR(4)      NOT NULL,
+    open_time      DATETIME(3)     NOT NULL,
+    close_time     DATETIME(3)     NOT NULL,
+    open           DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    high           DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    low            DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    close          DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    volume         DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    quote_volume   DECIMAL(36,18)  NOT NULL DEFAULT 0,
+    trades         BIGINT          NOT NULL DEFAULT 0,
+    first_trade_at DATETIME(3)     NOT NULL,
+    last_trade_at  DATETIME(3)     NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_klines_symbol_period_open (symbol, period, open_time)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS kline_ranges (
+    id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    updated_at DATETIME(3)     NULL,
+    symbol     VARCHAR(20)     NOT NULL,
+    period     VARCHAR(4)      NOT NULL,
+    start_time DATETIME(3)     NOT NULL,
+    end_time   DATETIME(3)     NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_kline_ranges_symbol_period (symbol, period)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE trades ADD INDEX idx_trades_symbol_created_at (symbol, created_at);
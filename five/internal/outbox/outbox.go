@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// Enqueue 在业务事务内写入一条待发布消息
+func Enqueue(tx *gorm.DB, topic, key string, payload []byte, headers map[string]string) error {
+	return tx.Create(&types.OutboxMessage{
+		Topic:   topic,
+		Key:     key,
+		Payload: payload,
+		Headers: headers,
+	}).Error
+}
+
+const (
+	leaderLock      = "order.outbox_relay" // 转发实例持有的 MySQL 命名锁
+	leaderWaitSec   = 5                    // 每次等待转发锁的秒数，超时后检查是否已退出再继续等待
+	cleanupInterval = time.Minute          // 清理已发送消息的间隔
+	cleanupBatch    = 1000                 // 每批删除的已发送消息数
+)
+
+// Options 发件箱转发参数
+type Options struct {
+	BatchSize    int           // 每批发布的消息数
+	PollInterval time.Duration // 无待发布消息时的轮询间隔
+	MaxBackoff   time.Duration // 发布失败后指数退避的上限
+	Retention    time.Duration // 已发送消息的保留时长，<=0 表示不清理
+}
+
+// Relay 将发件箱中的待发布消息按写入顺序发布到消息总线，成功后标记为已发送。
+// 发布成功但标记失败时消息会被重发，下游需按消息内容幂等处理（至少一次投递）。
+// MySQL 上多个实例同时运行时只有取得转发锁的实例转发，其余实例等待接替，保证同一 Key 的消息按写入顺序发布。
+type Relay struct {
+	db        *gorm.DB
+	publisher bus.Publisher
+	opts      Options
+}
+
+// publishError 发布到消息总线失败，数据库连接仍可用
+type publishError struct {
+	err error
+}
+
+func (e *publishError) Error() string { return e.err.Error() }
+func (e *publishError) Unwrap() error { return e.err }
+
+func NewRelay(db *gorm.DB, publisher bus.Publisher, opts Options) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 200 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.PollInterval {
+		opts.MaxBackoff = opts.PollInterval
+	}
+	return &Relay{db: db, publisher: publisher, opts: opts}
+}
+
+// Run 持续转发发件箱消息，直到 ctx 结束
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		err := r.lead(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("发件箱转发中断，%v 后重新取得转发锁: %v\n", r.opts.PollInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// lead 取得转发锁后在持有锁的连接上转发。连接断开时锁随之释放，由其他实例接替，
+// 原实例在断开的连接上无法再提交。SQLite 只有一个连接且仅用于单机，不加锁
+func (r *Relay) lead(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	if db.Dialector.Name() != "mysql" {
+		return r.relay(ctx, db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := acquire(ctx, conn); err != nil {
+			return err
+		}
+		defer conn.WithContext(context.Background()).Exec("SELECT RELEASE_LOCK(?)", leaderLock)
+		return r.relay(ctx, conn)
+	})
+}
+
+// acquire 等待取得转发锁，直到取得或 ctx 结束
+func acquire(ctx context.Context, conn *gorm.DB) error {
+	for {
+		var got sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", leaderLock, leaderWaitSec).Row().Scan(&got); err != nil {
+			return err
+		}
+		if got.Valid && got.Int64 == 1 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// relay 转发循环。发布失败时指数退避后重试；数据库出错时返回，重新取得连接与转发锁
+func (r *Relay) relay(ctx context.Context, db *gorm.DB) error {
+	backoff := r.opts.PollInterval
+	var cleaned time.Time
+	for {
+		if r.opts.Retention > 0 && time.Since(cleaned) >= cleanupInterval {
+			if err := r.cleanup(db); err != nil {
+				return err
+			}
+			cleaned = time.Now()
+		}
+
+		n, err := r.flush(ctx, db)
+		var perr *publishError
+		if err != nil && !errors.As(err, &perr) {
+			return err
+		}
+		wait := r.opts.PollInterval
+		switch {
+		case err != nil:
+			fmt.Printf("发件箱转发失败，%v 后重试: %v\n", backoff, err)
+			wait = backoff
+			backoff = min(backoff*2, r.opts.MaxBackoff)
+		case n == r.opts.BatchSize:
+			backoff = r.opts.PollInterval
+			continue // 还有积压，立即处理下一批
+		default:
+			backoff = r.opts.PollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// flush 发布一批待发布消息，返回成功发布的条数；发布失败时返回 *publishError
+func (r *Relay) flush(ctx context.Context, db *gorm.DB) (int, error) {
+	sent := 0
+	var publishErr error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var pending []types.OutboxMessage
+		if err := tx.Where("sent_at IS NULL").
+			Order("id ASC").
+			Limit(r.opts.BatchSize).
+			Find(&pending).Error; err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		msgs := make([]bus.Message, len(pending))
+		ids := make([]uint, len(pending))
+		for i, m := range pending {
+			msgs[i] = bus.Message{Topic: m.Topic, Key: []byte(m.Key), Value: m.Payload, Headers: m.Headers}
+			ids[i] = m.ID
+		}
+
+		if publishErr = r.publisher.Publish(ctx, msgs...); publishErr != nil {
+			// 记录失败次数后提交，消息保持待发布状态
+			reason := publishErr.Error()
+			if len(reason) > 500 {
+				reason = reason[:500]
+			}
+			if uerr := tx.Model(&types.OutboxMessage{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": reason,
+			}).Error; uerr != nil {
+				return uerr
+			}
+			return nil
+		}
+
+		now := time.Now()
+		if err := tx.Model(&types.OutboxMessage{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"sent_at":    &now,
+			"last_error": "",
+		}).Error; err != nil {
+			return err
+		}
+		sent = len(pending)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if publishErr != nil {
+		return 0, &publishError{err: publishErr}
+	}
+	return sent, nil
+}
+
+// cleanup 按批删除超过保留时长的已发送消息
+func (r *Relay) cleanup(db *gorm.DB) error {
+	before := time.Now().Add(-r.opts.Retention)
+	for {
+		var ids []uint
+		if err := db.Model(&types.OutboxMessage{}).
+			Where("sent_at < ?", before).
+			Order("id ASC").
+			Limit(cleanupBatch).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := db.Delete(&types.OutboxMessage{}, ids).Error; err != nil {
+			return err
+		}
+		if len(ids) < cleanupBatch {
+			return nil
+		}
+	}
+}
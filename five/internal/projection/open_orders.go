@@ -0,0 +1,70 @@
+package projection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"five/internal/event"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OpenOrders 每个用户未完结订单（含未触发的条件单）的集合
+type OpenOrders struct {
+	rdb *redis.Client
+}
+
+func NewOpenOrders(rdb *redis.Client) *OpenOrders {
+	return &OpenOrders{rdb: rdb}
+}
+
+func (p *OpenOrders) Name() string { return "open_orders" }
+
+func (p *OpenOrders) Handle(ctx context.Context, e *event.Event) error {
+	order := e.Order()
+	if order == nil {
+		return nil
+	}
+	versions := p.versionsKey()
+	set := p.userKey(order.UserID)
+
+	return update(ctx, p.rdb, func(tx *redis.Tx) error {
+		ok, err := newerVersion(ctx, tx, versions, order.OrderID, order.Version)
+		if err != nil || !ok {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, versions, order.OrderID, order.Version)
+			if order.Status.IsFinal() {
+				pipe.SRem(ctx, set, order.OrderID)
+			} else {
+				pipe.SAdd(ctx, set, order.OrderID)
+			}
+			return nil
+		})
+		return err
+	}, versions, set)
+}
+
+func (p *OpenOrders) Reset(ctx context.Context) error {
+	return deleteKeys(ctx, p.rdb, p.Name())
+}
+
+// List 用户未完结订单的订单号
+func (p *OpenOrders) List(ctx context.Context, userID int64) ([]string, error) {
+	ids, err := p.rdb.SMembers(ctx, p.userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (p *OpenOrders) versionsKey() string {
+	return keyPrefix + p.Name() + ":versions"
+}
+
+func (p *OpenOrders) userKey(userID int64) string {
+	return fmt.Sprintf("%s%s:user:%d", keyPrefix, p.Name(), userID)
+}
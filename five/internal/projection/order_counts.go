@@ -0,0 +1,104 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"five/internal/event"
+	"five/internal/types"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fieldTotal 订单总数在用户汇总哈希中的字段名，其余字段为各状态的订单数
+const fieldTotal = "total"
+
+// OrderCountSummary 用户订单数汇总
+type OrderCountSummary struct {
+	UserID   int64                       `json:"user_id"`
+	Total    int64                       `json:"total"`
+	ByStatus map[types.OrderStatus]int64 `json:"by_status"`
+}
+
+// OrderCounts 每个用户的订单总数与各状态订单数
+type OrderCounts struct {
+	rdb *redis.Client
+}
+
+func NewOrderCounts(rdb *redis.Client) *OrderCounts {
+	return &OrderCounts{rdb: rdb}
+}
+
+func (p *OrderCounts) Name() string { return "order_counts" }
+
+func (p *OrderCounts) Handle(ctx context.Context, e *event.Event) error {
+	order := e.Order()
+	if order == nil {
+		return nil
+	}
+	versions := p.key("versions")
+	statuses := p.key("statuses")
+	summary := p.userKey(order.UserID)
+
+	return update(ctx, p.rdb, func(tx *redis.Tx) error {
+		ok, err := newerVersion(ctx, tx, versions, order.OrderID, order.Version)
+		if err != nil || !ok {
+			return err
+		}
+		prev, err := tx.HGet(ctx, statuses, order.OrderID).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, versions, order.OrderID, order.Version)
+			pipe.HSet(ctx, statuses, order.OrderID, string(order.Status))
+			// 首次出现的订单计入总数，状态变化时从原状态转入新状态
+			if prev == "" {
+				pipe.HIncrBy(ctx, summary, fieldTotal, 1)
+			} else if prev != string(order.Status) {
+				pipe.HIncrBy(ctx, summary, prev, -1)
+			}
+			if prev != string(order.Status) {
+				pipe.HIncrBy(ctx, summary, string(order.Status), 1)
+			}
+			return nil
+		})
+		return err
+	}, versions, statuses, summary)
+}
+
+func (p *OrderCounts) Reset(ctx context.Context) error {
+	return deleteKeys(ctx, p.rdb, p.Name())
+}
+
+// Summary 用户订单数汇总
+func (p *OrderCounts) Summary(ctx context.Context, userID int64) (*OrderCountSummary, error) {
+	fields, err := p.rdb.HGetAll(ctx, p.userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrderCountSummary{UserID: userID, ByStatus: make(map[types.OrderStatus]int64)}
+	for field, value := range fields {
+		n, err := parseInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("order count %s: %w", field, err)
+		}
+		if field == fieldTotal {
+			result.Total = n
+		} else if n != 0 {
+			result.ByStatus[types.OrderStatus(field)] = n
+		}
+	}
+	return result, nil
+}
+
+func (p *OrderCounts) key(name string) string {
+	return keyPrefix + p.Name() + ":" + name
+}
+
+func (p *OrderCounts) userKey(userID int64) string {
+	return fmt.Sprintf("%s%s:user:%d", keyPrefix, p.Name(), userID)
+}
@@ -0,0 +1,113 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"five/internal/bus"
+	"five/internal/event"
+)
+
+// Handler 订单事件处理器，根据事件流维护一个读模型。
+// 消息至少投递一次，Handle 必须幂等；返回错误时消息不会被确认，稍后重新处理。
+type Handler interface {
+	Name() string
+	Handle(ctx context.Context, e *event.Event) error
+	// Reset 清空读模型，重建前调用
+	Reset(ctx context.Context) error
+}
+
+// Options 事件消费参数
+type Options struct {
+	Topic string
+	Group string
+	Retry bus.RetryPolicy
+}
+
+// Runner 以消费组订阅订单事件，依次交给全部处理器，全部成功后才提交位点。
+// 处理失败的事件按重试策略进入重试主题，最终进入死信主题。
+// 读模型可通过 Rebuild 清空并重置消费组位点，从最早的事件重新构建。
+type Runner struct {
+	bus      bus.Bus
+	opts     Options
+	handlers []Handler
+
+	mu     sync.Mutex
+	parent context.Context
+	cancel context.CancelFunc
+	done   <-chan struct{}
+}
+
+func NewRunner(b bus.Bus, opts Options, handlers ...Handler) *Runner {
+	return &Runner{bus: b, opts: opts, handlers: handlers}
+}
+
+// Start 订阅事件并在后台消费，直到 ctx 结束
+func (r *Runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parent = ctx
+	return r.start()
+}
+
+// Rebuild 停止消费，清空全部读模型并将消费组重置到最早的位点，然后重新开始消费
+func (r *Runner) Rebuild(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.parent == nil {
+		return errors.New("projection runner is not started")
+	}
+
+	r.stop()
+	for _, h := range r.handlers {
+		if err := h.Reset(ctx); err != nil {
+			return fmt.Errorf("reset projection %s: %w", h.Name(), err)
+		}
+	}
+	if err := r.bus.ResetGroup(ctx, r.opts.Topic, r.opts.Group); err != nil {
+		return err
+	}
+	return r.start()
+}
+
+// start 启动消费，调用方需持有 r.mu
+func (r *Runner) start() error {
+	ctx, cancel := context.WithCancel(r.parent)
+	consumer := bus.NewConsumer(r.bus, r.opts.Topic, r.opts.Group, r.opts.Retry, r.handle)
+	done, err := consumer.Start(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	r.cancel, r.done = cancel, done
+	return nil
+}
+
+// stop 停止消费并等待消费者退出消费组，调用方需持有 r.mu
+func (r *Runner) stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel, r.done = nil, nil
+}
+
+// handle 解码订单事件并依次交给全部处理器。无法解码的消息（含版本高于当前程序支持的事件）不再重试，
+// 直接进入死信主题，升级后可从死信重放
+func (r *Runner) handle(ctx context.Context, msg bus.Message) error {
+	events, err := event.Decode(msg.Value, msg.Headers)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("decode order event: %w", err))
+	}
+	for _, e := range events {
+		for _, h := range r.handlers {
+			if err := h.Handle(ctx, e); err != nil {
+				return fmt.Errorf("%s %s: %s: %w", e.Type, e.OrderID, h.Name(), err)
+			}
+		}
+	}
+	return nil
+}
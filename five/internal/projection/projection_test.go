@@ -0,0 +1,209 @@
+package projection
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/types"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// orderEvent 订单 orderID 在版本 version 时的事件
+func orderEvent(orderID string, userID int64, status types.OrderStatus, version int64) *event.Event {
+	order := event.Order{OrderID: orderID, UserID: userID, Symbol: "BTC/USDT", Status: status, Version: version}
+	switch status {
+	case types.OrderStatusPending, types.OrderStatusUntriggered:
+		return event.New(orderID, &event.OrderAccepted{Order: order})
+	case types.OrderStatusCancelled:
+		return event.New(orderID, &event.OrderCancelled{Order: order})
+	case types.OrderStatusRejected:
+		return event.New(orderID, &event.OrderRejected{Order: order})
+	}
+	return event.New(orderID, &event.OrderFilled{Order: order})
+}
+
+func tradeEvent(tradeID, orderID, counter string, role types.LiquidityRole, price, amount string) *event.Event {
+	return event.New(orderID, &event.TradeExecuted{Trade: event.Trade{
+		TradeID:        tradeID,
+		OrderID:        orderID,
+		CounterOrderID: counter,
+		Symbol:         "BTC/USDT",
+		Price:          dec(price),
+		Amount:         dec(amount),
+		Role:           role,
+	}})
+}
+
+func handle(t *testing.T, h Handler, events ...*event.Event) {
+	t.Helper()
+	for _, e := range events {
+		if err := h.Handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func openOrders(t *testing.T, p *OpenOrders, userID int64) []string {
+	t.Helper()
+	ids, err := p.List(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestOpenOrdersIgnoresStaleEvents(t *testing.T) {
+	p := NewOpenOrders(newTestRedis(t))
+	handle(t, p,
+		orderEvent("o1", 1, types.OrderStatusPending, 1),
+		orderEvent("o2", 1, types.OrderStatusUntriggered, 1),
+		orderEvent("o3", 2, types.OrderStatusPending, 1),
+		orderEvent("o1", 1, types.OrderStatusFilled, 3),
+		// 重复投递与乱序到达的旧版本不会让已完结的订单重新出现
+		orderEvent("o1", 1, types.OrderStatusPartFilled, 2),
+		orderEvent("o1", 1, types.OrderStatusPending, 1),
+	)
+	if got := openOrders(t, p, 1); !reflect.DeepEqual(got, []string{"o2"}) {
+		t.Fatalf("user 1 open orders %v, want [o2]", got)
+	}
+	if got := openOrders(t, p, 2); !reflect.DeepEqual(got, []string{"o3"}) {
+		t.Fatalf("user 2 open orders %v, want [o3]", got)
+	}
+}
+
+func TestOrderCountsFollowStatusChanges(t *testing.T) {
+	p := NewOrderCounts(newTestRedis(t))
+	handle(t, p,
+		orderEvent("o1", 1, types.OrderStatusPending, 1),
+		orderEvent("o2", 1, types.OrderStatusPending, 1),
+		orderEvent("o3", 1, types.OrderStatusRejected, 1),
+		orderEvent("o1", 1, types.OrderStatusPartFilled, 2),
+		orderEvent("o1", 1, types.OrderStatusFilled, 3),
+		orderEvent("o1", 1, types.OrderStatusFilled, 3),
+		orderEvent("o2", 1, types.OrderStatusCancelled, 2),
+		orderEvent("o2", 1, types.OrderStatusPending, 1),
+	)
+	summary, err := p.Summary(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[types.OrderStatus]int64{
+		types.OrderStatusFilled:    1,
+		types.OrderStatusCancelled: 1,
+		types.OrderStatusRejected:  1,
+	}
+	if summary.Total != 3 || !reflect.DeepEqual(summary.ByStatus, want) {
+		t.Fatalf("summary %+v, want 3 orders %v", summary, want)
+	}
+}
+
+func TestSymbolStatsCountsEachMatchOnce(t *testing.T) {
+	p := NewSymbolStats(newTestRedis(t))
+	handle(t, p,
+		tradeEvent("t1-m", "s1", "b1", types.LiquidityMaker, "100", "1"),
+		tradeEvent("t1-t", "b1", "s1", types.LiquidityTaker, "100", "1"),
+		tradeEvent("t1-t", "b1", "s1", types.LiquidityTaker, "100", "1"),
+		tradeEvent("t2-t", "b2", "s2", types.LiquidityTaker, "110", "2"),
+		// 人工成交没有对手方，直接计入
+		tradeEvent("t3", "b3", "", types.LiquidityMaker, "90", "1"),
+		orderEvent("b3", 1, types.OrderStatusFilled, 2),
+	)
+	stat, err := p.Get(context.Background(), "BTC/USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][2]decimal.Decimal{
+		"volume": {stat.Volume, dec("4")},
+		"quote":  {stat.QuoteVolume, dec("410")},
+		"last":   {stat.LastPrice, dec("90")},
+		"high":   {stat.High, dec("110")},
+		"low":    {stat.Low, dec("90")},
+		"avg":    {stat.AvgPrice, dec("102.5")},
+	} {
+		if !got[0].Equal(got[1]) {
+			t.Errorf("%s = %s, want %s", name, got[0], got[1])
+		}
+	}
+	if stat.Trades != 3 {
+		t.Errorf("trades = %d, want 3", stat.Trades)
+	}
+}
+
+func TestRunnerRebuildsFromEarliestEvent(t *testing.T) {
+	rdb := newTestRedis(t)
+	b := bus.NewMemoryBus(2)
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	open := NewOpenOrders(rdb)
+	counts := NewOrderCounts(rdb)
+	runner := NewRunner(b, Options{Topic: "orders", Group: "projection"}, open, counts)
+	if err := runner.Rebuild(ctx); err == nil {
+		t.Fatal("rebuild before start should fail")
+	}
+	if err := runner.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []*event.Event{
+		orderEvent("o1", 1, types.OrderStatusPending, 1),
+		orderEvent("o2", 1, types.OrderStatusPending, 1),
+		orderEvent("o1", 1, types.OrderStatusCancelled, 2),
+	} {
+		data, headers, err := event.Encode(event.Protobuf, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Publish(ctx, bus.Message{Topic: "orders", Key: []byte(e.OrderID), Value: data, Headers: headers}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(stage string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			summary, err := counts.Summary(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Total == 2 && summary.ByStatus[types.OrderStatusCancelled] == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: summary %+v", stage, summary)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := openOrders(t, open, 1); !reflect.DeepEqual(got, []string{"o2"}) {
+			t.Fatalf("%s: open orders %v, want [o2]", stage, got)
+		}
+	}
+	check("consume")
+
+	// 读模型被破坏后重建，结果与首次消费一致，计数不会翻倍
+	rdb.SAdd(ctx, open.userKey(1), "stale")
+	if err := runner.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("rebuild")
+}
@@ -0,0 +1,64 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 读模型的 Redis 键前缀，每个处理器的键为 projection:<处理器名>:...
+const keyPrefix = "projection:"
+
+// maxWatchRetries 乐观锁事务冲突时的最大重试次数
+const maxWatchRetries = 10
+
+// update 以 WATCH 乐观锁执行读-改-写，被监视的键被并发修改时重试
+func update(ctx context.Context, rdb *redis.Client, fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxWatchRetries; i++ {
+		err := rdb.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+// newerVersion 事件中的订单版本是否比已处理过的版本新，用于丢弃重复或过期的事件
+func newerVersion(ctx context.Context, tx *redis.Tx, versionsKey, orderID string, version int64) (bool, error) {
+	seen, err := tx.HGet(ctx, versionsKey, orderID).Int64()
+	if errors.Is(err, redis.Nil) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return version > seen, nil
+}
+
+// deleteKeys 删除处理器的全部读模型键
+func deleteKeys(ctx context.Context, rdb *redis.Client, name string) error {
+	iter := rdb.Scan(ctx, 0, keyPrefix+name+":*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := rdb.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return rdb.Del(ctx, batch...).Err()
+}
+
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
@@ -0,0 +1,130 @@
+package projection
+
+import (
+	"context"
+
+	"five/internal/event"
+	"five/internal/types"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+// SymbolStat 交易对累计成交统计
+type SymbolStat struct {
+	Symbol      string          `json:"symbol"`
+	Trades      int64           `json:"trades"`       // 成交笔数，一次撮合计一笔
+	Volume      decimal.Decimal `json:"volume"`       // 成交量（基础币）
+	QuoteVolume decimal.Decimal `json:"quote_volume"` // 成交额（计价币）
+	LastPrice   decimal.Decimal `json:"last_price"`
+	High        decimal.Decimal `json:"high"`
+	Low         decimal.Decimal `json:"low"`
+	AvgPrice    decimal.Decimal `json:"avg_price"` // 成交均价，成交额 / 成交量
+}
+
+// SymbolStats 每个交易对的成交统计，由 TradeExecuted 事件中的成交记录累计。
+// 撮合成交的 maker 与 taker 各有一条成交记录，只按 taker 一侧计入；人工成交没有对手方，直接计入。
+type SymbolStats struct {
+	rdb *redis.Client
+}
+
+func NewSymbolStats(rdb *redis.Client) *SymbolStats {
+	return &SymbolStats{rdb: rdb}
+}
+
+func (p *SymbolStats) Name() string { return "symbol_stats" }
+
+func (p *SymbolStats) Handle(ctx context.Context, e *event.Event) error {
+	trade := e.Trade()
+	if trade == nil {
+		return nil
+	}
+	if trade.CounterOrderID != "" && trade.Role != types.LiquidityTaker {
+		return nil
+	}
+
+	seen := p.key("trades")
+	statKey := p.key("symbol:" + trade.Symbol)
+	return update(ctx, p.rdb, func(tx *redis.Tx) error {
+		counted, err := tx.SIsMember(ctx, seen, trade.TradeID).Result()
+		if err != nil || counted {
+			return err
+		}
+		stat, err := p.load(ctx, tx, trade.Symbol)
+		if err != nil {
+			return err
+		}
+
+		stat.Trades++
+		stat.Volume = stat.Volume.Add(trade.Amount)
+		stat.QuoteVolume = stat.QuoteVolume.Add(trade.Price.Mul(trade.Amount))
+		stat.LastPrice = trade.Price
+		if stat.High.IsZero() || trade.Price.GreaterThan(stat.High) {
+			stat.High = trade.Price
+		}
+		if stat.Low.IsZero() || trade.Price.LessThan(stat.Low) {
+			stat.Low = trade.Price
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SAdd(ctx, seen, trade.TradeID)
+			pipe.HSet(ctx, statKey,
+				"trades", stat.Trades,
+				"volume", stat.Volume.String(),
+				"quote_volume", stat.QuoteVolume.String(),
+				"last_price", stat.LastPrice.String(),
+				"high", stat.High.String(),
+				"low", stat.Low.String(),
+			)
+			return nil
+		})
+		return err
+	}, seen, statKey)
+}
+
+func (p *SymbolStats) Reset(ctx context.Context) error {
+	return deleteKeys(ctx, p.rdb, p.Name())
+}
+
+// Get 查询交易对成交统计，尚无成交时各项为零
+func (p *SymbolStats) Get(ctx context.Context, symbol string) (*SymbolStat, error) {
+	stat, err := p.load(ctx, p.rdb, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Volume.IsPositive() {
+		stat.AvgPrice = stat.QuoteVolume.Div(stat.Volume)
+	}
+	return stat, nil
+}
+
+func (p *SymbolStats) load(ctx context.Context, c redis.Cmdable, symbol string) (*SymbolStat, error) {
+	fields, err := c.HGetAll(ctx, p.key("symbol:"+symbol)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &SymbolStat{Symbol: symbol}
+	if len(fields) == 0 {
+		return stat, nil
+	}
+	if stat.Trades, err = parseInt(fields["trades"]); err != nil {
+		return nil, err
+	}
+	for name, dst := range map[string]*decimal.Decimal{
+		"volume":       &stat.Volume,
+		"quote_volume": &stat.QuoteVolume,
+		"last_price":   &stat.LastPrice,
+		"high":         &stat.High,
+		"low":          &stat.Low,
+	} {
+		if *dst, err = decimal.NewFromString(fields[name]); err != nil {
+			return nil, err
+		}
+	}
+	return stat, nil
+}
+
+func (p *SymbolStats) key(name string) string {
+	return keyPrefix + p.Name() + ":" + name
+}
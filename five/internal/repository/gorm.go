@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"five/internal/account"
+	"five/internal/event"
+	"five/internal/eventstore"
+	"five/internal/outbox"
+	"five/internal/types"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// gormStore 基于 gorm 的实现，MySQL 与 SQLite 共用
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 基于 gorm 连接创建仓储，数据库结构需已迁移到最新版本
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Orders() OrderRepository     { return gormOrders{s.db} }
+func (s *gormStore) Trades() TradeRepository     { return gormTrades{s.db} }
+func (s *gormStore) Accounts() AccountRepository { return gormAccounts{s.db} }
+func (s *gormStore) Outbox() OutboxRepository    { return gormOutbox{s.db} }
+func (s *gormStore) Events() EventRepository     { return gormEvents{s.db} }
+
+func (s *gormStore) Transaction(fn func(tx Store) error) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+	if isLockConflict(err) {
+		return fmt.Errorf("%w: %v", ErrLockConflict, err)
+	}
+	return err
+}
+
+// MySQL 的死锁（事务已被回滚）与等待行锁超时错误码
+const (
+	mysqlDeadlock        = 1213
+	mysqlLockWaitTimeout = 1205
+)
+
+func isLockConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout
+}
+
+type gormOrders struct {
+	db *gorm.DB
+}
+
+func (r gormOrders) Create(order *types.Order) error {
+	return r.db.Create(order).Error
+}
+
+// Save 更新全部字段（包括零值，如释放后的冻结资金）
+func (r gormOrders) Save(order *types.Order) error {
+	version := order.Version
+	order.Version++
+	result := r.db.Model(order).
+		Where("order_id = ? AND version = ?", order.OrderID, version).
+		Select("*").Omit("id", "created_at").
+		Updates(order)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = fmt.Errorf("order %s version %d: %w", order.OrderID, version, types.ErrVersionConflict)
+	}
+	if result.Error != nil {
+		order.Version = version
+	}
+	return result.Error
+}
+
+func (r gormOrders) Get(orderID string) (*types.Order, error) {
+	var order types.Order
+	if err := r.db.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r gormOrders) GetByClientID(userID int64, clientOrderID string) (*types.Order, error) {
+	var order types.Order
+	if err := r.db.Where("user_id = ? AND client_order_id = ?", userID, clientOrderID).
+		First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r gormOrders) ListByUser(userID int64, status types.OrderStatus) ([]types.Order, error) {
+	var orders []types.Order
+	query := r.db.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r gormOrders) ListByStatus(statuses ...types.OrderStatus) ([]types.Order, error) {
+	var orders []types.Order
+	if err := r.db.Where("status IN ?", statuses).Order("id ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+type gormTrades struct {
+	db *gorm.DB
+}
+
+func (r gormTrades) Create(trade *types.Trade) error {
+	return r.db.Create(trade).Error
+}
+
+func (r gormTrades) ListByOrder(orderID string) ([]types.Trade, error) {
+	var trades []types.Trade
+	if err := r.db.Where("order_id = ?", orderID).Order("created_at ASC").Find(&trades).Error; err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+func (r gormTrades) Volume(userID int64, quoteAsset string, since time.Time) (decimal.Decimal, error) {
+	var volume decimal.NullDecimal
+	if err := r.db.Model(&types.Trade{}).
+		Select("SUM(price * amount)").
+		Where("user_id = ? AND symbol LIKE ? AND created_at >= ?", userID, "%/"+quoteAsset, since).
+		Row().Scan(&volume); err != nil {
+		return decimal.Zero, err
+	}
+	return volume.Decimal, nil
+}
+
+type gormAccounts struct {
+	db *gorm.DB
+}
+
+func (r gormAccounts) Post(j account.Journal) error {
+	return account.Post(r.db, j)
+}
+
+func (r gormAccounts) Get(userID int64, asset string) (*types.Account, error) {
+	return account.Get(r.db, userID, asset)
+}
+
+func (r gormAccounts) Lock(keys ...account.Key) error {
+	return account.Lock(r.db, keys...)
+}
+
+func (r gormAccounts) PaysFeeInToken(userID int64) (bool, error) {
+	return account.PaysFeeInToken(r.db, userID)
+}
+
+type gormOutbox struct {
+	db *gorm.DB
+}
+
+func (r gormOutbox) Enqueue(topic, key string, payload []byte, headers map[string]string) error {
+	return outbox.Enqueue(r.db, topic, key, payload, headers)
+}
+
+type gormEvents struct {
+	db *gorm.DB
+}
+
+func (r gormEvents) Append(e *event.Event) error {
+	return eventstore.Append(r.db, e)
+}
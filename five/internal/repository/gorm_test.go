@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"five/internal/types"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+)
+
+func TestTransactionReportsLockConflict(t *testing.T) {
+	db, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewGormStore(db)
+
+	for _, number := range []uint16{mysqlDeadlock, mysqlLockWaitTimeout} {
+		err := store.Transaction(func(tx Store) error {
+			return &mysql.MySQLError{Number: number, Message: "lock"}
+		})
+		if !errors.Is(err, ErrLockConflict) {
+			t.Fatalf("error %d: got %v, want ErrLockConflict", number, err)
+		}
+	}
+
+	// 其他错误原样返回
+	err = store.Transaction(func(tx Store) error {
+		return &mysql.MySQLError{Number: 1062, Message: "duplicate"}
+	})
+	if errors.Is(err, ErrLockConflict) {
+		t.Fatalf("duplicate key reported as lock conflict: %v", err)
+	}
+}
+
+// 成交额只统计以指定计价币计价的交易对，不同计价币的金额不相加
+func TestVolumeCountsOneQuoteAsset(t *testing.T) {
+	db, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for name, store := range map[string]Store{"gorm": NewGormStore(db), "memory": NewMemoryStore()} {
+		for i, trade := range []types.Trade{
+			{Symbol: "BTC/USDT", UserID: 1, Price: decimal.RequireFromString("100"), Amount: decimal.RequireFromString("2")},
+			{Symbol: "ETH/USDT", UserID: 1, Price: decimal.RequireFromString("10"), Amount: decimal.RequireFromString("3")},
+			{Symbol: "ETH/BTC", UserID: 1, Price: decimal.RequireFromString("0.05"), Amount: decimal.RequireFromString("100")},
+			{Symbol: "BTC/USDT", UserID: 2, Price: decimal.RequireFromString("100"), Amount: decimal.RequireFromString("1")},
+			{Symbol: "BTC/USDT", UserID: 1, Price: decimal.RequireFromString("100"), Amount: decimal.RequireFromString("1"), CreatedAt: now.Add(-48 * time.Hour)},
+		} {
+			trade.TradeID = fmt.Sprintf("t%d", i)
+			trade.OrderID = fmt.Sprintf("o%d", i)
+			if trade.CreatedAt.IsZero() {
+				trade.CreatedAt = now
+			}
+			if err := store.Trades().Create(&trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for asset, want := range map[string]string{"USDT": "230", "BTC": "5", "ETH": "0"} {
+			volume, err := store.Trades().Volume(1, asset, now.Add(-24*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !volume.Equal(decimal.RequireFromString(want)) {
+				t.Errorf("%s: %s volume %s, want %s", name, asset, volume, want)
+			}
+		}
+	}
+}
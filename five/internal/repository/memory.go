@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"five/internal/account"
+	"five/internal/event"
+	"five/internal/eventstore"
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// memoryState 内存仓储的全部数据
+type memoryState struct {
+	nextID      uint
+	orders      map[string]types.Order // 按订单号
+	clientIndex map[clientKey]string   // 用户与客户端订单号 -> 订单号
+	trades      []types.Trade
+	accounts    map[accountKey]types.Account
+	entries     []types.LedgerEntry
+	feeToken    map[int64]bool
+	outbox      []types.OutboxMessage
+	events      []types.EventRecord
+}
+
+type clientKey struct {
+	userID        int64
+	clientOrderID string
+}
+
+type accountKey struct {
+	userID int64
+	asset  string
+}
+
+func (st *memoryState) clone() *memoryState {
+	c := &memoryState{
+		nextID:      st.nextID,
+		orders:      make(map[string]types.Order, len(st.orders)),
+		clientIndex: make(map[clientKey]string, len(st.clientIndex)),
+		trades:      append([]types.Trade(nil), st.trades...),
+		accounts:    make(map[accountKey]types.Account, len(st.accounts)),
+		entries:     append([]types.LedgerEntry(nil), st.entries...),
+		feeToken:    make(map[int64]bool, len(st.feeToken)),
+		outbox:      append([]types.OutboxMessage(nil), st.outbox...),
+		events:      append([]types.EventRecord(nil), st.events...),
+	}
+	for k, v := range st.orders {
+		c.orders[k] = v
+	}
+	for k, v := range st.clientIndex {
+		c.clientIndex[k] = v
+	}
+	for k, v := range st.accounts {
+		c.accounts[k] = v
+	}
+	for k, v := range st.feeToken {
+		c.feeToken[k] = v
+	}
+	return c
+}
+
+func (st *memoryState) id() uint {
+	st.nextID++
+	return st.nextID
+}
+
+// MemoryStore 进程内实现，用于离线单元测试。
+// 事务在数据副本上执行，成功后整体替换，失败时丢弃副本；同一时间只执行一个事务。
+type MemoryStore struct {
+	mu    *sync.Mutex
+	state *memoryState
+	inTx  bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu: &sync.Mutex{},
+		state: &memoryState{
+			orders:      make(map[string]types.Order),
+			clientIndex: make(map[clientKey]string),
+			accounts:    make(map[accountKey]types.Account),
+			feeToken:    make(map[int64]bool),
+		},
+	}
+}
+
+func (s *MemoryStore) Orders() OrderRepository     { return memoryOrders{s} }
+func (s *MemoryStore) Trades() TradeRepository     { return memoryTrades{s} }
+func (s *MemoryStore) Accounts() AccountRepository { return memoryAccounts{s} }
+func (s *MemoryStore) Outbox() OutboxRepository    { return memoryOutbox{s} }
+func (s *MemoryStore) Events() EventRepository     { return memoryEvents{s} }
+
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	// 嵌套事务并入外层事务
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &MemoryStore{mu: s.mu, state: s.state.clone(), inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.state = tx.state
+	return nil
+}
+
+// SetFeeToken 设置用户是否使用平台币抵扣手续费
+func (s *MemoryStore) SetFeeToken(userID int64, enabled bool) {
+	defer s.lock()()
+	s.state.feeToken[userID] = enabled
+}
+
+// Entries 已写入的全部账本分录
+func (s *MemoryStore) Entries() []types.LedgerEntry {
+	defer s.lock()()
+	return append([]types.LedgerEntry(nil), s.state.entries...)
+}
+
+// OutboxMessages 已写入的全部发件箱消息
+func (s *MemoryStore) OutboxMessages() []types.OutboxMessage {
+	defer s.lock()()
+	return append([]types.OutboxMessage(nil), s.state.outbox...)
+}
+
+// EventRecords 已写入的全部订单事件
+func (s *MemoryStore) EventRecords() []types.EventRecord {
+	defer s.lock()()
+	return append([]types.EventRecord(nil), s.state.events...)
+}
+
+// lock 事务外的操作加锁，事务内已持有锁
+func (s *MemoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+type memoryOrders struct {
+	s *MemoryStore
+}
+
+func (r memoryOrders) Create(order *types.Order) error {
+	defer r.s.lock()()
+	st := r.s.state
+
+	key := clientKey{order.UserID, order.ClientOrderID}
+	if _, ok := st.orders[order.OrderID]; ok {
+		return fmt.Errorf("order %s: %w", order.OrderID, ErrDuplicate)
+	}
+	if _, ok := st.clientIndex[key]; ok {
+		return fmt.Errorf("client order %s: %w", order.ClientOrderID, ErrDuplicate)
+	}
+
+	now := time.Now()
+	order.ID = st.id()
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+	if order.UpdatedAt.IsZero() {
+		order.UpdatedAt = now
+	}
+	st.orders[order.OrderID] = *order
+	st.clientIndex[key] = order.OrderID
+	return nil
+}
+
+func (r memoryOrders) Save(order *types.Order) error {
+	defer r.s.lock()()
+	st := r.s.state
+
+	stored, ok := st.orders[order.OrderID]
+	if !ok || stored.Version != order.Version {
+		return fmt.Errorf("order %s version %d: %w", order.OrderID, order.Version, types.ErrVersionConflict)
+	}
+	order.Version++
+	order.ID = stored.ID
+	order.CreatedAt = stored.CreatedAt
+	st.orders[order.OrderID] = *order
+	return nil
+}
+
+func (r memoryOrders) Get(orderID string) (*types.Order, error) {
+	defer r.s.lock()()
+	order, ok := r.s.state.orders[orderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &order, nil
+}
+
+func (r memoryOrders) GetByClientID(userID int64, clientOrderID string) (*types.Order, error) {
+	defer r.s.lock()()
+	orderID, ok := r.s.state.clientIndex[clientKey{userID, clientOrderID}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	order := r.s.state.orders[orderID]
+	return &order, nil
+}
+
+func (r memoryOrders) ListByUser(userID int64, status types.OrderStatus) ([]types.Order, error) {
+	orders := r.filter(func(o *types.Order) bool {
+		return o.UserID == userID && (status == "" || o.Status == status)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+	return orders, nil
+}
+
+func (r memoryOrders) ListByStatus(statuses ...types.OrderStatus) ([]types.Order, error) {
+	orders := r.filter(func(o *types.Order) bool {
+		for _, status := range statuses {
+			if o.Status == status {
+				return true
+			}
+		}
+		return false
+	})
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders, nil
+}
+
+func (r memoryOrders) filter(match func(o *types.Order) bool) []types.Order {
+	defer r.s.lock()()
+	var orders []types.Order
+	for _, o := range r.s.state.orders {
+		if match(&o) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+type memoryTrades struct {
+	s *MemoryStore
+}
+
+func (r memoryTrades) Create(trade *types.Trade) error {
+	defer r.s.lock()()
+	st := r.s.state
+
+	for _, t := range st.trades {
+		if t.TradeID == trade.TradeID {
+			return fmt.Errorf("trade %s: %w", trade.TradeID, ErrDuplicate)
+		}
+	}
+	trade.ID = st.id()
+	if trade.CreatedAt.IsZero() {
+		trade.CreatedAt = time.Now()
+	}
+	st.trades = append(st.trades, *trade)
+	return nil
+}
+
+func (r memoryTrades) ListByOrder(orderID string) ([]types.Trade, error) {
+	defer r.s.lock()()
+	var trades []types.Trade
+	for _, t := range r.s.state.trades {
+		if t.OrderID == orderID {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].CreatedAt.Before(trades[j].CreatedAt) })
+	return trades, nil
+}
+
+func (r memoryTrades) Volume(userID int64, quoteAsset string, since time.Time) (decimal.Decimal, error) {
+	defer r.s.lock()()
+	volume := decimal.Zero
+	for _, t := range r.s.state.trades {
+		if t.UserID == userID && strings.HasSuffix(t.Symbol, "/"+quoteAsset) && !t.CreatedAt.Before(since) {
+			volume = volume.Add(t.Price.Mul(t.Amount))
+		}
+	}
+	return volume, nil
+}
+
+type memoryAccounts struct {
+	s *MemoryStore
+}
+
+// Post 与 account.Post 规则一致；单条变动失败时整组分录不生效
+func (r memoryAccounts) Post(j account.Journal) error {
+	if err := j.Check(); err != nil {
+		return err
+	}
+
+	defer r.s.lock()()
+	st := r.s.state
+	entries := j.Entries()
+	changed := make(map[accountKey]types.Account, len(entries))
+	for _, e := range entries {
+		key := accountKey{e.UserID, e.Asset}
+		acct, ok := changed[key]
+		if !ok {
+			acct = r.get(key)
+		}
+		if err := account.Apply(&acct, account.Posting{UserID: e.UserID, Asset: e.Asset, Bucket: e.Bucket, Amount: e.Amount}); err != nil {
+			return err
+		}
+		changed[key] = acct
+	}
+
+	now := time.Now()
+	for key, acct := range changed {
+		if acct.ID == 0 {
+			acct.ID = st.id()
+			acct.CreatedAt = now
+		}
+		acct.UpdatedAt = now
+		st.accounts[key] = acct
+	}
+	for _, e := range entries {
+		e.ID = st.id()
+		e.CreatedAt = now
+		st.entries = append(st.entries, e)
+	}
+	return nil
+}
+
+func (r memoryAccounts) Get(userID int64, asset string) (*types.Account, error) {
+	defer r.s.lock()()
+	acct := r.get(accountKey{userID, asset})
+	return &acct, nil
+}
+
+func (r memoryAccounts) get(key accountKey) types.Account {
+	if acct, ok := r.s.state.accounts[key]; ok {
+		return acct
+	}
+	return types.Account{UserID: key.userID, Asset: key.asset}
+}
+
+// Lock 内存实现的事务已串行执行，无需锁定
+func (r memoryAccounts) Lock(keys ...account.Key) error {
+	return nil
+}
+
+func (r memoryAccounts) PaysFeeInToken(userID int64) (bool, error) {
+	defer r.s.lock()()
+	return r.s.state.feeToken[userID], nil
+}
+
+type memoryOutbox struct {
+	s *MemoryStore
+}
+
+func (r memoryOutbox) Enqueue(topic, key string, payload []byte, headers map[string]string) error {
+	defer r.s.lock()()
+	st := r.s.state
+	st.outbox = append(st.outbox, types.OutboxMessage{
+		ID:        st.id(),
+		CreatedAt: time.Now(),
+		Topic:     topic,
+		Key:       key,
+		Payload:   payload,
+		Headers:   headers,
+	})
+	return nil
+}
+
+type memoryEvents struct {
+	s *MemoryStore
+}
+
+func (r memoryEvents) Append(e *event.Event) error {
+	record, err := eventstore.NewRecord(e)
+	if err != nil {
+		return err
+	}
+	defer r.s.lock()()
+	st := r.s.state
+	record.Sequence = uint64(st.id())
+	st.events = append(st.events, *record)
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"five/internal/account"
+	"five/internal/event"
+	"five/internal/types"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 各实现统一返回的错误，与 gorm 的错误相同，便于调用方用 errors.Is 判断
+var (
+	ErrNotFound  = gorm.ErrRecordNotFound
+	ErrDuplicate = gorm.ErrDuplicatedKey
+)
+
+// ErrLockConflict 事务因死锁被回滚或等待行锁超时，整个事务可以重试
+var ErrLockConflict = errors.New("transaction aborted by lock conflict")
+
+// OrderRepository 订单存取
+type OrderRepository interface {
+	// Create 写入新订单，订单号或用户的客户端订单号重复时返回 ErrDuplicate
+	Create(order *types.Order) error
+	// Save 按订单号更新订单全部字段，以乐观锁校验版本号并在成功后加一，
+	// 订单已被并发修改时返回 types.ErrVersionConflict
+	Save(order *types.Order) error
+	// Get 按订单号查询，不存在时返回 ErrNotFound
+	Get(orderID string) (*types.Order, error)
+	// GetByClientID 按用户与客户端订单号查询，不存在时返回 ErrNotFound
+	GetByClientID(userID int64, clientOrderID string) (*types.Order, error)
+	// ListByUser 按创建时间倒序查询用户订单，status 为空时不过滤
+	ListByUser(userID int64, status types.OrderStatus) ([]types.Order, error)
+	// ListByStatus 按写入顺序查询处于指定状态的订单
+	ListByStatus(statuses ...types.OrderStatus) ([]types.Order, error)
+}
+
+// TradeRepository 成交记录存取
+type TradeRepository interface {
+	Create(trade *types.Trade) error
+	// ListByOrder 按成交时间升序查询订单的成交记录
+	ListByOrder(orderID string) ([]types.Trade, error)
+	// Volume 用户自 since 起在以 quoteAsset 计价的交易对上的成交额（成交价 × 数量之和）
+	Volume(userID int64, quoteAsset string, since time.Time) (decimal.Decimal, error)
+}
+
+// AccountRepository 订单流程中用到的资产账户操作
+type AccountRepository interface {
+	// Post 记一组借贷平衡的分录并调整余额，用户余额不足时返回 account.ErrInsufficientBalance
+	Post(j account.Journal) error
+	// Get 锁定并查询用户单个币种的账户，不存在时返回零余额账户
+	Get(userID int64, asset string) (*types.Account, error)
+	// Lock 按 user_id、asset 顺序锁定一组账户，同一事务要记多组分录时先调用
+	Lock(keys ...account.Key) error
+	// PaysFeeInToken 用户是否开启平台币抵扣手续费
+	PaysFeeInToken(userID int64) (bool, error)
+}
+
+// OutboxRepository 发件箱写入
+type OutboxRepository interface {
+	Enqueue(topic, key string, payload []byte, headers map[string]string) error
+}
+
+// EventRepository 订单事件存储写入，事件按写入顺序分配全局序号
+type EventRepository interface {
+	Append(e *event.Event) error
+}
+
+// Store 订单逻辑依赖的全部仓储。Transaction 内传入的 tx 上的仓储操作在同一事务内提交或回滚，
+// 事务因死锁或等待行锁超时失败时返回 ErrLockConflict。
+type Store interface {
+	Orders() OrderRepository
+	Trades() TradeRepository
+	Accounts() AccountRepository
+	Outbox() OutboxRepository
+	Events() EventRepository
+	Transaction(fn func(tx Store) error) error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"five/internal/types"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// OpenSQLite 打开 SQLite 数据库并按模型建表，用于单机开发与离线测试。
+// path 为 ":memory:" 时数据只保存在进程内。SQLite 不支持 MySQL 的迁移脚本，
+// 表结构直接由模型生成。
+func OpenSQLite(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite 同一时间只允许一个写事务，单连接避免 database is locked 错误
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(&types.Order{}, &types.Trade{}, &types.Instrument{}, &types.Account{},
+		&types.LedgerEntry{}, &types.FeeSetting{}, &types.OutboxMessage{}, &types.DeadLetter{},
+		&types.EventRecord{}, &types.OrderSnapshot{}, &types.Kline{}, &types.KlineRange{})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewSQLiteStore 打开 SQLite 数据库并创建仓储
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGormStore(db), nil
+}
@@ -1,40 +1,102 @@
 package svc
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"five/internal/bus"
 	"five/internal/config"
+	"five/internal/deadletter"
+	"five/internal/event"
+	"five/internal/eventstore"
+	"five/internal/fee"
+	"five/internal/instrument"
+	"five/internal/kline"
+	"five/internal/market"
+	"five/internal/matching"
 	"five/internal/middleware"
+	"five/internal/migrate"
+	"five/internal/outbox"
+	"five/internal/projection"
+	"five/internal/repository"
 	"five/internal/types"
+	"five/internal/userstream"
+	"five/internal/ws"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
 	"github.com/zeromicro/go-zero/rest"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 type ServiceContext struct {
-	Config    config.Config
-	Auth      rest.Middleware
-	Log       rest.Middleware
-	MySQL     *gorm.DB
-	Redis     *redis.Client
-	KafkaProd *kafka.Writer
-	KafkaCons *kafka.Reader
+	Config      config.Config
+	Auth        rest.Middleware
+	Log         rest.Middleware
+	Admin       rest.Middleware
+	MySQL       *gorm.DB // Storage.Driver 为 sqlite 时为 SQLite 连接
+	Store       repository.Store
+	Redis       *redis.Client
+	Bus         bus.Bus
+	Events      event.Codec // 订单事件编码方式
+	Matcher     *matching.Engine
+	Instruments instrument.Catalog
+	Fees        *fee.Schedule
+	Outbox      *outbox.Relay
+	Projections *projection.Runner
+	DeadLetters *deadletter.Sink
+	Snapshots   *eventstore.Snapshotter
+	Market      *market.Gateway
+	UserStream  *userstream.Stream
+	Klines      *kline.Aggregator
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
-	// 初始化MySQL
-	db, err := gorm.Open(mysql.Open(c.MySQL.DSN), &gorm.Config{})
+	// 初始化数据库
+	db, err := OpenDB(c)
 	if err != nil {
-		panic("failed to connect database")
+		panic("failed to open database: " + err.Error())
+	}
+	store := repository.NewGormStore(db)
+
+	// 初始化撮合引擎
+	matcher := matching.NewEngine(matching.Options{
+		MaxSlippage:     decimal.NewFromFloat(c.Matching.MaxSlippage),
+		PostOnlyReprice: c.Matching.PostOnlyReprice,
+		PriceTick:       decimal.NewFromFloat(c.Matching.PriceTick),
+		AmountScale:     c.Matching.AmountScale,
+	})
+
+	// 加载交易对配置，同步到撮合引擎
+	instruments := instrument.NewRegistry(db)
+	if err := instruments.SeedDefaults(); err != nil {
+		panic("failed to seed instruments: " + err.Error())
+	}
+	if err := instruments.Load(); err != nil {
+		panic("failed to load instruments: " + err.Error())
+	}
+
+	// 从预写日志恢复订单簿与触发队列，与数据库核对无误后才开始接受新订单
+	if err := recoverMatcher(matcher, store.Orders(), c); err != nil {
+		panic("failed to recover matching engine: " + err.Error())
+	}
+	for _, inst := range instruments.List("") {
+		if err := matcher.Configure(inst.Symbol, inst.PriceTick, inst.QtyStep); err != nil {
+			panic("failed to configure matching engine: " + err.Error())
+		}
+	}
+	// 写入恢复后的快照并开始记录日志
+	if _, err := matcher.Checkpoint(); err != nil {
+		panic("failed to start matching journal: " + err.Error())
 	}
 
-	// 自动迁移数据库表 - 先删除表再重新创建
-	db.Exec("DROP TABLE IF EXISTS trades")
-	db.Exec("DROP TABLE IF EXISTS orders")
-	err = db.AutoMigrate(&types.Order{}, &types.Trade{})
+	// 初始化手续费率表
+	fees, err := newFeeSchedule(store.Trades(), c)
 	if err != nil {
-		panic("failed to migrate database: " + err.Error())
+		panic("invalid fee schedule: " + err.Error())
 	}
 
 	// 初始化Redis
@@ -44,29 +106,260 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		DB:       c.Redis.DB,
 	})
 
-	// 初始化Kafka生产者
-	producer := &kafka.Writer{
-		Addr:     kafka.TCP(c.Kafka.Brokers...),
-		Topic:    "orders",
-		Balancer: &kafka.LeastBytes{},
+	// 订单事件编码方式
+	codec, err := event.NewCodec(c.Event.Encoding)
+	if err != nil {
+		panic(err)
 	}
 
-	// 初始化Kafka消费者
-	consumer := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  c.Kafka.Brokers,
-		Topic:    "orders",
-		GroupID:  "order-group",
-		MinBytes: 10e3, // 10KB
-		MaxBytes: 10e6, // 10MB
-	})
+	// 初始化消息总线
+	var eventBus bus.Bus
+	if c.Bus.Driver == "memory" {
+		eventBus = bus.NewMemoryBus(c.Bus.Partitions)
+	} else {
+		eventBus = bus.NewKafkaBus(c.Kafka.Brokers)
+	}
 
 	return &ServiceContext{
-		Config:    c,
-		Auth:      middleware.NewAuthMiddleware().Handle,
-		Log:       middleware.NewLogMiddleware().Handle,
-		MySQL:     db,
-		Redis:     rdb,
-		KafkaProd: producer,
-		KafkaCons: consumer,
+		Config:      c,
+		Auth:        middleware.NewAuthMiddleware(c.Auth.AccessSecret).Handle,
+		Log:         middleware.NewLogMiddleware().Handle,
+		Admin:       middleware.NewAdminMiddleware(c.Admin.Token).Handle,
+		MySQL:       db,
+		Store:       store,
+		Redis:       rdb,
+		Bus:         eventBus,
+		Events:      codec,
+		Matcher:     matcher,
+		Instruments: instruments,
+		Fees:        fees,
+		Outbox: outbox.NewRelay(db, eventBus, outbox.Options{
+			BatchSize:    c.Outbox.BatchSize,
+			PollInterval: time.Duration(c.Outbox.PollIntervalMs) * time.Millisecond,
+			MaxBackoff:   time.Duration(c.Outbox.MaxBackoffMs) * time.Millisecond,
+			Retention:    time.Duration(c.Outbox.RetentionHours) * time.Hour,
+		}),
+		Projections: projection.NewRunner(eventBus, projection.Options{
+			Topic: c.Kafka.Topic,
+			Group: c.Kafka.Group,
+			Retry: retryPolicy(c),
+		},
+			projection.NewOpenOrders(rdb),
+			projection.NewOrderCounts(rdb),
+			projection.NewSymbolStats(rdb),
+		),
+		DeadLetters: deadletter.NewSink(db, eventBus, deadletter.Options{
+			Topic:      c.Kafka.Topic,
+			Group:      c.Kafka.Group + ".dlq",
+			Backoff:    time.Duration(c.Projection.RetryBackoffMs) * time.Millisecond,
+			MaxBackoff: time.Duration(c.Projection.MaxBackoffMs) * time.Millisecond,
+		}),
+		Snapshots: eventstore.NewSnapshotter(db, eventstore.SnapshotOptions{
+			Interval:  time.Duration(c.EventStore.SnapshotIntervalSec) * time.Second,
+			MinEvents: int64(c.EventStore.SnapshotMinEvents),
+			Keep:      c.EventStore.SnapshotKeep,
+			Settle:    time.Duration(c.EventStore.SnapshotSettleSec) * time.Second,
+		}),
+		Market: market.NewGateway(db, matcher, instruments, eventBus, market.Options{
+			Topic:            c.Kafka.Topic,
+			Group:            instanceGroup(c, "market"),
+			DepthLevels:      c.Market.DepthLevels,
+			TickerInterval:   time.Duration(c.Market.TickerIntervalMs) * time.Millisecond,
+			UpdateBuffer:     c.Market.UpdateBuffer,
+			MaxSubscriptions: c.Market.MaxSubscriptions,
+			Conn:             wsOptions(c),
+		}),
+		UserStream: userstream.NewStream(db, instruments, eventBus,
+			userstream.NewListenKeys(rdb, time.Duration(c.UserStream.ListenKeyTTLMin)*time.Minute),
+			userstream.Options{
+				Topic:         c.Kafka.Topic,
+				Group:         instanceGroup(c, "user"),
+				CheckInterval: time.Duration(c.UserStream.CheckIntervalSec) * time.Second,
+				Conn:          wsOptions(c),
+			}),
+		Klines: kline.NewAggregator(db, instruments, eventBus, kline.Options{
+			Topic:        c.Kafka.Topic,
+			Group:        c.Kafka.Group + ".kline",
+			DefaultLimit: c.Kline.DefaultLimit,
+			MaxLimit:     c.Kline.MaxLimit,
+		}),
+	}
+}
+
+// instanceGroup 每个实例独占的消费组。同一消费组内的实例分摊分区，
+// 而行情与用户数据流推送的每个实例都需要收到全部订单事件，因此按实例区分消费组；实例标识稳定时重启后沿用原位点
+func instanceGroup(c config.Config, name string) string {
+	instance := c.Kafka.Instance
+	if instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			panic("failed to resolve instance id: " + err.Error())
+		}
+		instance = hostname
+	}
+	return c.Kafka.Group + "." + name + "." + instance
+}
+
+// wsOptions 按配置构建 WebSocket 连接参数，行情与用户数据流共用
+func wsOptions(c config.Config) ws.Options {
+	return ws.Options{
+		SendBuffer:   c.Market.SendBuffer,
+		WriteTimeout: time.Duration(c.Market.WriteTimeoutMs) * time.Millisecond,
+		PingInterval: time.Duration(c.Market.PingIntervalSec) * time.Second,
+	}
+}
+
+// recoverMatcher 加载最新的订单簿快照并重放其后的日志，再与数据库中未完结的订单核对。
+// 日志无法恢复或与数据库不一致时（如崩溃发生在撮合之后、成交落库之前），Strict 模式拒绝启动，
+// 否则将日志移入留档目录，按数据库中的挂单与条件单重建订单簿。重放只恢复内存状态，不会重复落库成交。
+// 重建时挂单只恢复不撮合，数据库中的挂单彼此可成交时订单簿会交叉，此时同样拒绝启动，需人工处理这些订单。
+func recoverMatcher(matcher *matching.Engine, orders repository.OrderRepository, c config.Config) error {
+	resting, err := orders.ListByStatus(types.OrderStatusPending, types.OrderStatusPartFilled, types.OrderStatusTriggered)
+	if err != nil {
+		return err
+	}
+	untriggered, err := orders.ListByStatus(types.OrderStatusUntriggered)
+	if err != nil {
+		return err
+	}
+
+	rec, err := matcher.Recover(matching.JournalOptions{
+		Dir:  c.Matching.Journal.Dir,
+		Sync: c.Matching.Journal.Sync,
+		Keep: c.Matching.Journal.SnapshotKeep,
+	})
+	if err != nil {
+		fmt.Printf("撮合日志恢复失败: %v\n", err)
+	} else {
+		diffs := matcher.Verify(resting, untriggered)
+		if len(diffs) == 0 {
+			fmt.Printf("撮合引擎已恢复: 快照序号 %d, 重放命令 %d 条, 最新序号 %d\n", rec.Snapshot, rec.Replayed, rec.Seq)
+			return nil
+		}
+		fmt.Printf("撮合引擎恢复结果与数据库不一致，共 %d 处:\n", len(diffs))
+		for _, diff := range diffs {
+			fmt.Printf("  %s\n", diff)
+		}
+		err = fmt.Errorf("%d differences against database", len(diffs))
+	}
+	if c.Matching.Journal.Strict {
+		return err
+	}
+
+	fmt.Printf("按数据库重建订单簿: 挂单 %d 笔, 条件单 %d 笔\n", len(resting), len(untriggered))
+	if err := matcher.Reset(); err != nil {
+		return err
+	}
+	var crossed []string
+	for i := range resting {
+		if resting[i].OrderType != types.OrderTypeLimit {
+			continue
+		}
+		err := matcher.Restore(&resting[i])
+		if errors.Is(err, matching.ErrCrossedBook) {
+			crossed = append(crossed, resting[i].OrderID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(crossed) > 0 {
+		return fmt.Errorf("rebuilt book would be crossed by %d resting orders, settle them manually: %v", len(crossed), crossed)
+	}
+	for i := range untriggered {
+		if err := matcher.AddTrigger(&untriggered[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryPolicy 按配置构建订单事件消费的重试策略
+func retryPolicy(c config.Config) bus.RetryPolicy {
+	delays := c.Projection.RetryDelaysMs
+	if len(delays) == 0 {
+		delays = []int{5000, 60000}
+	}
+	policy := bus.RetryPolicy{
+		Attempts:   c.Projection.Attempts,
+		Backoff:    time.Duration(c.Projection.RetryBackoffMs) * time.Millisecond,
+		MaxBackoff: time.Duration(c.Projection.MaxBackoffMs) * time.Millisecond,
+	}
+	for _, ms := range delays {
+		policy.Delays = append(policy.Delays, time.Duration(ms)*time.Millisecond)
+	}
+	return policy
+}
+
+// newFeeSchedule 按配置构建手续费率表
+func newFeeSchedule(volumes fee.VolumeSource, c config.Config) (*fee.Schedule, error) {
+	var p decimalParser
+	opts := fee.Options{
+		Base: fee.Rates{
+			Maker: p.parse("Fee.MakerRate", c.Fee.MakerRate),
+			Taker: p.parse("Fee.TakerRate", c.Fee.TakerRate),
+		},
+		Overrides:   make(map[string]fee.Rates, len(c.Fee.Overrides)),
+		Window:      time.Duration(c.Fee.WindowDays) * 24 * time.Hour,
+		VolumeAsset: c.Fee.VolumeAsset,
+
+		Token:         c.Fee.Token,
+		TokenDiscount: p.parse("Fee.TokenDiscount", c.Fee.TokenDiscount),
+	}
+	for i, t := range c.Fee.Tiers {
+		field := fmt.Sprintf("Fee.Tiers[%d].", i)
+		opts.Tiers = append(opts.Tiers, fee.Tier{
+			MinVolume: p.parse(field+"MinVolume", t.MinVolume),
+			Rates: fee.Rates{
+				Maker: p.parse(field+"MakerRate", t.MakerRate),
+				Taker: p.parse(field+"TakerRate", t.TakerRate),
+			},
+		})
+	}
+	for _, o := range c.Fee.Overrides {
+		field := fmt.Sprintf("Fee.Overrides[%s].", o.Symbol)
+		opts.Overrides[o.Symbol] = fee.Rates{
+			Maker: p.parse(field+"MakerRate", o.MakerRate),
+			Taker: p.parse(field+"TakerRate", o.TakerRate),
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return fee.NewSchedule(volumes, opts)
+}
+
+// decimalParser 解析配置中的十进制字符串，保留第一个错误
+type decimalParser struct {
+	err error
+}
+
+func (p *decimalParser) parse(field, value string) decimal.Decimal {
+	d, err := decimal.NewFromString(value)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("config %s: invalid decimal %q", field, value)
+	}
+	return d
+}
+
+// OpenDB 按配置打开数据库。MySQL 需已通过 order migrate up 迁移到最新版本，启动时只做校验；
+// SQLite 按模型建表。
+func OpenDB(c config.Config) (*gorm.DB, error) {
+	if c.Storage.Driver == "sqlite" {
+		return repository.OpenSQLite(c.Storage.SQLite)
+	}
+
+	db, err := gorm.Open(mysql.Open(c.MySQL.DSN), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Verify(); err != nil {
+		return nil, err
 	}
+	return db, nil
 }
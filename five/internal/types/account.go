@@ -0,0 +1,38 @@
+package types
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Account 用户单个币种的资产账户
+type Account struct {
+	ID        uint            `gorm:"primaryKey;autoIncrement" json:"-"`
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+	UserID    int64           `gorm:"not null;uniqueIndex:idx_user_asset" json:"user_id"`
+	Asset     string          `gorm:"size:10;not null;uniqueIndex:idx_user_asset" json:"asset"`
+	Available decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"available"` // 可用余额
+	Frozen    decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"frozen"`    // 挂单冻结
+}
+
+// FeeSetting 用户手续费偏好
+type FeeSetting struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	UserID       int64     `gorm:"not null;uniqueIndex" json:"user_id"`
+	PayWithToken bool      `gorm:"not null;default:false" json:"pay_with_token"` // 使用平台币抵扣手续费
+}
+
+// FeeTokenReq 当前登录用户开启或关闭平台币抵扣手续费
+type FeeTokenReq struct {
+	Enabled bool `json:"enabled"`
+}
+
+// DepositReq 管理员入金请求
+type DepositReq struct {
+	UserID int64           `json:"user_id"`
+	Asset  string          `json:"asset"`
+	Amount decimal.Decimal `json:"amount"`
+}
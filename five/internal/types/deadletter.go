@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// DeadLetter 死信主题中的消息：重试耗尽或无法处理的事件，保存后供管理员查询与重放
+type DeadLetter struct {
+	ID            uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt     time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	DeadLetterID  string            `gorm:"size:64;not null;uniqueIndex" json:"dead_letter_id"` // 进入死信主题时分配，重复投递时去重
+	OriginalTopic string            `gorm:"size:100;not null;index" json:"original_topic"`      // 最初发布的主题，重放到该主题上处理失败的消费组
+	ConsumerGroup string            `gorm:"size:100;not null;default:''" json:"consumer_group"` // 处理失败的消费组
+	Key           string            `gorm:"size:100;not null;default:''" json:"key"`            // 消息 Key
+	Payload       []byte            `gorm:"type:blob;not null" json:"payload"`                  // 消息内容
+	Headers       map[string]string `gorm:"serializer:json;type:text" json:"headers"`           // 失败元数据等全部消息头
+	Error         string            `gorm:"size:1000;default:''" json:"error"`                  // 最后一次失败的错误
+	Attempts      int               `gorm:"not null;default:0" json:"attempts"`                 // 累计处理次数
+	FailedAt      *time.Time        `json:"failed_at"`                                          // 最后一次失败的时间
+	Replays       int               `gorm:"not null;default:0" json:"replays"`                  // 已重放次数
+	ReplayedAt    *time.Time        `gorm:"index" json:"replayed_at"`                           // 最近一次重放时间，为空表示待处理
+}
+
+// DeadLetterStatus 死信查询的状态过滤
+type DeadLetterStatus string
+
+const (
+	DeadLetterPending  DeadLetterStatus = "pending"  // 尚未重放
+	DeadLetterReplayed DeadLetterStatus = "replayed" // 已重放
+)
@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// EventRecord 事件存储中的一条订单事件，与订单变更在同一事务内写入，只追加不修改。
+// Sequence 为全局顺序，按 Sequence 依次折叠全部事件即可还原订单与成交记录。
+type EventRecord struct {
+	Sequence   uint64    `gorm:"primaryKey;autoIncrement" json:"sequence"`
+	EventID    string    `gorm:"size:64;not null;uniqueIndex" json:"event_id"`
+	Type       string    `gorm:"size:50;not null" json:"type"`
+	Version    int       `gorm:"not null" json:"version"` // 事件结构版本
+	OrderID    string    `gorm:"size:100;not null;index" json:"order_id"`
+	OccurredAt time.Time `gorm:"not null;index" json:"occurred_at"`
+	Payload    []byte    `gorm:"type:blob;not null" json:"payload"` // JSON 编码的完整事件
+}
+
+func (EventRecord) TableName() string { return "order_events" }
+
+// OrderSnapshot 订单状态快照：折叠到 Sequence 为止的全部事件后的订单与成交记录，用于缩短重放时间
+type OrderSnapshot struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	Sequence   uint64    `gorm:"not null;index" json:"sequence"`    // 快照包含的最后一个事件
+	OccurredAt time.Time `gorm:"not null;index" json:"occurred_at"` // 快照包含的事件中最晚的发生时间
+	Orders     int       `gorm:"not null;default:0" json:"orders"`
+	Trades     int       `gorm:"not null;default:0" json:"trades"`
+	Data       []byte    `gorm:"type:longblob;not null" json:"-"` // JSON 编码的订单与成交记录
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 交易对状态
+type InstrumentStatus string
+
+const (
+	InstrumentStatusPreTrading InstrumentStatus = "pre_trading" // 未开盘
+	InstrumentStatusTrading    InstrumentStatus = "trading"     // 交易中
+	InstrumentStatusHalted     InstrumentStatus = "halted"      // 暂停交易，只允许撤单
+	InstrumentStatusDelisted   InstrumentStatus = "delisted"    // 已下架
+)
+
+// Instrument 交易对配置
+type Instrument struct {
+	ID          uint             `gorm:"primaryKey;autoIncrement" json:"-"`
+	CreatedAt   time.Time        `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt   time.Time        `gorm:"autoUpdateTime" json:"-"`
+	Symbol      string           `gorm:"size:20;uniqueIndex" json:"symbol"`                                   // 交易对，如 BTC/USDT
+	BaseAsset   string           `gorm:"size:10;not null" json:"base_asset"`                                  // 基础币，如 BTC
+	QuoteAsset  string           `gorm:"size:10;not null" json:"quote_asset"`                                 // 计价币，如 USDT
+	PriceTick   decimal.Decimal  `gorm:"type:decimal(36,18);not null" json:"price_tick"`                      // 最小价格变动单位
+	QtyStep     decimal.Decimal  `gorm:"type:decimal(36,18);not null" json:"qty_step"`                        // 最小数量变动单位
+	MinQty      decimal.Decimal  `gorm:"type:decimal(36,18);not null;default:0" json:"min_qty,optional"`      // 最小下单数量
+	MaxQty      decimal.Decimal  `gorm:"type:decimal(36,18);not null;default:0" json:"max_qty,optional"`      // 最大下单数量，0 表示不限制
+	MinNotional decimal.Decimal  `gorm:"type:decimal(36,18);not null;default:0" json:"min_notional,optional"` // 最小下单金额
+	Status      InstrumentStatus `gorm:"size:20;not null;default:'pre_trading'" json:"status"`                // 交易状态
+}
+
+// Validate 校验交易对配置本身是否合法
+func (i *Instrument) Validate() error {
+	if i.Symbol == "" || i.BaseAsset == "" || i.QuoteAsset == "" {
+		return fmt.Errorf("symbol, base_asset and quote_asset are required")
+	}
+	if i.Symbol != i.BaseAsset+"/"+i.QuoteAsset {
+		return fmt.Errorf("symbol %s does not match %s/%s", i.Symbol, i.BaseAsset, i.QuoteAsset)
+	}
+	if !i.PriceTick.IsPositive() || !i.QtyStep.IsPositive() {
+		return fmt.Errorf("price_tick and qty_step must be positive")
+	}
+	if i.MinQty.IsNegative() || i.MaxQty.IsNegative() || i.MinNotional.IsNegative() {
+		return fmt.Errorf("min_qty, max_qty and min_notional must not be negative")
+	}
+	if i.MaxQty.IsPositive() && i.MaxQty.LessThan(i.MinQty) {
+		return fmt.Errorf("max_qty must not be less than min_qty")
+	}
+	switch i.Status {
+	case InstrumentStatusPreTrading, InstrumentStatusTrading, InstrumentStatusHalted, InstrumentStatusDelisted:
+	default:
+		return fmt.Errorf("unknown instrument status %s", i.Status)
+	}
+	return nil
+}
+
+// CheckOrder 按交易对规则校验订单的价格与数量
+func (i *Instrument) CheckOrder(order *Order) error {
+	if i.Status != InstrumentStatusTrading {
+		return fmt.Errorf("symbol %s is not open for trading (status %s)", i.Symbol, i.Status)
+	}
+
+	if order.OrderType.ExecType() == OrderTypeLimit {
+		if !isMultiple(order.Price, i.PriceTick) {
+			return fmt.Errorf("price %s is not a multiple of price tick %s", order.Price, i.PriceTick)
+		}
+	}
+	if order.TriggerPrice.IsPositive() && !isMultiple(order.TriggerPrice, i.PriceTick) {
+		return fmt.Errorf("trigger_price %s is not a multiple of price tick %s", order.TriggerPrice, i.PriceTick)
+	}
+	if order.TrailingOffset.IsPositive() && !isMultiple(order.TrailingOffset, i.PriceTick) {
+		return fmt.Errorf("trailing_offset %s is not a multiple of price tick %s", order.TrailingOffset, i.PriceTick)
+	}
+
+	// 市价买单按金额下单，只校验最小金额
+	if order.IsMarketBuy() {
+		if order.QuoteAmount.LessThan(i.MinNotional) {
+			return fmt.Errorf("quote_amount %s is below min notional %s", order.QuoteAmount, i.MinNotional)
+		}
+		return nil
+	}
+
+	if !isMultiple(order.Amount, i.QtyStep) {
+		return fmt.Errorf("amount %s is not a multiple of qty step %s", order.Amount, i.QtyStep)
+	}
+	if order.Amount.LessThan(i.MinQty) {
+		return fmt.Errorf("amount %s is below min qty %s", order.Amount, i.MinQty)
+	}
+	if i.MaxQty.IsPositive() && order.Amount.GreaterThan(i.MaxQty) {
+		return fmt.Errorf("amount %s exceeds max qty %s", order.Amount, i.MaxQty)
+	}
+	if order.OrderType.ExecType() == OrderTypeLimit {
+		if notional := order.Price.Mul(order.Amount); notional.LessThan(i.MinNotional) {
+			return fmt.Errorf("notional %s is below min notional %s", notional, i.MinNotional)
+		}
+	}
+	return nil
+}
+
+// isMultiple 判断 value 是否为 step 的整数倍
+func isMultiple(value, step decimal.Decimal) bool {
+	return value.Mod(step).IsZero()
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Kline 一根 K 线。只统计 taker 一侧的成交（人工成交没有对手方，直接统计），没有成交的周期不生成 K 线。
+// 周期列名为 period，interval 是 MySQL 保留字
+type Kline struct {
+	ID           uint            `gorm:"primaryKey;autoIncrement" json:"-"`
+	UpdatedAt    time.Time       `gorm:"autoUpdateTime" json:"-"`
+	Symbol       string          `gorm:"size:20;not null;uniqueIndex:idx_klines_symbol_period_open,priority:1" json:"symbol"`
+	Interval     string          `gorm:"column:period;size:4;not null;uniqueIndex:idx_klines_symbol_period_open,priority:2" json:"interval"`
+	OpenTime     time.Time       `gorm:"not null;uniqueIndex:idx_klines_symbol_period_open,priority:3" json:"open_time"`
+	CloseTime    time.Time       `gorm:"not null" json:"close_time"` // 周期结束时间（不含）
+	Open         decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"open"`
+	High         decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"high"`
+	Low          decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"low"`
+	Close        decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"close"`
+	Volume       decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"volume"`       // 成交量（基础币）
+	QuoteVolume  decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"quote_volume"` // 成交额（计价币）
+	Trades       int64           `gorm:"not null;default:0" json:"trades"`
+	FirstTradeAt time.Time       `gorm:"not null" json:"-"` // 周期内第一笔与最后一笔成交的时间，迟到的成交据此更新开盘价与收盘价
+	LastTradeAt  time.Time       `gorm:"not null" json:"-"`
+	Closed       bool            `gorm:"-" json:"closed"` // 周期已结束，查询时填充
+}
+
+// KlineRange 交易对在某个周期上 K 线完整的时间段 [StartTime, EndTime)：
+// 该时间段内有成交的周期都已生成 K 线，早于 StartTime 的 K 线需要从成交表回补
+type KlineRange struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	Symbol    string    `gorm:"size:20;not null;uniqueIndex:idx_kline_ranges_symbol_period,priority:1" json:"symbol"`
+	Interval  string    `gorm:"column:period;size:4;not null;uniqueIndex:idx_kline_ranges_symbol_period,priority:2" json:"interval"`
+	StartTime time.Time `gorm:"not null" json:"start_time"`
+	EndTime   time.Time `gorm:"not null" json:"end_time"`
+}
+
+// KlinesReq 查询 K 线，start、end 为 Unix 毫秒时间戳
+type KlinesReq struct {
+	Symbol   string `form:"symbol"`
+	Interval string `form:"interval"`
+	Start    int64  `form:"start,optional"` // 起始时间，为空时返回截至 end 的最近 limit 根
+	End      int64  `form:"end,optional"`   // 截止时间，默认当前时间
+	Limit    int    `form:"limit,optional"`
+}
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// EntryType 余额变动类型
+type EntryType string
+
+const (
+	EntryTypeFill       EntryType = "fill"       // 成交交割
+	EntryTypeFee        EntryType = "fee"        // 手续费
+	EntryTypeFreeze     EntryType = "freeze"     // 下单冻结
+	EntryTypeUnfreeze   EntryType = "unfreeze"   // 撤单或完结释放冻结
+	EntryTypeDeposit    EntryType = "deposit"    // 入金
+	EntryTypeAdjustment EntryType = "adjustment" // 人工调账
+)
+
+// Bucket 账户内的余额分类
+type Bucket string
+
+const (
+	BucketAvailable Bucket = "available"
+	BucketFrozen    Bucket = "frozen"
+)
+
+// LedgerEntry 复式记账分录行，只追加不修改。
+// Amount 为正表示借方（余额增加），为负表示贷方（余额减少），同一 JournalID 下每个币种的分录之和为零。
+type LedgerEntry struct {
+	ID        uint            `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	JournalID string          `gorm:"size:64;not null;index" json:"journal_id"`
+	EntryType EntryType       `gorm:"size:20;not null" json:"entry_type"`
+	UserID    int64           `gorm:"not null;index:idx_ledger_user_asset" json:"user_id"`
+	Asset     string          `gorm:"size:10;not null;index:idx_ledger_user_asset" json:"asset"`
+	Bucket    Bucket          `gorm:"size:10;not null" json:"bucket"`
+	Amount    decimal.Decimal `gorm:"type:decimal(36,18);not null" json:"amount"`
+	OrderID   string          `gorm:"size:100;index" json:"order_id,omitempty"`
+	TradeID   string          `gorm:"size:100;index" json:"trade_id,omitempty"`
+	Remark    string          `gorm:"size:200;default:''" json:"remark,omitempty"`
+}
+
+// AdjustReq 管理员调账请求，Amount 为正增加可用余额，为负扣减
+type AdjustReq struct {
+	UserID int64           `json:"user_id"`
+	Asset  string          `json:"asset"`
+	Amount decimal.Decimal `json:"amount"`
+	Remark string          `json:"remark,optional"`
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // 订单类型
@@ -10,8 +12,29 @@ type OrderType string
 const (
 	OrderTypeLimit  OrderType = "limit"  // 限价单
 	OrderTypeMarket OrderType = "market" // 市价单
+
+	// 条件单：触发前存放在触发队列中，不进入订单簿
+	OrderTypeStopLimit    OrderType = "stop_limit"    // 止损限价单，触发后转为限价单
+	OrderTypeStopMarket   OrderType = "stop_market"   // 止损市价单，触发后转为市价单
+	OrderTypeTrailingStop OrderType = "trailing_stop" // 追踪止损单，触发后转为市价单
 )
 
+// IsTrigger 是否为条件单类型
+func (t OrderType) IsTrigger() bool {
+	return t == OrderTypeStopLimit || t == OrderTypeStopMarket || t == OrderTypeTrailingStop
+}
+
+// ExecType 条件单触发后实际执行的订单类型
+func (t OrderType) ExecType() OrderType {
+	switch t {
+	case OrderTypeStopLimit:
+		return OrderTypeLimit
+	case OrderTypeStopMarket, OrderTypeTrailingStop:
+		return OrderTypeMarket
+	}
+	return t
+}
+
 // 订单方向
 type OrderSide string
 
@@ -24,46 +47,116 @@ const (
 type OrderStatus string
 
 const (
-	OrderStatusPending    OrderStatus = "pending"    // 挂单中
-	OrderStatusPartFilled OrderStatus = "part_filled" // 部分成交
-	OrderStatusFilled     OrderStatus = "filled"      // 完全成交
-	OrderStatusCancelled  OrderStatus = "cancelled"   // 已取消
-	OrderStatusRejected   OrderStatus = "rejected"    // 已拒绝
+	OrderStatusPending     OrderStatus = "pending"     // 挂单中
+	OrderStatusPartFilled  OrderStatus = "part_filled" // 部分成交
+	OrderStatusFilled      OrderStatus = "filled"      // 完全成交
+	OrderStatusCancelled   OrderStatus = "cancelled"   // 已取消
+	OrderStatusRejected    OrderStatus = "rejected"    // 已拒绝
+	OrderStatusUntriggered OrderStatus = "untriggered" // 条件单等待触发
+	OrderStatusTriggered   OrderStatus = "triggered"   // 条件单已触发，尚未成交
+)
+
+// IsOpen 订单是否仍在订单簿上等待成交
+func (s OrderStatus) IsOpen() bool {
+	return s == OrderStatusPending || s == OrderStatusPartFilled || s == OrderStatusTriggered
+}
+
+// 订单有效方式
+type TimeInForce string
+
+const (
+	TimeInForceGTC      TimeInForce = "GTC"       // 一直有效直到取消
+	TimeInForceIOC      TimeInForce = "IOC"       // 立即成交，剩余部分取消
+	TimeInForceFOK      TimeInForce = "FOK"       // 全部成交，否则整单拒绝
+	TimeInForcePostOnly TimeInForce = "POST_ONLY" // 只做 maker，会吃单时拒绝或改价
+	TimeInForceGTD      TimeInForce = "GTD"       // 有效至 ExpireAt，到期自动取消
+)
+
+// 系统取消原因
+const (
+	CancelReasonSlippage    = "slippage_exceeded" // 市价单超出最大滑点，剩余部分取消
+	CancelReasonNoLiquidity = "no_liquidity"      // 对手盘流动性不足，剩余部分取消
+	CancelReasonIOC         = "ioc_remainder"     // IOC 订单未立即成交的部分
+	CancelReasonFOK         = "fok_unfillable"    // FOK 订单无法全部成交，整单拒绝
+	CancelReasonPostOnly    = "post_only_taker"   // 只做 maker 的订单会吃单，拒绝
+	CancelReasonExpired     = "expired"           // GTD 订单到期
+	CancelReasonSettlement  = "settlement_failed" // 成交落库失败，撤销撮合后取消
 )
 
 type Order struct {
-	ID              uint        `gorm:"primaryKey;autoIncrement" json:"-"`
-	CreatedAt       time.Time   `gorm:"autoCreateTime" json:"-"`
-	UpdatedAt       time.Time   `gorm:"autoUpdateTime" json:"-"`
-	OrderID         string      `gorm:"size:100;uniqueIndex" json:"order_id"`
-	UserID          int64       `json:"user_id"`
-	Symbol          string      `gorm:"size:20;not null" json:"symbol"`           // 交易对，如 BTC/USDT
-	OrderType       OrderType   `gorm:"size:20;not null" json:"order_type"`        // 订单类型：limit, market
-	OrderSide       OrderSide   `gorm:"size:10;not null" json:"order_side"`        // 订单方向：buy, sell
-	Price           float64     `gorm:"not null;default:0" json:"price"`           // 限价单价格
-	Amount          float64     `gorm:"not null;default:0" json:"amount"`          // 订单数量
-	FilledAmount    float64     `gorm:"not null;default:0" json:"filled_amount"`    // 已成交数量
-	Fee             float64     `gorm:"not null;default:0" json:"fee"`              // 手续费
-	FeeAsset        string      `gorm:"size:10;default:'USDT'" json:"fee_asset"`   // 手续费币种
-	Status          OrderStatus `gorm:"size:20;not null;default:'pending'" json:"status"` // 订单状态
-	CancelReason    string      `gorm:"size:200;default:''" json:"cancel_reason"`  // 取消原因
+	ID             uint            `gorm:"primaryKey;autoIncrement" json:"-"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt      time.Time       `gorm:"autoUpdateTime" json:"-"`
+	OrderID        string          `gorm:"size:100;uniqueIndex" json:"order_id,optional"`                                                 // 订单号，由服务端生成
+	ClientOrderID  string          `gorm:"size:64;not null;uniqueIndex:idx_user_client_order,priority:2" json:"client_order_id,optional"` // 客户端订单号，同一用户内唯一，未指定时与订单号相同
+	UserID         int64           `gorm:"uniqueIndex:idx_user_client_order,priority:1" json:"user_id"`
+	Symbol         string          `gorm:"size:20;not null" json:"symbol"`                                         // 交易对，如 BTC/USDT
+	OrderType      OrderType       `gorm:"size:20;not null" json:"order_type"`                                     // 订单类型：limit, market
+	OrderSide      OrderSide       `gorm:"size:10;not null" json:"order_side"`                                     // 订单方向：buy, sell
+	Price          decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"price"`                    // 限价单价格
+	Amount         decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"amount"`                   // 订单数量
+	QuoteAmount    decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"quote_amount,optional"`    // 市价买单的计价币金额
+	FilledAmount   decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"filled_amount,optional"`   // 已成交数量
+	FilledQuote    decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"filled_quote,optional"`    // 已成交金额
+	Fee            decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"fee,optional"`             // 手续费
+	FeeAsset       string          `gorm:"size:10;default:'USDT'" json:"fee_asset,optional"`                       // 手续费币种，默认为收到的资产
+	TokenFee       decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"token_fee,optional"`       // 以平台币抵扣的手续费
+	Frozen         decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"frozen,optional"`          // 尚未结算的冻结资金：买单为计价币，卖单为基础币
+	TriggerType    OrderType       `gorm:"size:20;default:''" json:"trigger_type,optional"`                        // 条件单原始类型，触发后 OrderType 转为 limit/market
+	TriggerPrice   decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"trigger_price,optional"`   // 止损触发价
+	TrailingOffset decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"trailing_offset,optional"` // 追踪止损回撤价差
+	TriggeredAt    *time.Time      `json:"triggered_at,optional,omitempty"`                                        // 触发时间
+	TimeInForce    TimeInForce     `gorm:"size:10;not null;default:'GTC'" json:"time_in_force,optional"`           // 有效方式
+	ExpireAt       *time.Time      `gorm:"index" json:"expire_at,optional,omitempty"`                              // GTD 订单的到期时间
+	Status         OrderStatus     `gorm:"size:20;not null;default:'pending';index" json:"status,optional"`        // 订单状态
+	CancelReason   string          `gorm:"size:200;default:''" json:"cancel_reason,optional"`                      // 取消原因
+	Version        int64           `gorm:"not null;default:0" json:"version,optional"`                             // 乐观锁版本号，每次更新加一
+}
+
+// IsMarketBuy 是否为按计价币金额下单的市价买单（含触发后转市价的条件单）
+func (o *Order) IsMarketBuy() bool {
+	return o.OrderType.ExecType() == OrderTypeMarket && o.OrderSide == OrderSideBuy
+}
+
+// IsFullyFilled 订单是否已全部成交（市价买单按成交金额判断）
+func (o *Order) IsFullyFilled() bool {
+	if o.IsMarketBuy() {
+		return o.FilledQuote.GreaterThanOrEqual(o.QuoteAmount)
+	}
+	return o.FilledAmount.GreaterThanOrEqual(o.Amount)
 }
 
-type OrderMessage struct {
-	Action  string `json:"action"` // create, update, cancel, fill
-	OrderID string `json:"order_id"`
-	Data    Order  `json:"data"`
+// 手续费精度：按 FeeScale 位小数向上取整，不足一个最小单位的部分按一个单位收取
+const FeeScale = 8
+
+// RoundFee 按手续费精度规则取整
+func RoundFee(fee decimal.Decimal) decimal.Decimal {
+	return fee.RoundCeil(FeeScale)
 }
 
+// LiquidityRole 成交中的流动性角色
+type LiquidityRole string
+
+const (
+	LiquidityMaker LiquidityRole = "maker" // 挂单方，提供流动性
+	LiquidityTaker LiquidityRole = "taker" // 吃单方，消耗流动性
+)
+
 // 成交记录
 type Trade struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
-	TradeID   string    `gorm:"size:100;uniqueIndex" json:"trade_id"`
-	OrderID   string    `gorm:"size:100;index" json:"order_id"`
-	Symbol    string    `gorm:"size:20;not null" json:"symbol"`
-	Price     float64   `gorm:"not null;default:0" json:"price"`
-	Amount    float64   `gorm:"not null;default:0" json:"amount"`
-	Fee       float64   `gorm:"not null;default:0" json:"fee"`
-	FeeAsset  string    `gorm:"size:10;default:'USDT'" json:"fee_asset"`
-}
\ No newline at end of file
+	ID             uint            `gorm:"primaryKey;autoIncrement" json:"-"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime;index:idx_trades_symbol_created_at,priority:2" json:"-"`
+	TradeID        string          `gorm:"size:100;uniqueIndex" json:"trade_id"`
+	MatchID        string          `gorm:"size:100;index" json:"match_id"` // 撮合编号，maker 与 taker 两条成交记录相同
+	OrderID        string          `gorm:"size:100;index" json:"order_id"`
+	CounterOrderID string          `gorm:"size:100" json:"counter_order_id"` // 对手方订单
+	UserID         int64           `gorm:"index" json:"user_id"`
+	Symbol         string          `gorm:"size:20;not null;index:idx_trades_symbol_created_at,priority:1" json:"symbol"`
+	Side           OrderSide       `gorm:"size:10" json:"side"`
+	Price          decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"price"`
+	Amount         decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"amount"`
+	Role           LiquidityRole   `gorm:"size:10;not null;default:''" json:"role"`
+	FeeRate        decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"fee_rate"` // 实际适用的费率
+	Fee            decimal.Decimal `gorm:"type:decimal(36,18);not null;default:0" json:"fee"`
+	FeeAsset       string          `gorm:"size:10;default:'USDT'" json:"fee_asset"`
+}
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	ErrOrderClosed       = errors.New("order is already closed")
+	ErrOverfill          = errors.New("fill exceeds order amount")
+	ErrInvalidFill       = errors.New("fill amount must be positive")
+	ErrVersionConflict   = errors.New("order was modified concurrently")
+)
+
+// orderTransitions 订单状态机：每个状态允许流转到的下一状态。
+// part_filled 可以流转到自身（继续部分成交），filled/cancelled/rejected 为终态。
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:     {OrderStatusPartFilled, OrderStatusFilled, OrderStatusCancelled, OrderStatusRejected},
+	OrderStatusUntriggered: {OrderStatusTriggered, OrderStatusCancelled, OrderStatusRejected},
+	OrderStatusTriggered:   {OrderStatusPartFilled, OrderStatusFilled, OrderStatusCancelled, OrderStatusRejected},
+	OrderStatusPartFilled:  {OrderStatusPartFilled, OrderStatusFilled, OrderStatusCancelled},
+}
+
+// IsFinal 订单是否已进入终态
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusFilled || s == OrderStatusCancelled || s == OrderStatusRejected
+}
+
+// CanTransition 状态机是否允许从 s 流转到 to
+func (s OrderStatus) CanTransition(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError 不允许的订单状态流转
+type TransitionError struct {
+	OrderID string
+	From    OrderStatus
+	To      OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order %s: cannot transition from %s to %s", e.OrderID, e.From, e.To)
+}
+
+// Unwrap 已终态的订单返回 ErrOrderClosed，其余返回 ErrInvalidTransition
+func (e *TransitionError) Unwrap() error {
+	if e.From.IsFinal() {
+		return ErrOrderClosed
+	}
+	return ErrInvalidTransition
+}
+
+// Transition 按状态机校验并修改订单状态
+func (o *Order) Transition(to OrderStatus) error {
+	if !o.Status.CanTransition(to) {
+		return &TransitionError{OrderID: o.OrderID, From: o.Status, To: to}
+	}
+	o.Status = to
+	return nil
+}
+
+// ApplyFill 累加一笔成交并推进订单状态。
+// 已终态或未触发的订单拒绝成交，成交后数量（市价买单为金额）超出订单上限时返回 ErrOverfill。
+func (o *Order) ApplyFill(amount, quote decimal.Decimal) error {
+	if !amount.IsPositive() || !quote.IsPositive() {
+		return fmt.Errorf("order %s: %w", o.OrderID, ErrInvalidFill)
+	}
+	if !o.Status.CanTransition(OrderStatusPartFilled) {
+		return &TransitionError{OrderID: o.OrderID, From: o.Status, To: OrderStatusPartFilled}
+	}
+
+	filledAmount := o.FilledAmount.Add(amount)
+	filledQuote := o.FilledQuote.Add(quote)
+	if o.IsMarketBuy() {
+		if filledQuote.GreaterThan(o.QuoteAmount) {
+			return fmt.Errorf("order %s: %w: filled quote %s > %s", o.OrderID, ErrOverfill, filledQuote, o.QuoteAmount)
+		}
+	} else if filledAmount.GreaterThan(o.Amount) {
+		return fmt.Errorf("order %s: %w: filled amount %s > %s", o.OrderID, ErrOverfill, filledAmount, o.Amount)
+	}
+
+	o.FilledAmount = filledAmount
+	o.FilledQuote = filledQuote
+	if o.IsFullyFilled() {
+		o.Status = OrderStatusFilled
+	} else {
+		o.Status = OrderStatusPartFilled
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package types
+
+import "time"
+
+// OutboxMessage 事务发件箱中的待发布消息，与业务数据在同一事务内写入
+type OutboxMessage struct {
+	ID        uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	Topic     string            `gorm:"size:100;not null" json:"topic"`
+	Key       string            `gorm:"size:100;not null" json:"key"`
+	Payload   []byte            `gorm:"type:blob;not null" json:"payload"`
+	Headers   map[string]string `gorm:"serializer:json;type:text" json:"headers"` // 随消息发布的消息头，如事件编码方式
+	Attempts  int               `gorm:"not null;default:0" json:"attempts"`       // 发布失败次数
+	LastError string            `gorm:"size:500;default:''" json:"last_error"`    // 最近一次发布失败原因
+	SentAt    *time.Time        `gorm:"index" json:"sent_at"`                     // 发布成功时间，为空表示待发布
+}
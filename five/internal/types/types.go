@@ -18,12 +18,12 @@ type OrderCancelResp struct {
 }
 
 type OrderCreateReq struct {
-	UserID int64   `json:"user_id"`
-	Symbol string  `json:"symbol"` // 交易对
-	Price  float64 `json:"price"`  // 价格
-	Amount float64 `json:"amount"` // 数量
-	Side   int     `json:"side"`   // 买卖方向: 1-买, 2-卖
-	Type   int     `json:"type"`   // 订单类型: 1-限价, 2-市价
+	UserID int64  `json:"user_id"`
+	Symbol string `json:"symbol"` // 交易对
+	Price  string `json:"price"`  // 价格
+	Amount string `json:"amount"` // 数量
+	Side   int    `json:"side"`   // 买卖方向: 1-买, 2-卖
+	Type   int    `json:"type"`   // 订单类型: 1-限价, 2-市价
 }
 
 type OrderCreateResp struct {
@@ -32,22 +32,24 @@ type OrderCreateResp struct {
 }
 
 type OrderItem struct {
-	Price  float64 `json:"price"`
-	Amount float64 `json:"amount"`
+	Price  string `json:"price"`
+	Amount string `json:"amount"`
 }
 
 type OrderMatchReq struct {
-	TakerOrderID int64   `json:"taker_order_id"`
-	MakerOrderID int64   `json:"maker_order_id"`
-	Price        float64 `json:"price"`
-	Amount       float64 `json:"amount"`
+	TakerOrderID int64  `json:"taker_order_id"`
+	MakerOrderID int64  `json:"maker_order_id"`
+	Price        string `json:"price"`
+	Amount       string `json:"amount"`
 }
 
 type OrderMatchResp struct {
-	Success bool    `json:"success"`
-	Fee     float64 `json:"fee"` // 手续费
+	Success bool   `json:"success"`
+	Fee     string `json:"fee"` // 手续费
 }
 
 type SymbolReq struct {
-	Symbol string `json:"symbol"`
+	Symbol    string `form:"symbol"`
+	Depth     int    `form:"depth,optional"`     // 档位数量，默认20
+	Precision string `form:"precision,optional"` // 价格聚合步长
 }
@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+// ListenKeyReq 续期或关闭 listen key
+type ListenKeyReq struct {
+	ListenKey string `form:"listen_key"`
+}
+
+// ListenKeyResp listen key 及其过期时间，过期前需调用续期接口
+type ListenKeyResp struct {
+	ListenKey string    `json:"listen_key"`
+	ExpireAt  time.Time `json:"expire_at"`
+}
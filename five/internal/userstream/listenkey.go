@@ -0,0 +1,111 @@
+package userstream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// listen key 在 Redis 中的键为 userstream:listen-key:<key>，值为用户编号，过期时间即 listen key 的有效期
+const listenKeyPrefix = "userstream:listen-key:"
+
+var ErrListenKeyNotFound = errors.New("listen key not found or expired")
+
+// ListenKeys 用户数据流的 listen key。listen key 是连接用户数据流的凭证，
+// 有效期内调用 Keepalive 续期，过期或被关闭后，使用它的连接会被断开。
+type ListenKeys struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewListenKeys(rdb *redis.Client, ttl time.Duration) *ListenKeys {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &ListenKeys{rdb: rdb, ttl: ttl}
+}
+
+// Create 为用户生成新的 listen key，返回 key 与过期时间
+func (k *ListenKeys) Create(ctx context.Context, userID int64) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	key := hex.EncodeToString(buf)
+	if err := k.rdb.Set(ctx, listenKeyPrefix+key, userID, k.ttl).Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return key, time.Now().Add(k.ttl), nil
+}
+
+// Keepalive 将 listen key 的有效期从现在起重新计算，返回新的过期时间
+func (k *ListenKeys) Keepalive(ctx context.Context, key string) (time.Time, error) {
+	ok, err := k.rdb.Expire(ctx, listenKeyPrefix+key, k.ttl).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !ok {
+		return time.Time{}, ErrListenKeyNotFound
+	}
+	return time.Now().Add(k.ttl), nil
+}
+
+// Delete 使 listen key 立即失效
+func (k *ListenKeys) Delete(ctx context.Context, key string) error {
+	n, err := k.rdb.Del(ctx, listenKeyPrefix+key).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrListenKeyNotFound
+	}
+	return nil
+}
+
+// User 查询 listen key 所属的用户
+func (k *ListenKeys) User(ctx context.Context, key string) (int64, error) {
+	val, err := k.rdb.Get(ctx, listenKeyPrefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrListenKeyNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// Check 校验 listen key 属于 userID，他人的 listen key 按不存在处理
+func (k *ListenKeys) Check(ctx context.Context, key string, userID int64) error {
+	owner, err := k.User(ctx, key)
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return ErrListenKeyNotFound
+	}
+	return nil
+}
+
+// expired 返回 keys 中已过期或被关闭的 listen key
+func (k *ListenKeys) expired(ctx context.Context, keys []string) (map[string]bool, error) {
+	pipe := k.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Exists(ctx, listenKeyPrefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	expired := make(map[string]bool)
+	for i, cmd := range cmds {
+		if cmd.Val() == 0 {
+			expired[keys[i]] = true
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,408 @@
+package userstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"five/internal/account"
+	"five/internal/bus"
+	"five/internal/event"
+	"five/internal/instrument"
+	"five/internal/types"
+	"five/internal/ws"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 推送的频道。用户数据流无需订阅，连接后即收到该用户的全部推送
+const (
+	ChannelOrders     = "orders"     // 订单状态变化
+	ChannelExecutions = "executions" // 本人的成交明细，含手续费与 maker/taker 角色
+	ChannelBalances   = "balances"   // 余额变化：连接时推送全部余额的快照，之后推送有变化的币种
+	ChannelListenKey  = "listen_key" // listen key 过期或被关闭，随后服务端断开连接
+)
+
+// listen key 失效时 listen_key 频道的推送类型
+const (
+	ListenKeyExpired = "expired"
+	ListenKeyClosed  = "closed"
+)
+
+// CloseListenKeyExpired listen key 失效时断开连接的关闭码（4000-4999 为应用自定义）
+const CloseListenKeyExpired = 4000
+
+// Options 用户数据流参数
+type Options struct {
+	Topic         string        // 订单事件主题
+	Group         string        // 消费组
+	CheckInterval time.Duration // 检查连接所用 listen key 是否失效的间隔
+	Conn          ws.Options
+}
+
+// OrderData 订单状态推送。同一订单按 Version 递增推送，事件按至少一次投递，客户端可按 EventID 或 Version 去重
+type OrderData struct {
+	EventID string     `json:"event_id"`
+	Event   event.Type `json:"event"`
+	Reason  string     `json:"reason,omitempty"` // 拒绝或系统取消的原因
+	Time    time.Time  `json:"time"`
+	event.Order
+}
+
+// ExecutionData 成交明细推送
+type ExecutionData struct {
+	EventID  string              `json:"event_id"`
+	TradeID  string              `json:"trade_id"`
+	MatchID  string              `json:"match_id,omitempty"` // 撮合编号，人工成交为空
+	OrderID  string              `json:"order_id"`
+	Symbol   string              `json:"symbol"`
+	Side     types.OrderSide     `json:"side"`
+	Price    decimal.Decimal     `json:"price"`
+	Amount   decimal.Decimal     `json:"amount"`
+	Role     types.LiquidityRole `json:"role"`
+	FeeRate  decimal.Decimal     `json:"fee_rate"`
+	Fee      decimal.Decimal     `json:"fee"`
+	FeeAsset string              `json:"fee_asset"`
+	Time     time.Time           `json:"time"`
+}
+
+// ListenKeyData listen key 失效推送
+type ListenKeyData struct {
+	ListenKey string `json:"listen_key"`
+}
+
+// client 一个用户数据流连接
+type client struct {
+	conn      *ws.Conn
+	userID    int64
+	listenKey string
+}
+
+// session 同一用户的全部连接
+type session struct {
+	clients map[*client]bool // 由 Stream.mu 保护
+
+	mu       sync.Mutex               // 串行化余额的查询与推送，使推送的余额不会回退
+	balances map[string]types.Account // 最近一次推送的各币种余额
+}
+
+// Stream 私有用户数据流 WebSocket 网关。订单与成交推送来自订单事件，事件涉及的币种随后从账户表
+// 重新查询，有变化时推送余额。连接使用 listen key 鉴权，listen key 过期或被关闭后断开。
+type Stream struct {
+	db          *gorm.DB
+	instruments instrument.Catalog
+	bus         bus.Bus
+	keys        *ListenKeys
+	opts        Options
+
+	mu    sync.Mutex
+	users map[int64]*session
+	since time.Time // 早于该时间的事件在连接建立前已发生，重新投递时跳过
+}
+
+func NewStream(db *gorm.DB, instruments instrument.Catalog, b bus.Bus, keys *ListenKeys, opts Options) *Stream {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 30 * time.Second
+	}
+	return &Stream{
+		db:          db,
+		instruments: instruments,
+		bus:         b,
+		keys:        keys,
+		opts:        opts,
+		users:       make(map[int64]*session),
+	}
+}
+
+// ListenKeys 用户数据流的 listen key
+func (s *Stream) ListenKeys() *ListenKeys {
+	return s.keys
+}
+
+// Start 订阅订单事件并定期检查 listen key，直到 ctx 结束
+func (s *Stream) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.since = time.Now()
+	s.mu.Unlock()
+
+	consumer := bus.NewConsumer(s.bus, s.opts.Topic, s.opts.Group, bus.RetryPolicy{}, s.handleEvent)
+	if _, err := consumer.Start(ctx); err != nil {
+		return err
+	}
+	go s.watch(ctx)
+	return nil
+}
+
+// handleEvent 将订单事件推送给事件所属用户的连接，并刷新事件涉及币种的余额。
+// 推送尽力而为，无法解码的事件直接跳过，由订单读模型的消费者送入死信
+func (s *Stream) handleEvent(ctx context.Context, msg bus.Message) error {
+	events, err := event.Decode(msg.Value, msg.Headers)
+	if err != nil {
+		return nil
+	}
+	for _, e := range events {
+		var (
+			userID int64
+			symbol string
+			assets []string
+			push   ws.Push
+		)
+		if o := e.Order(); o != nil {
+			userID, symbol = o.UserID, o.Symbol
+			assets = append(assets, o.FeeAsset)
+			push = ws.Push{Channel: ChannelOrders, Data: orderData(e, o)}
+		} else if t := e.Trade(); t != nil {
+			userID, symbol = t.UserID, t.Symbol
+			assets = append(assets, t.FeeAsset)
+			push = ws.Push{Channel: ChannelExecutions, Data: executionData(e, t)}
+		} else {
+			continue
+		}
+		if inst, err := s.instruments.Get(symbol); err == nil {
+			assets = append(assets, inst.BaseAsset, inst.QuoteAsset)
+		}
+
+		s.mu.Lock()
+		stale := e.OccurredAt.Before(s.since)
+		sess := s.users[userID]
+		s.mu.Unlock()
+		if stale || sess == nil {
+			continue
+		}
+		s.broadcast(userID, push)
+		s.refresh(ctx, userID, assets)
+	}
+	return nil
+}
+
+func orderData(e *event.Event, o *event.Order) OrderData {
+	data := OrderData{EventID: e.ID, Event: e.Type, Time: e.OccurredAt, Order: *o}
+	switch p := e.Payload.(type) {
+	case *event.OrderCancelled:
+		data.Reason = p.Reason
+	case *event.OrderRejected:
+		data.Reason = p.Reason
+	}
+	return data
+}
+
+// executionData 只推送本人一侧的成交，不包含对手方订单
+func executionData(e *event.Event, t *event.Trade) ExecutionData {
+	return ExecutionData{
+		EventID:  e.ID,
+		TradeID:  t.TradeID,
+		MatchID:  t.MatchID,
+		OrderID:  t.OrderID,
+		Symbol:   t.Symbol,
+		Side:     t.Side,
+		Price:    t.Price,
+		Amount:   t.Amount,
+		Role:     t.Role,
+		FeeRate:  t.FeeRate,
+		Fee:      t.Fee,
+		FeeAsset: t.FeeAsset,
+		Time:     t.CreatedAt,
+	}
+}
+
+// NotifyBalance 用户的余额在订单事件之外发生变化（如入金、调账）后调用，向该用户的连接推送最新余额
+func (s *Stream) NotifyBalance(ctx context.Context, userID int64, assets ...string) {
+	s.refresh(ctx, userID, assets)
+}
+
+// refresh 查询用户指定币种的余额，向其连接推送与上一次推送相比有变化的币种
+func (s *Stream) refresh(ctx context.Context, userID int64, assets []string) {
+	s.mu.Lock()
+	sess := s.users[userID]
+	s.mu.Unlock()
+	if sess == nil {
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	accounts, err := account.List(s.db.WithContext(ctx), userID, assets...)
+	if err != nil {
+		fmt.Printf("查询用户 %d 余额失败: %v\n", userID, err)
+		return
+	}
+	changed := make([]types.Account, 0, len(accounts))
+	for _, acct := range accounts {
+		prev, ok := sess.balances[acct.Asset]
+		if ok && prev.Available.Equal(acct.Available) && prev.Frozen.Equal(acct.Frozen) {
+			continue
+		}
+		sess.balances[acct.Asset] = acct
+		changed = append(changed, acct)
+	}
+	if len(changed) > 0 {
+		s.broadcast(userID, ws.Push{Channel: ChannelBalances, Type: ws.PushUpdate, Data: changed})
+	}
+}
+
+// broadcast 向用户的全部连接推送
+func (s *Stream) broadcast(userID int64, push ws.Push) {
+	msg, err := json.Marshal(push)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess := s.users[userID]; sess != nil {
+		for c := range sess.clients {
+			c.conn.Send(msg)
+		}
+	}
+}
+
+// watch 定期检查连接所用的 listen key，断开已过期的连接。Redis 不可用时跳过本轮检查，不断开连接
+func (s *Stream) watch(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		seen := make(map[string]bool)
+		var keys []string
+		for _, sess := range s.users {
+			for c := range sess.clients {
+				if !seen[c.listenKey] {
+					seen[c.listenKey] = true
+					keys = append(keys, c.listenKey)
+				}
+			}
+		}
+		s.mu.Unlock()
+		if len(keys) == 0 {
+			continue
+		}
+
+		expired, err := s.keys.expired(ctx, keys)
+		if err != nil {
+			fmt.Printf("检查 listen key 失败: %v\n", err)
+			continue
+		}
+		for key := range expired {
+			s.disconnect(key, ListenKeyExpired)
+		}
+	}
+}
+
+// CloseListenKey 使 listen key 立即失效，并断开使用它的连接
+func (s *Stream) CloseListenKey(ctx context.Context, key string) error {
+	if err := s.keys.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.disconnect(key, ListenKeyClosed)
+	return nil
+}
+
+// disconnect 通知并断开使用 listen key 的连接
+func (s *Stream) disconnect(key, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.users {
+		for c := range sess.clients {
+			if c.listenKey != key {
+				continue
+			}
+			c.conn.SendJSON(ws.Push{Channel: ChannelListenKey, Type: reason, Data: ListenKeyData{ListenKey: key}})
+			c.conn.Close(CloseListenKeyExpired, "listen key "+reason)
+		}
+	}
+}
+
+// ServeHTTP 校验 listen key 后升级为 WebSocket 连接，推送余额快照，之后持续推送该用户的订单、成交与余额变化
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("listen_key")
+	if key == "" {
+		http.Error(w, "listen_key is required", http.StatusUnauthorized)
+		return
+	}
+	userID, err := s.keys.User(r.Context(), key)
+	if errors.Is(err, ErrListenKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := ws.Upgrade(w, r, s.opts.Conn)
+	if err != nil {
+		return
+	}
+	c := &client{conn: conn, userID: userID, listenKey: key}
+	sess := s.join(c)
+	if err := s.snapshot(r.Context(), sess, c); err != nil {
+		fmt.Printf("查询用户 %d 余额失败: %v\n", userID, err)
+		conn.Close(ws.CloseInternalError, "internal error")
+	}
+	conn.Run(func(msg []byte) {
+		s.handle(c, msg)
+	})
+	s.leave(c)
+}
+
+// join 登记连接。先登记再推送快照，快照之后的变化不会遗漏
+func (s *Stream) join(c *client) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.users[c.userID]
+	if sess == nil {
+		sess = &session{clients: make(map[*client]bool), balances: make(map[string]types.Account)}
+		s.users[c.userID] = sess
+	}
+	sess.clients[c] = true
+	return sess
+}
+
+func (s *Stream) leave(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.users[c.userID]
+	if sess == nil {
+		return
+	}
+	delete(sess.clients, c)
+	if len(sess.clients) == 0 {
+		delete(s.users, c.userID)
+	}
+}
+
+// snapshot 向新连接推送用户全部余额。不更新已推送的余额，同一用户的其他连接仍按原有基准收到变化
+func (s *Stream) snapshot(ctx context.Context, sess *session, c *client) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	accounts, err := account.List(s.db.WithContext(ctx), c.userID)
+	if err != nil {
+		return err
+	}
+	c.conn.SendJSON(ws.Push{Channel: ChannelBalances, Type: ws.PushSnapshot, Data: accounts})
+	return nil
+}
+
+// handle 用户数据流只接受 ping，其余推送无需订阅
+func (s *Stream) handle(c *client, msg []byte) {
+	var req ws.Request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		c.conn.SendJSON(ws.Response{Op: ws.OpError, Error: "invalid request"})
+		return
+	}
+	if req.Op != ws.OpPing {
+		c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpError, Error: fmt.Sprintf("unknown op %q", req.Op)})
+		return
+	}
+	c.conn.SendJSON(ws.Response{ID: req.ID, Op: ws.OpPong})
+}
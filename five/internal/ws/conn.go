@@ -0,0 +1,191 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Options 连接参数
+type Options struct {
+	SendBuffer     int           // 每个连接的待发送消息队列长度，写满视为慢消费者并断开
+	WriteTimeout   time.Duration // 单条消息的写超时
+	PingInterval   time.Duration // 心跳间隔，超过两个间隔未收到客户端的任何消息（含 pong）时断开
+	MaxMessageSize int64         // 客户端消息的大小上限
+}
+
+// 慢消费者断开时的关闭码与原因
+const (
+	CloseSlowConsumer       = websocket.ClosePolicyViolation
+	CloseSlowConsumerReason = "slow consumer"
+)
+
+// CloseInternalError 服务端内部错误时断开的关闭码
+const CloseInternalError = websocket.CloseInternalServerErr
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// 行情与用户数据流不依赖 Cookie 鉴权，允许跨域连接
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Conn 一个 WebSocket 连接。发送不会阻塞调用方：消息先进入连接自己的有界队列，由写协程依次写出；
+// 队列写满说明客户端读得太慢，直接断开该连接，不拖慢发布方与其他连接。
+type Conn struct {
+	ws   *websocket.Conn
+	opts Options
+	send chan []byte
+
+	once       sync.Once
+	done       chan struct{} // 连接关闭后关闭
+	writerDone chan struct{}
+	code       int
+	reason     string
+}
+
+// Upgrade 将 HTTP 请求升级为 WebSocket 连接，失败时已向客户端返回错误响应
+func Upgrade(w http.ResponseWriter, r *http.Request, opts Options) (*Conn, error) {
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = 256
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 5 * time.Second
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 4096
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		ws:         conn,
+		opts:       opts,
+		send:       make(chan []byte, opts.SendBuffer),
+		done:       make(chan struct{}),
+		writerDone: make(chan struct{}),
+		code:       websocket.CloseNormalClosure,
+	}, nil
+}
+
+// Send 将消息放入发送队列。连接已关闭或队列已满时返回 false，队列已满时同时断开连接
+func (c *Conn) Send(msg []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		c.Close(CloseSlowConsumer, CloseSlowConsumerReason)
+		return false
+	}
+}
+
+// SendJSON 将 v 编码为 JSON 后放入发送队列
+func (c *Conn) SendJSON(v any) bool {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return c.Send(msg)
+}
+
+// Close 关闭连接：写协程写出队列中已有的消息（慢消费者除外）并发送关闭帧后断开，只有第一次调用生效
+func (c *Conn) Close(code int, reason string) {
+	c.once.Do(func() {
+		c.code, c.reason = code, reason
+		close(c.done)
+	})
+}
+
+// Done 连接关闭后关闭的通道
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// RemoteAddr 客户端地址
+func (c *Conn) RemoteAddr() string {
+	return c.ws.RemoteAddr().String()
+}
+
+// Run 启动写协程，并在当前协程读取客户端消息依次交给 onMessage，直到连接断开
+func (c *Conn) Run(onMessage func(msg []byte)) {
+	go c.writeLoop()
+
+	c.ws.SetReadLimit(c.opts.MaxMessageSize)
+	deadline := func() {
+		c.ws.SetReadDeadline(time.Now().Add(2 * c.opts.PingInterval))
+	}
+	deadline()
+	c.ws.SetPongHandler(func(string) error {
+		deadline()
+		return nil
+	})
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			c.Close(websocket.CloseNormalClosure, "")
+			break
+		}
+		deadline()
+		onMessage(msg)
+	}
+	<-c.writerDone
+}
+
+// writeLoop 依次写出队列中的消息并定时发送心跳，连接关闭时发送关闭帧并断开底层连接
+func (c *Conn) writeLoop() {
+	defer close(c.writerDone)
+	defer c.ws.Close()
+
+	ticker := time.NewTicker(c.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			deadline := time.Now().Add(c.opts.WriteTimeout)
+			if c.code != CloseSlowConsumer {
+				c.flush(deadline)
+			}
+			c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.code, c.reason), deadline)
+			return
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+			if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.opts.WriteTimeout)); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// flush 在 deadline 之前尽量写出队列中剩余的消息，如关闭前的通知
+func (c *Conn) flush(deadline time.Time) {
+	c.ws.SetWriteDeadline(deadline)
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
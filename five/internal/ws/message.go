@@ -0,0 +1,51 @@
+package ws
+
+import "strings"
+
+// 客户端请求的操作
+const (
+	OpSubscribe   = "subscribe"
+	OpUnsubscribe = "unsubscribe"
+	OpPing        = "ping"
+	OpPong        = "pong"
+	OpError       = "error"
+)
+
+// Request 客户端请求，如 {"id":1,"op":"subscribe","channels":["depth:BTC/USDT"]}
+type Request struct {
+	ID       int64    `json:"id,omitempty"` // 客户端自定义的请求编号，应答中原样返回
+	Op       string   `json:"op"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// Response 对请求的应答，失败时 Op 为 error
+type Response struct {
+	ID       int64    `json:"id,omitempty"`
+	Op       string   `json:"op"`
+	Channels []string `json:"channels,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Push 服务端推送的频道数据
+type Push struct {
+	Channel string `json:"channel"`
+	Type    string `json:"type,omitempty"` // 增量频道区分 snapshot 与 update
+	Data    any    `json:"data"`
+}
+
+// 增量频道的推送类型
+const (
+	PushSnapshot = "snapshot"
+	PushUpdate   = "update"
+)
+
+// Channel 频道名，格式为 <类型>:<交易对>
+func Channel(kind, symbol string) string {
+	return kind + ":" + symbol
+}
+
+// ParseChannel 拆分频道名为类型与交易对
+func ParseChannel(channel string) (kind, symbol string, ok bool) {
+	kind, symbol, ok = strings.Cut(channel, ":")
+	return kind, symbol, ok && kind != "" && symbol != ""
+}
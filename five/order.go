@@ -3,9 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 
 	"five/internal/config"
+	"five/internal/eventstore"
 	"five/internal/handler"
+	"five/internal/migrate"
 	"five/internal/svc"
 
 	"github.com/zeromicro/go-zero/core/conf"
@@ -15,6 +18,17 @@ import (
 var configFile = flag.String("f", "etc/order-api.yaml", "the config file")
 
 func main() {
+	// order migrate [-f config] up|down|status|version
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate.RunCommand(os.Args[2:])
+		return
+	}
+	// order replay [-f config] [--until time] [-o file] [--verify] [--restore]
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		eventstore.RunCommand(os.Args[2:], svc.OpenDB)
+		return
+	}
+
 	flag.Parse()
 
 	var c config.Config
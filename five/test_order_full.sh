@@ -2,75 +2,203 @@
 
 # 完整的订单生命周期测试脚本
 echo "=== 订单生命周期完整测试 ==="
+# 启动服务前先执行数据库迁移: go run order.go migrate up
+
+# 用户接口的 JWT，密钥与配置 Auth.AccessSecret 一致
+b64url() { openssl base64 -A | tr '+/' '-_' | tr -d '='; }
+jwt() {
+  local header claims sig
+  header=$(printf '{"alg":"HS256","typ":"JWT"}' | b64url)
+  claims=$(printf '{"userId":%d,"exp":%d}' "$1" $(( $(date +%s) + 3600 )) | b64url)
+  sig=$(printf '%s.%s' "$header" "$claims" | openssl dgst -sha256 -hmac "change-me-too" -binary | b64url)
+  echo "$header.$claims.$sig"
+}
+TOKEN=$(jwt 123)
+TOKEN_456=$(jwt 456)
+
+# 0. 入金：买方 USDT，卖方 BTC 与 ETH
+echo "0. 管理员入金..."
+for deposit in '{"user_id": 123, "asset": "USDT", "amount": "100000"}' \
+               '{"user_id": 123, "asset": "ETH", "amount": "10"}' \
+               '{"user_id": 456, "asset": "BTC", "amount": "1"}'; do
+  curl -X POST "http://localhost:8888/admin/account/deposit" \
+    -H "Content-Type: application/json" \
+    -H "X-Admin-Token: change-me" \
+    -d "$deposit"
+done
 
 # 1. 创建订单
-echo "1. 创建订单..."
+echo -e "\n\n1. 创建订单..."
 curl -X POST "http://localhost:8888/order/create" \
   -H "Content-Type: application/json" \
   -d '{
-    "order_id": "test_order_001",
+    "client_order_id": "test_order_001",
     "user_id": 123,
     "symbol": "BTC/USDT",
     "order_type": "limit",
     "order_side": "buy",
-    "price": 50000.0,
-    "amount": 1.0
+    "price": "50000.0",
+    "amount": "1.0"
+  }'
+
+echo -e "\n\n1.1 重复提交（Idempotency-Key），返回原订单..."
+curl -X POST "http://localhost:8888/order/create" \
+  -H "Content-Type: application/json" \
+  -H "Idempotency-Key: test_order_001" \
+  -d '{
+    "user_id": 123,
+    "symbol": "BTC/USDT",
+    "order_type": "limit",
+    "order_side": "buy",
+    "price": "50000.0",
+    "amount": "1.0"
   }'
 
 echo -e "\n\n2. 查询订单..."
-curl "http://localhost:8888/order/get?order_id=test_order_001"
+curl "http://localhost:8888/order/get?user_id=123&client_order_id=test_order_001"
 
 echo -e "\n\n3. 查询用户订单..."
 curl "http://localhost:8888/order/user-orders?user_id=123"
 
-echo -e "\n\n4. 部分成交订单 (0.5)..."
-curl -X POST "http://localhost:8888/order/fill?order_id=test_order_001&price=50000.0&amount=0.5&fee_rate=0.001"
+echo -e "\n\n3.1 查询订单簿深度..."
+curl "http://localhost:8888/order/book?symbol=BTC/USDT&depth=10&precision=10"
+
+echo -e "\n\n4. 对手方卖单撮合，部分成交 (0.5)..."
+curl -X POST "http://localhost:8888/order/create" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "client_order_id": "test_order_101",
+    "user_id": 456,
+    "symbol": "BTC/USDT",
+    "order_type": "limit",
+    "order_side": "sell",
+    "price": "50000.0",
+    "amount": "0.5"
+  }'
 
 echo -e "\n\n5. 查询订单状态（部分成交）..."
-curl "http://localhost:8888/order/get?order_id=test_order_001"
+curl "http://localhost:8888/order/get?user_id=123&client_order_id=test_order_001"
 
 echo -e "\n\n6. 查询成交记录..."
-curl "http://localhost:8888/order/trades?order_id=test_order_001"
+curl "http://localhost:8888/order/trades?user_id=123&client_order_id=test_order_001"
 
-echo -e "\n\n7. 完全成交订单 (剩余0.5)..."
-curl -X POST "http://localhost:8888/order/fill?order_id=test_order_001&price=50000.0&amount=0.5&fee_rate=0.001"
+echo -e "\n\n7. 对手方卖单撮合，完全成交 (剩余0.5)..."
+curl -X POST "http://localhost:8888/order/create" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "client_order_id": "test_order_102",
+    "user_id": 456,
+    "symbol": "BTC/USDT",
+    "order_type": "limit",
+    "order_side": "sell",
+    "price": "49900.0",
+    "amount": "0.5"
+  }'
 
 echo -e "\n\n8. 查询订单状态（完全成交）..."
-curl "http://localhost:8888/order/get?order_id=test_order_001"
+curl "http://localhost:8888/order/get?user_id=123&client_order_id=test_order_001"
 
 echo -e "\n\n9. 尝试取消已成交订单（应该失败）..."
-curl -X POST "http://localhost:8888/order/cancel?order_id=test_order_001&reason=test_cancel"
+curl -X POST "http://localhost:8888/order/cancel?user_id=123&client_order_id=test_order_001&reason=test_cancel"
 
 echo -e "\n\n10. 创建新订单用于取消测试..."
 curl -X POST "http://localhost:8888/order/create" \
   -H "Content-Type: application/json" \
   -d '{
-    "order_id": "test_order_002",
+    "client_order_id": "test_order_002",
     "user_id": 123,
     "symbol": "ETH/USDT",
     "order_type": "limit",
     "order_side": "sell",
-    "price": 3000.0,
-    "amount": 10.0
+    "price": "3000.0",
+    "amount": "10.0"
   }'
 
 echo -e "\n\n11. 取消订单..."
-curl -X POST "http://localhost:8888/order/cancel?order_id=test_order_002&reason=user_request"
+curl -X POST "http://localhost:8888/order/cancel?user_id=123&client_order_id=test_order_002&reason=user_request"
 
 echo -e "\n\n12. 查询取消后的订单状态..."
-curl "http://localhost:8888/order/get?order_id=test_order_002"
+curl "http://localhost:8888/order/get?user_id=123&client_order_id=test_order_002"
 
 echo -e "\n\n13. 查询用户所有订单..."
 curl "http://localhost:8888/order/user-orders?user_id=123"
 
+echo -e "\n\n14. 查询资产余额（撤单后冻结已释放）..."
+curl -H "Authorization: Bearer $TOKEN" "http://localhost:8888/account/balances"
+curl -H "Authorization: Bearer $TOKEN_456" "http://localhost:8888/account/balances"
+
+echo -e "\n\n15. 查询账本流水..."
+curl -H "Authorization: Bearer $TOKEN" "http://localhost:8888/account/ledger?limit=20"
+
+echo -e "\n\n15.1 查询用户费率等级..."
+curl -H "Authorization: Bearer $TOKEN" "http://localhost:8888/fee/rates?symbol=BTC/USDT"
+
+echo -e "\n\n16. 全量对账（每个币种分录之和为零）..."
+curl -H "X-Admin-Token: change-me" "http://localhost:8888/admin/ledger/audit"
+
+echo -e "\n\n17. 查询读模型（由订单事件异步构建）..."
+sleep 1
+curl "http://localhost:8888/projection/open-orders?user_id=123"
+curl "http://localhost:8888/projection/order-counts?user_id=123"
+curl "http://localhost:8888/projection/symbol-stats?symbol=BTC/USDT"
+
+echo -e "\n\n17.1 重建读模型（清空后从最早的事件重新消费）..."
+curl -X POST -H "X-Admin-Token: change-me" "http://localhost:8888/admin/projection/rebuild"
+
+echo -e "\n\n18. 查询未重放的死信..."
+curl -X GET -H "X-Admin-Token: change-me" "http://localhost:8888/admin/dlq/list?status=pending&limit=20"
+
+echo -e "\n\n18.1 重放全部未重放的死信..."
+curl -X POST -H "X-Admin-Token: change-me" "http://localhost:8888/admin/dlq/replay?all=true"
+
 echo -e "\n\n=== 测试完成 ==="
 
 # 数据库检查
 echo -e "\n=== 数据库检查 ==="
-docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT order_id, symbol, order_type, order_side, price, amount, filled_amount, status FROM orders;"
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT order_id, client_order_id, symbol, order_type, order_side, price, amount, filled_amount, fee, fee_asset, token_fee, status FROM orders;"
 
 echo -e "\n=== 成交记录检查 ==="
-docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT order_id, price, amount, fee, fee_asset FROM trades;"
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT match_id, order_id, counter_order_id, price, amount, role, fee_rate, fee, fee_asset FROM trades;"
+
+echo -e "\n=== 资产账户检查 ==="
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT user_id, asset, available, frozen FROM accounts;"
+
+echo -e "\n=== 发件箱检查（sent_at 为空表示待发布）==="
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT id, topic, \`key\`, attempts, last_error, sent_at FROM outbox_messages;"
+
+echo -e "\n=== 死信检查（replayed_at 为空表示未重放）==="
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT id, dead_letter_id, original_topic, consumer_group, attempts, error, replays, replayed_at FROM dead_letters;"
+
+echo -e "\n=== 事件存储检查 ==="
+docker exec -it mysql mysql -uapp_user -papp_password app_db -e "SELECT sequence, type, order_id, occurred_at FROM order_events;"
+
+echo -e "\n=== 事件重放检查（由事件存储还原订单与成交记录并与数据库比对）==="
+go run order.go replay --verify
+
+echo -e "\n=== 撮合预写日志检查（服务重启时由最新快照与其后的日志恢复订单簿）==="
+ls -l data/matching
+
+echo -e "\n=== 公开行情推送检查（需安装 websocat）==="
+if command -v websocat >/dev/null; then
+  echo '{"op":"subscribe","channels":["depth:BTC/USDT","trades:BTC/USDT","ticker:BTC/USDT"]}' | timeout 3 websocat -n ws://localhost:8888/ws/market
+fi
+
+echo -e "\n=== K线查询 ==="
+curl -s "http://localhost:8888/market/klines?symbol=BTC/USDT&interval=1m&limit=5"
+curl -s "http://localhost:8888/market/klines?symbol=BTC/USDT&interval=1d&start=$(( ($(date +%s) - 7*86400) * 1000 ))"
+
+echo -e "\n=== 用户数据流检查（需安装 websocat）==="
+LISTEN_KEY=$(curl -s -X POST -H "Authorization: Bearer $TOKEN" "http://localhost:8888/user-stream/listen-key" \
+  | sed -n 's/.*"listen_key":"\([^"]*\)".*/\1/p')
+echo "listen key: $LISTEN_KEY"
+curl -s -X PUT -H "Authorization: Bearer $TOKEN" "http://localhost:8888/user-stream/listen-key?listen_key=$LISTEN_KEY"
+if command -v websocat >/dev/null; then
+  echo '{"op":"ping"}' | timeout 3 websocat -n "ws://localhost:8888/ws/user?listen_key=$LISTEN_KEY"
+fi
+curl -s -X DELETE -H "Authorization: Bearer $TOKEN" "http://localhost:8888/user-stream/listen-key?listen_key=$LISTEN_KEY"
 
 echo -e "\n=== Redis缓存检查 ==="
-docker exec -it redis redis-cli KEYS "order:*"
\ No newline at end of file
+docker exec -it redis redis-cli KEYS "order:*"
+
+echo -e "\n=== 读模型检查 ==="
+docker exec -it redis redis-cli KEYS "projection:*"
\ No newline at end of file